@@ -8,6 +8,11 @@ var GRAPH_DRAW_PARENTS  = [],
     GRAPH_CONTROL_LOCK    = false,
     GRAPH_CONTROL_TIMEOUT = null,
 
+    GRAPH_STREAM_COUNT         = 0,
+    GRAPH_STREAM_SESSION       = null,
+    GRAPH_STREAM_SOURCE        = null,
+    GRAPH_STREAM_SUBSCRIPTIONS = {},
+
     $graphTemplate;
 
 function graphDraw(graph, postpone, delay, preview) {
@@ -42,7 +47,13 @@ function graphDraw(graph, postpone, delay, preview) {
             graph.find('.graphctrl .ranges').hide();
 
             if (readOnly)
-                graph.find('.graphctrl .edit').hide();
+                graph.find('.graphctrl .edit, .graphctrl a[href="#snapshot"]').hide();
+
+            // Snapshots plots are frozen, thus only keep legend and export controls
+            if (graph.attr('data-snapshot')) {
+                graph.find('.graphctrl .step, .graphctrl .actgroup:not(:last)').hide();
+                graph.find('.graphctrl a[href="#embed"], .graphctrl a[href="#snapshot"]').hide();
+            }
 
             graph.find('.placeholder').text(graph.data('options').title || 'N/A');
         }
@@ -63,7 +74,9 @@ function graphDraw(graph, postpone, delay, preview) {
     return $.Deferred(function ($deferred) {
         setTimeout(function () {
             var graphOpts,
-                query;
+                key,
+                query,
+                request;
 
             graph.find('.placeholder').text($.t('main.mesg_loading'));
 
@@ -87,6 +100,17 @@ function graphDraw(graph, postpone, delay, preview) {
             if (!graphOpts.range)
                 graphOpts.range = GRAPH_DEFAULT_RANGE;
 
+            // Collect graph template variables values
+            for (key in graphOpts) {
+                if (key.indexOf('variables.') !== 0)
+                    continue;
+
+                graphOpts.variables = graphOpts.variables || {};
+                graphOpts.variables[key.substr(10)] = String(graphOpts[key]);
+
+                delete graphOpts[key];
+            }
+
             // Set graph options
             graph.data('options', graphOpts);
 
@@ -97,9 +121,15 @@ function graphDraw(graph, postpone, delay, preview) {
                 sample: graphOpts.sample,
                 percentiles: graphOpts.percentiles ? $.map(graphOpts.percentiles.split(','), function (x) {
                     return parseFloat(x.trim());
-                }) : undefined
+                }) : undefined,
+                variables: graphOpts.variables
             };
 
+            if (graphOpts.annotations === true)
+                query.annotations = [];
+            else if (graphOpts.annotations)
+                query.annotations = $.map(String(graphOpts.annotations).split(','), $.trim);
+
             if (preview) {
                 query.graph = preview;
 
@@ -108,14 +138,30 @@ function graphDraw(graph, postpone, delay, preview) {
                 query.id = graph.attr('data-graph');
             }
 
-            return $.ajax({
-                url: urlPrefix + '/api/v1/library/graphs/plots',
-                type: 'POST',
-                contentType: 'application/json',
-                data: JSON.stringify(query),
-                dataType: 'json'
-            }).pipe(function (data) {
+            graph.data('query', query);
+
+            if (graph.attr('data-snapshot')) {
+                request = $.ajax({
+                    url: urlPrefix + '/api/v1/snapshots/' + graph.attr('data-snapshot'),
+                    type: 'GET',
+                    dataType: 'json'
+                }).pipe(function (data) {
+                    return data.plots || data;
+                });
+            } else if (!preview && graphOpts.refresh_interval && !graphOpts.time && window.EventSource &&
+                    !graph.data('stream-disabled')) {
+                request = graphStream(graph, query, parseInt(graphOpts.refresh_interval, 10));
+            } else {
+                // Close previous plots stream
+                graphStreamClose(graph.data('stream'));
+
+                request = graphPlots(query);
+            }
+
+            return request.pipe(function (data) {
                 var $container,
+                    annotationEnd,
+                    annotationStart,
                     graphTableUpdate,
                     highchartOpts,
                     startTime,
@@ -153,7 +199,7 @@ function graphDraw(graph, postpone, delay, preview) {
 
                 graphTableUpdate = function () {
                     if (graphOpts.legend)
-                        Highcharts.drawTable.apply(this, [seriesData]);
+                        Highcharts.drawTable.apply(this, [this.options._data.series]);
                 };
 
                 highchartOpts = {
@@ -288,7 +334,8 @@ function graphDraw(graph, postpone, delay, preview) {
                         }
                     },
                     _data: {
-                        plotlines: {}
+                        plotlines: {},
+                        series: seriesData
                     },
                     _opts: data
                 };
@@ -384,12 +431,46 @@ function graphDraw(graph, postpone, delay, preview) {
                         color: data.series[i].options ? data.series[i].options.color : null
                     });
 
+                    // Draw constants as distinct lines, never stacking them with other series
+                    if (data.series[i].options && data.series[i].options.constant) {
+                        highchartOpts.series[highchartOpts.series.length - 1].type = 'line';
+                        highchartOpts.series[highchartOpts.series.length - 1].stack = 'constant' + i;
+                    }
+
                     seriesData[data.series[i].name] = {
                         summary: data.series[i].summary,
                         options: data.series[i].options
                     };
                 }
 
+                // Draw annotations as vertical lines, or bands if they span over a time range
+                for (i in data.annotations) {
+                    annotationStart = moment(data.annotations[i].start).valueOf();
+                    annotationEnd   = moment(data.annotations[i].end).valueOf();
+
+                    if (annotationEnd > annotationStart) {
+                        highchartOpts.xAxis.plotBands = highchartOpts.xAxis.plotBands || [];
+                        highchartOpts.xAxis.plotBands.push({
+                            color: 'rgba(255, 128, 0, 0.1)',
+                            from: annotationStart,
+                            to: annotationEnd,
+                            label: {
+                                text: data.annotations[i].name
+                            }
+                        });
+                    } else {
+                        highchartOpts.xAxis.plotLines = highchartOpts.xAxis.plotLines || [];
+                        highchartOpts.xAxis.plotLines.push({
+                            color: '#f80',
+                            value: annotationStart,
+                            width: 1,
+                            label: {
+                                text: data.annotations[i].name
+                            }
+                        });
+                    }
+                }
+
                 // Prepare legend spacing
                 $container = graph.children('.graphcntr');
 
@@ -416,8 +497,8 @@ function graphDraw(graph, postpone, delay, preview) {
 
                 $container.highcharts(highchartOpts);
 
-                // Set next refresh if needed
-                if (graphOpts.refresh_interval) {
+                // Set next refresh if needed, streamed graphs being updated as new plots come
+                if (graphOpts.refresh_interval && !graph.data('stream')) {
                     graph.data('timeout', setTimeout(function () {
                         graphDraw(graph, !graph.inViewport());
                     }, graphOpts.refresh_interval * 1000));
@@ -476,6 +557,231 @@ function graphExport(graph) {
     }
 }
 
+function graphPlots(query) {
+    return $.ajax({
+        url: urlPrefix + '/api/v1/library/graphs/plots',
+        type: 'POST',
+        contentType: 'application/json',
+        data: JSON.stringify(query),
+        dataType: 'json'
+    });
+}
+
+function graphStream(graph, query, interval) {
+    var $deferred = $.Deferred(),
+        previous = graph.data('stream'),
+        id = 'graph' + (++GRAPH_STREAM_COUNT);
+
+    GRAPH_STREAM_SUBSCRIPTIONS[id] = {
+        graph: graph,
+        query: query,
+        interval: interval,
+        deferred: $deferred,
+        subscribed: $.Deferred()
+    };
+
+    graph.data('stream', id);
+
+    // Close previous subscription once subscribed, letting the server send the plots of an already running stream
+    graphStreamSubscribe(id).always(function () {
+        graphStreamClose(previous);
+    });
+
+    return $deferred.promise();
+}
+
+function graphStreamOpen() {
+    // All the page graphs share the same connection, events being dispatched using their subscription identifier
+    GRAPH_STREAM_SOURCE = new EventSource(urlPrefix + '/api/v1/streams/');
+
+    GRAPH_STREAM_SOURCE.addEventListener('session', function (e) {
+        var id;
+
+        GRAPH_STREAM_SESSION = JSON.parse(e.data).id;
+
+        for (id in GRAPH_STREAM_SUBSCRIPTIONS)
+            graphStreamSubscribe(id);
+    });
+
+    GRAPH_STREAM_SOURCE.addEventListener('plots', function (e) {
+        var data = JSON.parse(e.data),
+            subscription = GRAPH_STREAM_SUBSCRIPTIONS[data.id];
+
+        if (!subscription || subscription.graph.data('stream') !== data.id)
+            return;
+
+        if (subscription.deferred.state() == 'pending')
+            subscription.deferred.resolve(data.data);
+        else
+            graphDraw(subscription.graph, !subscription.graph.inViewport());
+    });
+
+    GRAPH_STREAM_SOURCE.addEventListener('update', function (e) {
+        var data = JSON.parse(e.data),
+            subscription = GRAPH_STREAM_SUBSCRIPTIONS[data.id];
+
+        if (!subscription || subscription.graph.data('stream') !== data.id ||
+                subscription.deferred.state() == 'pending')
+            return;
+
+        graphStreamUpdate(subscription.graph, data.data);
+    });
+
+    GRAPH_STREAM_SOURCE.addEventListener('error', function () {
+        var subscriptions = GRAPH_STREAM_SUBSCRIPTIONS;
+
+        GRAPH_STREAM_SOURCE.close();
+
+        GRAPH_STREAM_SOURCE = null;
+        GRAPH_STREAM_SESSION = null;
+        GRAPH_STREAM_SUBSCRIPTIONS = {};
+
+        $.each(subscriptions, function (id, subscription) {
+            if (subscription.graph.data('stream') !== id)
+                return;
+
+            subscription.graph.removeData('stream');
+            subscription.subscribed.reject();
+
+            if (subscription.deferred.state() == 'pending') {
+                graphPlots(subscription.query)
+                    .done(subscription.deferred.resolve)
+                    .fail(subscription.deferred.reject);
+
+                return;
+            }
+
+            // Subscribe again to the stream on next refresh
+            subscription.graph.data('timeout', setTimeout(function () {
+                graphDraw(subscription.graph, !subscription.graph.inViewport());
+            }, subscription.interval * 1000));
+        });
+    });
+}
+
+function graphStreamSubscribe(id) {
+    var subscription = GRAPH_STREAM_SUBSCRIPTIONS[id];
+
+    if (!GRAPH_STREAM_SOURCE)
+        graphStreamOpen();
+
+    // Wait for the session to be opened before subscribing
+    if (!GRAPH_STREAM_SESSION || subscription.session)
+        return subscription.subscribed.promise();
+
+    subscription.session = GRAPH_STREAM_SESSION;
+
+    $.ajax({
+        url: urlPrefix + '/api/v1/streams/' + subscription.session,
+        type: 'POST',
+        contentType: 'application/json',
+        data: JSON.stringify({
+            id: id,
+            interval: subscription.interval,
+            request: subscription.query
+        })
+    }).done(function () {
+        subscription.subscribed.resolve();
+    }).fail(function (xhr) {
+        if (GRAPH_STREAM_SUBSCRIPTIONS[id] !== subscription)
+            return;
+
+        delete GRAPH_STREAM_SUBSCRIPTIONS[id];
+
+        subscription.graph.removeData('stream');
+        subscription.subscribed.reject();
+
+        // Fall back to plots polling, streams being rejected for too short refresh intervals or when too many of
+        // them are already running on the server
+        if (xhr.status == 400)
+            subscription.graph.data('stream-disabled', true);
+
+        if (subscription.deferred.state() == 'pending') {
+            graphPlots(subscription.query)
+                .done(subscription.deferred.resolve)
+                .fail(subscription.deferred.reject);
+        }
+    });
+
+    return subscription.subscribed.promise();
+}
+
+function graphStreamClose(id) {
+    var subscription = GRAPH_STREAM_SUBSCRIPTIONS[id];
+
+    if (!subscription)
+        return;
+
+    delete GRAPH_STREAM_SUBSCRIPTIONS[id];
+
+    if (subscription.graph.data('stream') === id)
+        subscription.graph.removeData('stream');
+
+    subscription.subscribed.done(function () {
+        $.ajax({
+            url: urlPrefix + '/api/v1/streams/' + subscription.session + '/' + id,
+            type: 'DELETE'
+        });
+    });
+}
+
+function graphStreamUpdate(graph, data) {
+    var chart = graph.children('.graphcntr').highcharts(),
+        startTime = moment(data.start).valueOf(),
+        series,
+        time,
+        i,
+        j,
+        k;
+
+    if (!chart)
+        return;
+
+    for (i in data.series) {
+        series = chart.get(data.series[i].name);
+        if (!series)
+            continue;
+
+        // Update legend summary, computed by the server over the whole time range
+        if (data.series[i].summary && chart.options._data.series[data.series[i].name])
+            chart.options._data.series[data.series[i].name].summary = data.series[i].summary;
+
+        for (j in data.series[i].plots) {
+            time = data.series[i].plots[j][0] * 1000;
+
+            // Replace points previously sent while not yet available on the origin backend
+            for (k = series.data.length - 1; k >= 0 && series.data[k].x >= time; k--)
+                series.data[k].remove(false);
+
+            series.addPoint([time, data.series[i].plots[j][1]], false);
+        }
+
+        // Discard points being out of the graph time range
+        while (series.data.length > 0 && series.data[0].x < startTime)
+            series.data[0].remove(false);
+    }
+
+    chart.xAxis[0].setExtremes(startTime, moment(data.end).valueOf(), false);
+    chart.redraw();
+}
+
+function graphSnapshot(graph) {
+    return $.ajax({
+        url: urlPrefix + '/api/v1/snapshots/',
+        type: 'POST',
+        contentType: 'application/json',
+        data: JSON.stringify(graph.data('query'))
+    }).then(function (data, textStatus, jqXHR) {
+        var location = jqXHR.getResponseHeader('Location');
+
+        window.open(urlPrefix + '/show/snapshot/' + location.substr(location.lastIndexOf('/') + 1));
+    }).fail(function () {
+        overlayCreate('alert', {
+            message: $.t('graph.mesg_snapshot_fail')
+        });
+    });
+}
+
 function graphHandleActions(e) {
     var $target = $(e.target),
         $graph = $target.closest('[data-graph]'),
@@ -519,6 +825,8 @@ function graphHandleActions(e) {
         window.open(urlPrefix + '/show/graphs/' + $(e.target).closest('[data-graph]').attr('data-graph'));
     } else if (e.target.href.endsWith('#export')) {
         graphExport($graph);
+    } else if (e.target.href.endsWith('#snapshot')) {
+        graphSnapshot($graph);
     } else if (e.target.href.endsWith('#set-range')) {
         // Toggle range selector
         $(e.target).closest('.graphctrl').find('.ranges').toggle();
@@ -13,7 +13,10 @@ const (
 	cmdUsage = `Usage: %s [OPTIONS] COMMAND
 
 Commands:
-   refresh  refresh server catalog and library`
+   refresh                           refresh server catalog and library
+   library check                     report library items referencing unknown items
+   library export COLLECTION [FILE]  export a collection and its dependencies as a bundle
+   library import FILE [POLICY]      import a bundle (policy: skip, rename or overwrite)`
 
 	defaultConfigFile string = "/etc/facette/facette.json"
 )
@@ -60,6 +63,8 @@ func main() {
 	switch flag.Args()[0] {
 	case "refresh":
 		handler = handleService
+	case "library":
+		handler = handleLibrary
 	default:
 		utils.PrintUsage(os.Stderr, cmdUsage)
 		os.Exit(1)
@@ -70,5 +75,6 @@ func main() {
 		utils.PrintUsage(os.Stderr, cmdUsage)
 	} else if err != nil {
 		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/utils"
+)
+
+func handleLibrary(config *config.Config, args []string) error {
+	cmd := &cmdLibrary{config: config}
+
+	if len(args) < 2 {
+		return os.ErrInvalid
+	}
+
+	switch args[1] {
+	case "check":
+		return cmd.check(args[2:])
+	case "export":
+		return cmd.export(args[2:])
+	case "import":
+		return cmd.importBundle(args[2:])
+	}
+
+	return os.ErrInvalid
+}
+
+type cmdLibrary struct {
+	config *config.Config
+}
+
+func (cmd *cmdLibrary) check(args []string) error {
+	if len(args) > 0 {
+		return os.ErrInvalid
+	}
+
+	lib, err := cmd.load()
+	if err != nil {
+		return err
+	}
+
+	references := lib.CheckReferences()
+	for _, reference := range references {
+		fmt.Println(reference)
+	}
+
+	if len(references) > 0 {
+		return fmt.Errorf("found %d dangling reference(s)", len(references))
+	}
+
+	return nil
+}
+
+func (cmd *cmdLibrary) export(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return os.ErrInvalid
+	}
+
+	lib, err := cmd.load()
+	if err != nil {
+		return err
+	}
+
+	// Search collection by identifier, then by name
+	collectionID := args[0]
+
+	if !lib.ItemExists(collectionID, library.LibraryItemCollection) {
+		item, err := lib.GetItemByName(collectionID, library.LibraryItemCollection)
+		if err != nil {
+			return fmt.Errorf("unknown collection `%s'", collectionID)
+		}
+
+		collectionID = item.(*library.Collection).ID
+	}
+
+	bundle, err := lib.ExportCollection(collectionID)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		return utils.JSONDump(args[1], bundle, time.Now())
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+func (cmd *cmdLibrary) importBundle(args []string) error {
+	var bundle *library.Bundle
+
+	if len(args) == 0 || len(args) > 2 {
+		return os.ErrInvalid
+	}
+
+	policy := library.ImportPolicySkip
+	if len(args) == 2 {
+		policy = args[1]
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &bundle); err != nil || bundle == nil {
+		return fmt.Errorf("invalid bundle file `%s'", args[0])
+	}
+
+	lib, err := cmd.load()
+	if err != nil {
+		return err
+	}
+
+	result, err := lib.ImportBundle(bundle, policy, "", nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bundle imported: %d created, %d overwritten, %d renamed, %d skipped\n", result.Created,
+		result.Overwritten, result.Renamed, result.Skipped)
+
+	// Notify running server if any
+	if cmd.config.PidFile != "" {
+		if _, err := os.Stat(cmd.config.PidFile); err == nil {
+			return (&cmdServer{config: cmd.config}).refresh(nil)
+		}
+	}
+
+	return nil
+}
+
+func (cmd *cmdLibrary) load() (*library.Library, error) {
+	lib := library.NewLibrary(cmd.config, nil)
+
+	if err := lib.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return lib, nil
+}
@@ -0,0 +1,77 @@
+// Package auth implements the authentication of HTTP requests.
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// SchemeBasic represents the HTTP basic authentication scheme.
+	SchemeBasic = "Basic"
+	// SchemeBearer represents the HTTP bearer token authentication scheme.
+	SchemeBearer = "Bearer"
+)
+
+// Backend represents the main interface of an authentication backend.
+type Backend interface {
+	// Authenticate checks the request credentials, returning the authenticated user name.
+	Authenticate(request *http.Request) (string, bool)
+	// Scheme returns the HTTP authentication scheme handled by the backend.
+	Scheme() string
+	// Reload reloads the backend credentials.
+	Reload() error
+}
+
+// Authenticator represents a set of authentication backends.
+type Authenticator struct {
+	Realm    string
+	Backends []Backend
+}
+
+// NewAuthenticator creates a new instance of authenticator.
+func NewAuthenticator(realm string, backends ...Backend) *Authenticator {
+	return &Authenticator{
+		Realm:    realm,
+		Backends: backends,
+	}
+}
+
+// Authenticate checks the request credentials against each of the authenticator backends, returning the
+// authenticated user name.
+func (authenticator *Authenticator) Authenticate(request *http.Request) (string, bool) {
+	for _, backend := range authenticator.Backends {
+		if user, ok := backend.Authenticate(request); ok {
+			return user, true
+		}
+	}
+
+	return "", false
+}
+
+// Challenge sets the authentication challenge headers of an unauthorized HTTP response.
+func (authenticator *Authenticator) Challenge(writer http.ResponseWriter) {
+	schemes := make(map[string]bool)
+
+	for _, backend := range authenticator.Backends {
+		if schemes[backend.Scheme()] {
+			continue
+		}
+
+		writer.Header().Add("WWW-Authenticate", backend.Scheme()+" realm=\""+
+			strings.Replace(authenticator.Realm, "\"", "\\\"", -1)+"\"")
+
+		schemes[backend.Scheme()] = true
+	}
+}
+
+// Reload reloads the credentials of each of the authenticator backends.
+func (authenticator *Authenticator) Reload() error {
+	for _, backend := range authenticator.Backends {
+		if err := backend.Reload(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
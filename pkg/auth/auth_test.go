@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/facette/facette/thirdparty/github.com/golang/crypto/bcrypt"
+)
+
+func Test_HTPasswd(test *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "facette")
+	if err != nil {
+		test.Fatalf("unable to create temporary directory: %s", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	filePath := path.Join(tmpDir, "htpasswd")
+	ioutil.WriteFile(filePath, []byte("# Users\nuser1:"+string(hash)+"\n\nuser2:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=\n"), 0644)
+
+	backend, err := NewHTPasswd(filePath)
+	if err != nil {
+		test.Fatalf("NewHTPasswd() returned an error: %s", err)
+	}
+
+	for _, entry := range []struct {
+		User     string
+		Password string
+		Result   bool
+	}{
+		{"user1", "secret", true},
+		{"user1", "secret", true},
+		{"user1", "wrong", false},
+		{"user2", "secret", false},
+		{"unknown", "secret", false},
+	} {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.SetBasicAuth(entry.User, entry.Password)
+
+		user, ok := backend.Authenticate(request)
+		if ok != entry.Result || ok && user != entry.User {
+			test.Logf("\nExpected %#v (%v)\nbut got  %#v (%v)", entry.User, entry.Result, user, ok)
+			test.Fail()
+		}
+	}
+
+	// Invalid file
+	ioutil.WriteFile(filePath, []byte("user1\n"), 0644)
+
+	if err := backend.Reload(); err == nil {
+		test.Logf("Reload() should have returned an error")
+		test.Fail()
+	}
+}
+
+func Test_Tokens(test *testing.T) {
+	backend := NewTokens(map[string]string{"grafana": "s3cr3t", "empty": ""})
+
+	for _, entry := range []struct {
+		Header string
+		User   string
+		Result bool
+	}{
+		{"Bearer s3cr3t", "grafana", true},
+		{"Bearer wrong", "", false},
+		{"Bearer ", "", false},
+		{"Basic s3cr3t", "", false},
+		{"", "", false},
+	} {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", entry.Header)
+
+		user, ok := backend.Authenticate(request)
+		if ok != entry.Result || user != entry.User {
+			test.Logf("\nExpected %#v (%v)\nbut got  %#v (%v)", entry.User, entry.Result, user, ok)
+			test.Fail()
+		}
+	}
+}
+
+func Test_Authenticator(test *testing.T) {
+	authenticator := NewAuthenticator("Test", NewTokens(map[string]string{"user1": "token1"}),
+		NewTokens(map[string]string{"user2": "token2"}))
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", "Bearer token2")
+
+	if user, ok := authenticator.Authenticate(request); !ok || user != "user2" {
+		test.Logf("\nExpected %#v\nbut got  %#v", "user2", user)
+		test.Fail()
+	}
+
+	recorder := httptest.NewRecorder()
+	authenticator.Challenge(recorder)
+
+	expected := []string{"Bearer realm=\"Test\""}
+	if !reflect.DeepEqual(expected, recorder.Header()["Www-Authenticate"]) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, recorder.Header()["Www-Authenticate"])
+		test.Fail()
+	}
+}
+
+func Test_ParseRole(test *testing.T) {
+	for name, expected := range map[string]int{"viewer": RoleViewer, "editor": RoleEditor, "admin": RoleAdmin} {
+		if role, err := ParseRole(name); err != nil || role != expected {
+			test.Logf("\nExpected %#v\nbut got  %#v", expected, role)
+			test.Fail()
+		}
+	}
+
+	if _, err := ParseRole("root"); err == nil {
+		test.Logf("ParseRole(%q) should have returned an error", "root")
+		test.Fail()
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/thirdparty/github.com/golang/crypto/bcrypt"
+)
+
+// HTPasswd represents an authentication backend checking HTTP basic credentials against an Apache htpasswd file.
+// Only bcrypt password hashes are supported.
+type HTPasswd struct {
+	Path  string
+	users map[string][]byte
+	cache map[string][sha256.Size]byte
+	sync.RWMutex
+}
+
+// NewHTPasswd creates a new instance of htpasswd backend, loading credentials from the given file.
+func NewHTPasswd(path string) (*HTPasswd, error) {
+	htpasswd := &HTPasswd{Path: path}
+
+	if err := htpasswd.Reload(); err != nil {
+		return nil, err
+	}
+
+	return htpasswd, nil
+}
+
+// Authenticate checks the request HTTP basic credentials, returning the authenticated user name.
+func (htpasswd *HTPasswd) Authenticate(request *http.Request) (string, bool) {
+	user, password, ok := request.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	sum := sha256.Sum256([]byte(password))
+
+	htpasswd.RLock()
+	hash, ok := htpasswd.users[user]
+	cached, hit := htpasswd.cache[user]
+	htpasswd.RUnlock()
+
+	if !ok {
+		return "", false
+	}
+
+	// Skip bcrypt comparison if the same credentials have already been checked successfully, as it is expensive by
+	// design
+	if hit && subtle.ConstantTimeCompare(cached[:], sum[:]) == 1 {
+		return user, true
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return "", false
+	}
+
+	htpasswd.Lock()
+	htpasswd.cache[user] = sum
+	htpasswd.Unlock()
+
+	return user, true
+}
+
+// Scheme returns the HTTP authentication scheme handled by the backend.
+func (htpasswd *HTPasswd) Scheme() string {
+	return SchemeBasic
+}
+
+// Reload reloads the credentials from the htpasswd file.
+func (htpasswd *HTPasswd) Reload() error {
+	fd, err := os.Open(htpasswd.Path)
+	if err != nil {
+		return fmt.Errorf("unable to open htpasswd file: %s", err)
+	}
+
+	defer fd.Close()
+
+	users := make(map[string][]byte)
+
+	scanner := bufio.NewScanner(fd)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		chunks := strings.SplitN(entry, ":", 2)
+		if len(chunks) != 2 || chunks[0] == "" {
+			return fmt.Errorf("invalid htpasswd entry at line %d", line)
+		}
+
+		if _, err := bcrypt.Cost([]byte(chunks[1])); err != nil {
+			logger.Log(logger.LevelWarning, "auth", "skipping user `%s': unsupported password hash", chunks[0])
+			continue
+		}
+
+		users[chunks[0]] = []byte(chunks[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read htpasswd file: %s", err)
+	}
+
+	htpasswd.Lock()
+	htpasswd.users = users
+	htpasswd.cache = make(map[string][sha256.Size]byte)
+	htpasswd.Unlock()
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "fmt"
+
+const (
+	// RoleViewer represents the role of users only allowed to read library items.
+	RoleViewer = iota
+	// RoleEditor represents the role of users allowed to edit library items, except collections owned by others.
+	RoleEditor
+	// RoleAdmin represents the role of users allowed to edit any library item.
+	RoleAdmin
+)
+
+var roleNames = map[string]int{
+	"viewer": RoleViewer,
+	"editor": RoleEditor,
+	"admin":  RoleAdmin,
+}
+
+// ParseRole returns the role matching a given name.
+func ParseRole(name string) (int, error) {
+	role, ok := roleNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown role `%s'", name)
+	}
+
+	return role, nil
+}
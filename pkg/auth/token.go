@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Tokens represents an authentication backend checking static bearer API tokens.
+type Tokens struct {
+	tokens map[string]string
+}
+
+// NewTokens creates a new instance of tokens backend, given a map of tokens indexed by user name.
+func NewTokens(tokens map[string]string) *Tokens {
+	backend := &Tokens{tokens: make(map[string]string)}
+
+	for user, token := range tokens {
+		if token == "" {
+			continue
+		}
+
+		backend.tokens[user] = token
+	}
+
+	return backend
+}
+
+// Authenticate checks the request bearer token, returning the authenticated user name.
+func (backend *Tokens) Authenticate(request *http.Request) (string, bool) {
+	header := request.Header.Get("Authorization")
+
+	if !strings.HasPrefix(header, SchemeBearer+" ") {
+		return "", false
+	}
+
+	token := []byte(strings.TrimSpace(strings.TrimPrefix(header, SchemeBearer+" ")))
+
+	for user := range backend.tokens {
+		if subtle.ConstantTimeCompare(token, []byte(backend.tokens[user])) == 1 {
+			return user, true
+		}
+	}
+
+	return "", false
+}
+
+// Scheme returns the HTTP authentication scheme handled by the backend.
+func (backend *Tokens) Scheme() string {
+	return SchemeBearer
+}
+
+// Reload does nothing as tokens are statically defined in the configuration.
+func (backend *Tokens) Reload() error {
+	return nil
+}
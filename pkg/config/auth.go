@@ -0,0 +1,11 @@
+package config
+
+// AuthConfig represents the HTTP authentication settings in the configuration system.
+type AuthConfig struct {
+	Realm         string            `json:"realm"`
+	HTPasswdFile  string            `json:"htpasswd_file"`
+	Tokens        map[string]string `json:"tokens"`
+	AnonymousRead bool              `json:"anonymous_read"`
+	Roles         map[string]string `json:"roles"`
+	DefaultRole   string            `json:"default_role"`
+}
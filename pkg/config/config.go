@@ -24,25 +24,41 @@ const (
 	DefaultDataDir string = "/var/lib/facette"
 	// DefaultProvidersDir represents the default providers definition files directory location.
 	DefaultProvidersDir string = "/etc/facette/providers"
+	// DefaultRulesDir represents the default alerting rules definition files directory location.
+	DefaultRulesDir string = "/etc/facette/rules"
+	// DefaultReportsDir represents the default scheduled reports definition files directory location.
+	DefaultReportsDir string = "/etc/facette/reports"
 	// DefaultPidFile represents the default server process PID file location.
 	DefaultPidFile string = "/var/run/facette/facette.pid"
 	// DefaultPlotSample represents the default plot sample for graph querying.
 	DefaultPlotSample int = 400
+	// DefaultAuthRealm represents the default HTTP authentication realm.
+	DefaultAuthRealm string = "Facette"
+	// DefaultAuthRole represents the default role of authenticated users.
+	DefaultAuthRole string = "editor"
+	// DefaultRevisionsLimit represents the default number of revisions kept for each library item.
+	DefaultRevisionsLimit int = 50
 )
 
 // Config represents the global configuration of the instance.
 type Config struct {
-	BindAddr     string                     `json:"bind"`
-	SocketUser   int                        `json:"socket_user,string"`
-	SocketGroup  int                        `json:"socket_group,string"`
-	SocketMode   *string                    `json:"socket_mode"`
-	BaseDir      string                     `json:"base_dir"`
-	DataDir      string                     `json:"data_dir"`
-	ProvidersDir string                     `json:"providers_dir"`
-	PidFile      string                     `json:"pid_file"`
-	URLPrefix    string                     `json:"url_prefix"`
-	ReadOnly     bool                       `json:"read_only"`
-	Providers    map[string]*ProviderConfig `json:"-"`
+	BindAddr       string                     `json:"bind"`
+	SocketUser     int                        `json:"socket_user,string"`
+	SocketGroup    int                        `json:"socket_group,string"`
+	SocketMode     *string                    `json:"socket_mode"`
+	BaseDir        string                     `json:"base_dir"`
+	DataDir        string                     `json:"data_dir"`
+	ProvidersDir   string                     `json:"providers_dir"`
+	RulesDir       string                     `json:"rules_dir"`
+	ReportsDir     string                     `json:"reports_dir"`
+	PidFile        string                     `json:"pid_file"`
+	URLPrefix      string                     `json:"url_prefix"`
+	ReadOnly       bool                       `json:"read_only"`
+	RevisionsLimit int                        `json:"revisions_limit"`
+	Auth           *AuthConfig                `json:"auth"`
+	Providers      map[string]*ProviderConfig `json:"-"`
+	Rules          map[string]*RuleConfig     `json:"-"`
+	Reports        map[string]*ReportConfig   `json:"-"`
 }
 
 // Load loads the configuration from the filesystem.
@@ -86,9 +102,74 @@ func (config *Config) Load(filePath string) error {
 		return errOutput
 	}
 
+	// Load alerting rules and scheduled reports definitions, both being optional
+	if err := config.LoadRules(); err != nil {
+		return err
+	}
+
+	if err := config.LoadReports(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LoadRules loads the alerting rules definitions from the filesystem, replacing the previously loaded ones.
+func (config *Config) LoadRules() error {
+	rules := make(map[string]*RuleConfig)
+
+	if err := loadDefinitions(config.RulesDir, func(name string) interface{} {
+		rules[name] = &RuleConfig{}
+		return rules[name]
+	}); err != nil {
+		return fmt.Errorf("unable to load rule definitions: %s", err)
+	}
+
+	config.Rules = rules
+
+	return nil
+}
+
+// LoadReports loads the scheduled reports definitions from the filesystem, replacing the previously loaded ones.
+func (config *Config) LoadReports() error {
+	reports := make(map[string]*ReportConfig)
+
+	if err := loadDefinitions(config.ReportsDir, func(name string) interface{} {
+		reports[name] = &ReportConfig{}
+		return reports[name]
+	}); err != nil {
+		return fmt.Errorf("unable to load report definitions: %s", err)
+	}
+
+	config.Reports = reports
+
 	return nil
 }
 
+// loadDefinitions loads the JSON definition files found in a directory, if existing, each definition being named
+// after its file name.
+func loadDefinitions(dirPath string, newDefinition func(name string) interface{}) error {
+	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	walkFunc := func(filePath string, fileInfo os.FileInfo, err error) error {
+		if fileInfo.IsDir() || !strings.HasSuffix(filePath, ".json") {
+			return nil
+		}
+
+		_, name := path.Split(strings.TrimSuffix(filePath, ".json"))
+
+		if _, err = utils.JSONLoad(filePath, newDefinition(name)); err != nil {
+			return fmt.Errorf("in %s, %s", filePath, err)
+		}
+
+		return nil
+	}
+
+	return utils.WalkDir(dirPath, walkFunc)
+}
+
 func getSetting(config map[string]interface{}, setting string, kind reflect.Kind,
 	mandatory bool, fallbackValue interface{}) (interface{}, error) {
 
@@ -0,0 +1,13 @@
+package config
+
+// ReportConfig represents a scheduled report definition in the configuration system.
+type ReportConfig struct {
+	Collection string `json:"collection"`
+	Schedule   string `json:"schedule"`
+	Range      string `json:"range"`
+	Format     string `json:"format"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	OutputDir  string `json:"output_dir"`
+	Webhook    string `json:"webhook"`
+}
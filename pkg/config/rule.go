@@ -0,0 +1,19 @@
+package config
+
+// RuleConfig represents an alerting rule definition in the configuration system.
+type RuleConfig struct {
+	Graph     string            `json:"graph"`
+	Origin    string            `json:"origin"`
+	Source    string            `json:"source"`
+	Metric    string            `json:"metric"`
+	Series    string            `json:"series"`
+	Variables map[string]string `json:"variables"`
+	Range     string            `json:"range"`
+	Interval  int               `json:"interval"`
+	Summary   string            `json:"summary"`
+	Operator  string            `json:"operator"`
+	Warning   *float64          `json:"warning"`
+	Critical  *float64          `json:"critical"`
+	Webhooks  []string          `json:"webhooks"`
+	Exec      string            `json:"exec"`
+}
@@ -0,0 +1,387 @@
+// +build opentsdb
+
+package connector
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/catalog"
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/plot"
+	"github.com/facette/facette/pkg/utils"
+)
+
+const (
+	opentsdbURLSuggest       string  = "/api/suggest"
+	opentsdbURLLookup        string  = "/api/search/lookup"
+	opentsdbURLQuery         string  = "/api/query"
+	opentsdbDefaultSourceTag string  = "host"
+	opentsdbDefaultTimeout   float64 = 10
+	opentsdbDefaultMaxSeries int     = 100000
+)
+
+type opentsdbLookupResponse struct {
+	Results []opentsdbLookupResult `json:"results"`
+}
+
+type opentsdbLookupResult struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags"`
+}
+
+type opentsdbQueryRequest struct {
+	Start   int64                 `json:"start"`
+	End     int64                 `json:"end"`
+	Queries []opentsdbQueryMetric `json:"queries"`
+}
+
+type opentsdbQueryMetric struct {
+	Aggregator string            `json:"aggregator"`
+	Metric     string            `json:"metric"`
+	Tags       map[string]string `json:"tags"`
+	Downsample string            `json:"downsample,omitempty"`
+}
+
+type opentsdbQueryResult struct {
+	Metric string             `json:"metric"`
+	Tags   map[string]string  `json:"tags"`
+	DPS    map[string]float64 `json:"dps"`
+}
+
+type opentsdbSeries struct {
+	Metric string
+	Tags   map[string]string
+}
+
+// OpenTSDBConnector represents the main structure of the OpenTSDB connector.
+type OpenTSDBConnector struct {
+	name        string
+	URL         string
+	sourceTag   string
+	maxSeries   int
+	insecureTLS bool
+	timeout     float64
+	series      map[string]map[string]*opentsdbSeries
+}
+
+func init() {
+	Connectors["opentsdb"] = func(name string, settings map[string]interface{}) (Connector, error) {
+		var err error
+
+		connector := &OpenTSDBConnector{
+			name:        name,
+			insecureTLS: false,
+			series:      make(map[string]map[string]*opentsdbSeries),
+		}
+
+		if connector.URL, err = config.GetString(settings, "url", true); err != nil {
+			return nil, err
+		}
+
+		if connector.sourceTag, err = config.GetString(settings, "source_tag", false); err != nil {
+			return nil, err
+		}
+
+		if connector.maxSeries, err = config.GetInt(settings, "max_series", false); err != nil {
+			return nil, err
+		}
+
+		if connector.insecureTLS, err = config.GetBool(settings, "allow_insecure_tls", false); err != nil {
+			return nil, err
+		}
+
+		if connector.timeout, err = config.GetFloat(settings, "timeout", false); err != nil {
+			return nil, err
+		}
+
+		if connector.sourceTag == "" {
+			connector.sourceTag = opentsdbDefaultSourceTag
+		}
+
+		if connector.maxSeries <= 0 {
+			connector.maxSeries = opentsdbDefaultMaxSeries
+		}
+
+		// Enforce minimal timeout value bound
+		if connector.timeout <= 0 {
+			connector.timeout = opentsdbDefaultTimeout
+		}
+
+		return connector, nil
+	}
+}
+
+// GetPlots retrieves time series data from provider based on a query and a time interval.
+func (connector *OpenTSDBConnector) GetPlots(query *plot.Query) ([]plot.Series, error) {
+	var resultSeries []plot.Series
+
+	if len(query.Group.Series) == 0 {
+		return nil, fmt.Errorf("opentsdb[%s]: group has no series", connector.name)
+	}
+
+	step := 1
+	if query.Sample > 0 {
+		step = int(query.EndTime.Sub(query.StartTime).Seconds()) / query.Sample
+	}
+
+	if step < 1 {
+		step = 1
+	}
+
+	for _, series := range query.Group.Series {
+		var queryResults []opentsdbQueryResult
+
+		entry, ok := connector.series[series.Metric.Source][series.Metric.Name]
+		if !ok {
+			return nil, fmt.Errorf("opentsdb[%s]: unknown series `%s'", connector.name, series.Metric)
+		}
+
+		queryRequest := opentsdbQueryRequest{
+			Start: query.StartTime.Unix(),
+			End:   query.EndTime.Unix(),
+			Queries: []opentsdbQueryMetric{
+				opentsdbQueryMetric{
+					Aggregator: "sum",
+					Metric:     entry.Metric,
+					Tags:       entry.Tags,
+					Downsample: fmt.Sprintf("%ds-avg", step),
+				},
+			},
+		}
+
+		body, err := json.Marshal(queryRequest)
+		if err != nil {
+			return nil, fmt.Errorf("opentsdb[%s]: unable to marshal query: %s", connector.name, err)
+		}
+
+		if err = connector.request("POST", opentsdbURLQuery, bytes.NewReader(body), &queryResults); err != nil {
+			return nil, fmt.Errorf("opentsdb[%s]: %s", connector.name, err)
+		}
+
+		resultEntry, err := opentsdbExtractResult(queryResults, step)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"opentsdb[%s]: unable to extract plot values from backend response: %s",
+				connector.name,
+				err,
+			)
+		}
+
+		resultEntry.Name = series.Metric.Name
+
+		if scale, _ := config.GetFloat(series.Options, "scale", false); scale != 0 {
+			resultEntry.Scale(plot.Value(scale))
+		}
+
+		resultSeries = append(resultSeries, resultEntry)
+	}
+
+	if query.Group.Type == OperGroupTypeSum {
+		sumSeries, err := plot.SumSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("opentsdb[%s]: unable to sum series: %s", connector.name, err)
+		}
+
+		return []plot.Series{sumSeries}, nil
+	} else if query.Group.Type == OperGroupTypeAvg {
+		avgSeries, err := plot.AvgSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("opentsdb[%s]: unable to average series: %s", connector.name, err)
+		}
+
+		return []plot.Series{avgSeries}, nil
+	}
+
+	return resultSeries, nil
+}
+
+// Refresh triggers a full connector data update.
+func (connector *OpenTSDBConnector) Refresh(originName string, outputChan chan *catalog.Record) error {
+	var metricsList []string
+
+	URLQuery := url.Values{}
+	URLQuery.Set("type", "metrics")
+	URLQuery.Set("max", strconv.Itoa(connector.maxSeries))
+
+	if err := connector.request("GET", opentsdbURLSuggest+"?"+URLQuery.Encode(), nil, &metricsList); err != nil {
+		return fmt.Errorf("opentsdb[%s]: %s", connector.name, err)
+	}
+
+	for _, metric := range metricsList {
+		lookupResponse := opentsdbLookupResponse{}
+
+		URLQuery := url.Values{}
+		URLQuery.Set("m", fmt.Sprintf("%s{%s=*}", metric, connector.sourceTag))
+		URLQuery.Set("limit", strconv.Itoa(connector.maxSeries))
+
+		if err := connector.request("GET", opentsdbURLLookup+"?"+URLQuery.Encode(), nil,
+			&lookupResponse); err != nil {
+			return fmt.Errorf("opentsdb[%s]: %s", connector.name, err)
+		}
+
+		for _, result := range lookupResponse.Results {
+			sourceName := result.Tags[connector.sourceTag]
+			if sourceName == "" {
+				continue
+			}
+
+			metricName := opentsdbFormatMetric(metric, result.Tags, connector.sourceTag)
+
+			if _, ok := connector.series[sourceName]; !ok {
+				connector.series[sourceName] = make(map[string]*opentsdbSeries)
+			}
+
+			connector.series[sourceName][metricName] = &opentsdbSeries{
+				Metric: metric,
+				Tags:   result.Tags,
+			}
+
+			outputChan <- &catalog.Record{
+				Origin:    originName,
+				Source:    sourceName,
+				Metric:    metricName,
+				Connector: connector,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (connector *OpenTSDBConnector) request(method, path string, body io.Reader, result interface{}) error {
+	httpTransport := &http.Transport{
+		Dial: (&net.Dialer{
+			// Enable dual IPv4/IPv6 stack connectivity:
+			DualStack: true,
+			// Enforce HTTP connection timeout:
+			Timeout: time.Duration(connector.timeout) * time.Second,
+		}).Dial,
+	}
+
+	if connector.insecureTLS {
+		httpTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	httpClient := http.Client{Transport: httpTransport}
+
+	request, err := http.NewRequest(method, strings.TrimSuffix(connector.URL, "/")+path, body)
+	if err != nil {
+		return fmt.Errorf("unable to set up HTTP request: %s", err)
+	}
+
+	if body != nil {
+		request.Header.Add("Content-Type", "application/json")
+	}
+
+	request.Header.Add("User-Agent", "Facette")
+	request.Header.Add("X-Requested-With", "OpenTSDBConnector")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to perform HTTP request: %s", err)
+	}
+
+	defer response.Body.Close()
+
+	if err = opentsdbCheckBackendResponse(response); err != nil {
+		return fmt.Errorf("invalid HTTP backend response: %s", err)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read HTTP response body: %s", err)
+	}
+
+	if err = json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("unable to unmarshal JSON data: %s", err)
+	}
+
+	return nil
+}
+
+func opentsdbCheckBackendResponse(response *http.Response) error {
+	if response.StatusCode != 200 {
+		return fmt.Errorf("got HTTP status code %d, expected 200", response.StatusCode)
+	}
+
+	if utils.HTTPGetContentType(response) != "application/json" {
+		return fmt.Errorf("got HTTP content type `%s', expected `application/json'", response.Header["Content-Type"])
+	}
+
+	return nil
+}
+
+func opentsdbFormatMetric(metric string, tags map[string]string, sourceTag string) string {
+	var keys, chunks []string
+
+	for key := range tags {
+		if key == sourceTag {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return metric
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		chunks = append(chunks, key+"="+tags[key])
+	}
+
+	return metric + "{" + strings.Join(chunks, ",") + "}"
+}
+
+func opentsdbExtractResult(results []opentsdbQueryResult, step int) (plot.Series, error) {
+	var timestamps []int
+
+	series := plot.Series{
+		Summary: make(map[string]plot.Value),
+		Step:    step,
+	}
+
+	// Queries include the full tags set, thus a query must not return more than one series
+	if len(results) == 0 {
+		return series, nil
+	} else if len(results) > 1 {
+		return series, fmt.Errorf("got %d series, expected 1", len(results))
+	}
+
+	values := make(map[int]float64)
+
+	for key, value := range results[0].DPS {
+		timestamp, err := strconv.Atoi(key)
+		if err != nil {
+			return series, fmt.Errorf("invalid timestamp `%s'", key)
+		}
+
+		timestamps = append(timestamps, timestamp)
+		values[timestamp] = value
+	}
+
+	sort.Ints(timestamps)
+
+	for _, timestamp := range timestamps {
+		series.Plots = append(series.Plots, plot.Plot{
+			Value: plot.Value(values[timestamp]),
+			Time:  time.Unix(int64(timestamp), 0),
+		})
+	}
+
+	return series, nil
+}
@@ -0,0 +1,187 @@
+// +build opentsdb
+
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/catalog"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func opentsdbTestServer(test *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		switch request.URL.Path {
+		case opentsdbURLSuggest:
+			fmt.Fprint(writer, `["sys.cpu.user","sys.load"]`)
+
+		case opentsdbURLLookup:
+			switch request.FormValue("m") {
+			case "sys.cpu.user{host=*}":
+				fmt.Fprint(writer, `{"type":"LOOKUP","results":[
+					{"metric":"sys.cpu.user","tags":{"host":"web01","cpu":"0"}},
+					{"metric":"sys.cpu.user","tags":{"host":"web01","cpu":"1"}}
+				]}`)
+
+			case "sys.load{host=*}":
+				fmt.Fprint(writer, `{"type":"LOOKUP","results":[
+					{"metric":"sys.load","tags":{"host":"web01"}},
+					{"metric":"sys.load","tags":{"host":"web02"}}
+				]}`)
+
+			default:
+				fmt.Fprint(writer, `{"type":"LOOKUP","results":[]}`)
+			}
+
+		case opentsdbURLQuery:
+			queryRequest := opentsdbQueryRequest{}
+
+			body, _ := ioutil.ReadAll(request.Body)
+			if err := json.Unmarshal(body, &queryRequest); err != nil || len(queryRequest.Queries) != 1 {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if queryRequest.Queries[0].Downsample != "60s-avg" {
+				test.Logf("\nExpected %q\nbut got  %q", "60s-avg", queryRequest.Queries[0].Downsample)
+				test.Fail()
+			}
+
+			switch queryRequest.Queries[0].Tags["host"] {
+			case "web01":
+				fmt.Fprint(writer, `[{"metric":"sys.load","dps":{"120":3,"0":1,"60":2,"180":4}}]`)
+
+			case "web02":
+				fmt.Fprint(writer, `[{"metric":"sys.load","dps":{"0":5,"60":6,"120":7,"180":8}}]`)
+
+			default:
+				fmt.Fprint(writer, `[]`)
+			}
+
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func Test_OpenTSDBRefresh(test *testing.T) {
+	server := opentsdbTestServer(test)
+	defer server.Close()
+
+	connector, err := Connectors["opentsdb"]("test", map[string]interface{}{"url": server.URL})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	recordChan := make(chan *catalog.Record)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- connector.Refresh("opentsdb", recordChan)
+		close(recordChan)
+	}()
+
+	result := []string{}
+	for record := range recordChan {
+		result = append(result, record.Source+"/"+record.Metric)
+	}
+
+	if err = <-errChan; err != nil {
+		test.Fatal(err)
+	}
+
+	sort.Strings(result)
+
+	expected := []string{
+		"web01/sys.cpu.user{cpu=0}",
+		"web01/sys.cpu.user{cpu=1}",
+		"web01/sys.load",
+		"web02/sys.load",
+	}
+
+	if !reflect.DeepEqual(expected, result) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, result)
+		test.Fail()
+	}
+}
+
+func Test_OpenTSDBGetPlots(test *testing.T) {
+	server := opentsdbTestServer(test)
+	defer server.Close()
+
+	connector, err := Connectors["opentsdb"]("test", map[string]interface{}{"url": server.URL})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	recordChan := make(chan *catalog.Record)
+
+	go func() {
+		connector.Refresh("opentsdb", recordChan)
+		close(recordChan)
+	}()
+
+	for _ = range recordChan {
+	}
+
+	query := &plot.Query{
+		Group: &plot.QueryGroup{
+			Type: OperGroupTypeNone,
+			Series: []*plot.QuerySeries{
+				&plot.QuerySeries{Metric: &plot.QueryMetric{Name: "sys.load", Source: "web01"}},
+			},
+		},
+		StartTime: time.Unix(0, 0),
+		EndTime:   time.Unix(240, 0),
+		Sample:    4,
+	}
+
+	series, err := connector.GetPlots(query)
+	if err != nil {
+		test.Fatal(err)
+	} else if len(series) != 1 {
+		test.Fatalf("\nExpected %d series\nbut got  %d", 1, len(series))
+	}
+
+	expected := []plot.Plot{
+		{Time: time.Unix(0, 0), Value: 1},
+		{Time: time.Unix(60, 0), Value: 2},
+		{Time: time.Unix(120, 0), Value: 3},
+		{Time: time.Unix(180, 0), Value: 4},
+	}
+
+	if !reflect.DeepEqual(expected, series[0].Plots) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, series[0].Plots)
+		test.Fail()
+	}
+
+	// Test operation group
+	query.Group.Type = OperGroupTypeAvg
+	query.Group.Series = append(query.Group.Series, &plot.QuerySeries{
+		Metric: &plot.QueryMetric{Name: "sys.load", Source: "web02"},
+	})
+
+	series, err = connector.GetPlots(query)
+	if err != nil {
+		test.Fatal(err)
+	} else if len(series) != 1 {
+		test.Fatalf("\nExpected %d series\nbut got  %d", 1, len(series))
+	}
+
+	for i, value := range []plot.Value{3, 4, 5, 6} {
+		if series[0].Plots[i].Value != value {
+			test.Logf("\nExpected %v\nbut got  %v", value, series[0].Plots[i].Value)
+			test.Fail()
+		}
+	}
+}
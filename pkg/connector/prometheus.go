@@ -0,0 +1,443 @@
+// +build prometheus
+
+package connector
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/catalog"
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/plot"
+	"github.com/facette/facette/pkg/utils"
+)
+
+const (
+	prometheusURLLabelValues   string  = "/api/v1/label/__name__/values"
+	prometheusURLSeries        string  = "/api/v1/series"
+	prometheusURLQueryRange    string  = "/api/v1/query_range"
+	prometheusDefaultTimeout   float64 = 10
+	prometheusStatusSuccess    string  = "success"
+	prometheusResultTypeMatrix string  = "matrix"
+)
+
+type prometheusResponse struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+type prometheusQueryData struct {
+	ResultType string                  `json:"resultType"`
+	Result     []prometheusQueryResult `json:"result"`
+}
+
+type prometheusQueryResult struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+// PrometheusConnector represents the main structure of the Prometheus connector.
+type PrometheusConnector struct {
+	name        string
+	URL         string
+	insecureTLS bool
+	timeout     float64
+	re          *regexp.Regexp
+	series      map[string]map[string]string
+}
+
+func init() {
+	Connectors["prometheus"] = func(name string, settings map[string]interface{}) (Connector, error) {
+		var (
+			pattern string
+			err     error
+		)
+
+		connector := &PrometheusConnector{
+			name:        name,
+			insecureTLS: false,
+			series:      make(map[string]map[string]string),
+		}
+
+		if connector.URL, err = config.GetString(settings, "url", true); err != nil {
+			return nil, err
+		}
+
+		if connector.insecureTLS, err = config.GetBool(settings, "allow_insecure_tls", false); err != nil {
+			return nil, err
+		}
+
+		if connector.timeout, err = config.GetFloat(settings, "timeout", false); err != nil {
+			return nil, err
+		}
+
+		if pattern, err = config.GetString(settings, "pattern", true); err != nil {
+			return nil, err
+		}
+
+		// Check and compile regexp pattern
+		if connector.re, err = compilePattern(pattern); err != nil {
+			return nil, fmt.Errorf("unable to compile regexp pattern: %s", err)
+		}
+
+		// Enforce minimal timeout value bound
+		if connector.timeout <= 0 {
+			connector.timeout = prometheusDefaultTimeout
+		}
+
+		return connector, nil
+	}
+}
+
+// GetPlots retrieves time series data from provider based on a query and a time interval.
+func (connector *PrometheusConnector) GetPlots(query *plot.Query) ([]plot.Series, error) {
+	var resultSeries []plot.Series
+
+	if len(query.Group.Series) == 0 {
+		return nil, fmt.Errorf("prometheus[%s]: group has no series", connector.name)
+	}
+
+	step := 1
+	if query.Sample > 0 {
+		step = int(query.EndTime.Sub(query.StartTime).Seconds()) / query.Sample
+	}
+
+	if step < 1 {
+		step = 1
+	}
+
+	for _, series := range query.Group.Series {
+		selector, ok := connector.series[series.Metric.Source][series.Metric.Name]
+		if !ok {
+			return nil, fmt.Errorf("prometheus[%s]: unknown series `%s'", connector.name, series.Metric)
+		}
+
+		URLQuery := url.Values{}
+		URLQuery.Set("query", selector)
+		URLQuery.Set("start", strconv.FormatInt(query.StartTime.Unix(), 10))
+		URLQuery.Set("end", strconv.FormatInt(query.EndTime.Unix(), 10))
+		URLQuery.Set("step", strconv.Itoa(step))
+
+		queryData := prometheusQueryData{}
+
+		if err := connector.request(prometheusURLQueryRange, URLQuery, &queryData); err != nil {
+			return nil, fmt.Errorf("prometheus[%s]: %s", connector.name, err)
+		} else if queryData.ResultType != prometheusResultTypeMatrix {
+			return nil, fmt.Errorf(
+				"prometheus[%s]: got result type `%s', expected `%s'",
+				connector.name,
+				queryData.ResultType,
+				prometheusResultTypeMatrix,
+			)
+		}
+
+		resultEntry, err := prometheusExtractResult(queryData.Result, step)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"prometheus[%s]: unable to extract plot values from backend response: %s",
+				connector.name,
+				err,
+			)
+		}
+
+		resultEntry.Name = series.Metric.Name
+
+		if scale, _ := config.GetFloat(series.Options, "scale", false); scale != 0 {
+			resultEntry.Scale(plot.Value(scale))
+		}
+
+		resultSeries = append(resultSeries, resultEntry)
+	}
+
+	if query.Group.Type == OperGroupTypeSum {
+		sumSeries, err := plot.SumSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus[%s]: unable to sum series: %s", connector.name, err)
+		}
+
+		return []plot.Series{sumSeries}, nil
+	} else if query.Group.Type == OperGroupTypeAvg {
+		avgSeries, err := plot.AvgSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus[%s]: unable to average series: %s", connector.name, err)
+		}
+
+		return []plot.Series{avgSeries}, nil
+	}
+
+	return resultSeries, nil
+}
+
+// Refresh triggers a full connector data update.
+func (connector *PrometheusConnector) Refresh(originName string, outputChan chan *catalog.Record) error {
+	var metricsList []string
+
+	if err := connector.request(prometheusURLLabelValues, nil, &metricsList); err != nil {
+		return fmt.Errorf("prometheus[%s]: %s", connector.name, err)
+	}
+
+	for _, metric := range metricsList {
+		var labelsList []map[string]string
+
+		if err := connector.request(prometheusURLSeries, url.Values{"match[]": {metric}}, &labelsList); err != nil {
+			return fmt.Errorf("prometheus[%s]: %s", connector.name, err)
+		}
+
+		for _, labels := range labelsList {
+			var sourceName, metricName string
+
+			series := prometheusFormatSelector(labels)
+
+			seriesMatch, err := matchSeriesPattern(connector.re, series)
+			if err != nil {
+				logger.Log(
+					logger.LevelInfo,
+					"connector",
+					"prometheus[%s]: series `%s' does not match pattern, ignoring",
+					connector.name,
+					series,
+				)
+				continue
+			}
+
+			sourceName = seriesMatch[0]
+			metricName = prometheusMetricName(seriesMatch[1], prometheusSourceLabels(connector.re, labels), labels)
+
+			if _, ok := connector.series[sourceName]; !ok {
+				connector.series[sourceName] = make(map[string]string)
+			}
+
+			connector.series[sourceName][metricName] = series
+
+			outputChan <- &catalog.Record{
+				Origin:    originName,
+				Source:    sourceName,
+				Metric:    metricName,
+				Connector: connector,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (connector *PrometheusConnector) request(path string, query url.Values, result interface{}) error {
+	httpTransport := &http.Transport{
+		Dial: (&net.Dialer{
+			// Enable dual IPv4/IPv6 stack connectivity:
+			DualStack: true,
+			// Enforce HTTP connection timeout:
+			Timeout: time.Duration(connector.timeout) * time.Second,
+		}).Dial,
+	}
+
+	if connector.insecureTLS {
+		httpTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	httpClient := http.Client{Transport: httpTransport}
+
+	URL := strings.TrimSuffix(connector.URL, "/") + path
+	if len(query) > 0 {
+		URL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequest("GET", URL, nil)
+	if err != nil {
+		return fmt.Errorf("unable to set up HTTP request: %s", err)
+	}
+
+	request.Header.Add("User-Agent", "Facette")
+	request.Header.Add("X-Requested-With", "PrometheusConnector")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to perform HTTP request: %s", err)
+	}
+
+	defer response.Body.Close()
+
+	if err = prometheusCheckBackendResponse(response); err != nil {
+		return fmt.Errorf("invalid HTTP backend response: %s", err)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read HTTP response body: %s", err)
+	}
+
+	prometheusResult := prometheusResponse{}
+
+	if err = json.Unmarshal(data, &prometheusResult); err != nil {
+		return fmt.Errorf("unable to unmarshal JSON data: %s", err)
+	} else if prometheusResult.Status != prometheusStatusSuccess {
+		return fmt.Errorf("backend returned an error: %s", prometheusResult.Error)
+	}
+
+	if err = json.Unmarshal(prometheusResult.Data, result); err != nil {
+		return fmt.Errorf("unable to unmarshal JSON data: %s", err)
+	}
+
+	return nil
+}
+
+func prometheusCheckBackendResponse(response *http.Response) error {
+	if response.StatusCode != 200 {
+		return fmt.Errorf("got HTTP status code %d, expected 200", response.StatusCode)
+	}
+
+	if utils.HTTPGetContentType(response) != "application/json" {
+		return fmt.Errorf("got HTTP content type `%s', expected `application/json'", response.Header["Content-Type"])
+	}
+
+	return nil
+}
+
+func prometheusFormatSelector(labels map[string]string) string {
+	var keys, chunks []string
+
+	for key := range labels {
+		if key == "__name__" {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		chunks = append(chunks, fmt.Sprintf("%s=%s", key, strconv.Quote(labels[key])))
+	}
+
+	return labels["__name__"] + "{" + strings.Join(chunks, ",") + "}"
+}
+
+// prometheusSourceLabels returns the keys of the series labels matched by the pattern `source' keyword.
+func prometheusSourceLabels(re *regexp.Regexp, labels map[string]string) map[string]bool {
+	var keys []string
+
+	result := make(map[string]bool)
+
+	index := -1
+	for i, name := range re.SubexpNames() {
+		if name == "source" {
+			index = i
+			break
+		}
+	}
+
+	submatch := re.FindStringSubmatchIndex(prometheusFormatSelector(labels))
+	if index == -1 || len(submatch) == 0 || submatch[2*index] == -1 {
+		return result
+	}
+
+	start, end := submatch[2*index], submatch[2*index+1]
+
+	for key := range labels {
+		if key != "__name__" {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	// Walk through the selector labels as formatted by prometheusFormatSelector, checking for values overlapping
+	// the source submatch
+	offset := len(labels["__name__"]) + 1
+
+	for _, key := range keys {
+		valueStart := offset + len(key) + 1
+		valueEnd := valueStart + len(strconv.Quote(labels[key]))
+
+		if start < valueEnd && end > valueStart {
+			result[key] = true
+		}
+
+		offset = valueEnd + 1
+	}
+
+	return result
+}
+
+// prometheusMetricName appends to a metric name the series labels not used to build the source name, series
+// differing only by such labels (e.g. `cpu') being otherwise indistinguishable.
+func prometheusMetricName(metricName string, sourceLabels map[string]bool, labels map[string]string) string {
+	var keys, chunks []string
+
+	// Skip metric names already including labels from the pattern
+	if strings.Contains(metricName, "{") {
+		return metricName
+	}
+
+	for key := range labels {
+		if key == "__name__" || sourceLabels[key] {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return metricName
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		chunks = append(chunks, fmt.Sprintf("%s=%s", key, strconv.Quote(labels[key])))
+	}
+
+	return metricName + "{" + strings.Join(chunks, ",") + "}"
+}
+
+func prometheusExtractResult(results []prometheusQueryResult, step int) (plot.Series, error) {
+	series := plot.Series{
+		Summary: make(map[string]plot.Value),
+		Step:    step,
+	}
+
+	// Selectors include the full label set, thus a query must not return more than one series
+	if len(results) == 0 {
+		return series, nil
+	} else if len(results) > 1 {
+		return series, fmt.Errorf("got %d series, expected 1", len(results))
+	}
+
+	for _, plotPoint := range results[0].Values {
+		timestamp, ok := plotPoint[0].(float64)
+		if !ok {
+			return series, fmt.Errorf("invalid timestamp `%v'", plotPoint[0])
+		}
+
+		valueString, ok := plotPoint[1].(string)
+		if !ok {
+			return series, fmt.Errorf("invalid value `%v'", plotPoint[1])
+		}
+
+		value, err := strconv.ParseFloat(valueString, 64)
+		if err != nil {
+			value = math.NaN()
+		}
+
+		series.Plots = append(series.Plots, plot.Plot{
+			Value: plot.Value(value),
+			Time:  time.Unix(int64(timestamp), 0),
+		})
+	}
+
+	return series, nil
+}
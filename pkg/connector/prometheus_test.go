@@ -0,0 +1,203 @@
+// +build prometheus
+
+package connector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/catalog"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func prometheusTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+
+		switch request.URL.Path {
+		case prometheusURLLabelValues:
+			fmt.Fprint(writer, `{"status":"success","data":["node_cpu","node_load1","up"]}`)
+
+		case prometheusURLSeries:
+			switch request.FormValue("match[]") {
+			case "node_load1":
+				fmt.Fprint(writer, `{"status":"success","data":[
+					{"__name__":"node_load1","instance":"host1","job":"node"},
+					{"__name__":"node_load1","instance":"host2","job":"node"},
+					{"__name__":"node_load1","instance":"web","job":"webserver"}
+				]}`)
+
+			case "up":
+				fmt.Fprint(writer, `{"status":"success","data":[
+					{"__name__":"up","instance":"host1"}
+				]}`)
+
+			case "node_cpu":
+				fmt.Fprint(writer, `{"status":"success","data":[
+					{"__name__":"node_cpu","instance":"host1","mode":"idle"},
+					{"__name__":"node_cpu","instance":"host1","mode":"user"}
+				]}`)
+
+			default:
+				fmt.Fprint(writer, `{"status":"success","data":[]}`)
+			}
+
+		case prometheusURLQueryRange:
+			switch request.FormValue("query") {
+			case `node_load1{instance="host1",job="node"}`:
+				fmt.Fprint(writer, `{"status":"success","data":{"resultType":"matrix","result":[
+					{"metric":{"__name__":"node_load1"},"values":[[0,"1"],[60,"2"],[120,"NaN"],[180,"4"]]}
+				]}}`)
+
+			case `node_load1{instance="host2",job="node"}`:
+				fmt.Fprint(writer, `{"status":"success","data":{"resultType":"matrix","result":[
+					{"metric":{"__name__":"node_load1"},"values":[[0,"3"],[60,"4"],[120,"5"],[180,"6"]]}
+				]}}`)
+
+			default:
+				writer.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(writer, `{"status":"error","error":"unknown query"}`)
+			}
+
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func Test_PrometheusRefresh(test *testing.T) {
+	server := prometheusTestServer()
+	defer server.Close()
+
+	connector, err := Connectors["prometheus"]("test", map[string]interface{}{
+		"url":     server.URL,
+		"pattern": "(?P<metric>[^{]+)\\{instance=\"(?P<source>[^\"]+)\".*\\}",
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	recordChan := make(chan *catalog.Record)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- connector.Refresh("prometheus", recordChan)
+		close(recordChan)
+	}()
+
+	result := []string{}
+	for record := range recordChan {
+		result = append(result, record.Source+"/"+record.Metric)
+	}
+
+	if err = <-errChan; err != nil {
+		test.Fatal(err)
+	}
+
+	sort.Strings(result)
+
+	expected := []string{
+		`host1/node_cpu{mode="idle"}`,
+		`host1/node_cpu{mode="user"}`,
+		`host1/node_load1{job="node"}`,
+		"host1/up",
+		`host2/node_load1{job="node"}`,
+		`web/node_load1{job="webserver"}`,
+	}
+
+	if !reflect.DeepEqual(expected, result) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, result)
+		test.Fail()
+	}
+}
+
+func Test_PrometheusGetPlots(test *testing.T) {
+	server := prometheusTestServer()
+	defer server.Close()
+
+	connector, err := Connectors["prometheus"]("test", map[string]interface{}{
+		"url":     server.URL,
+		"pattern": "(?P<metric>[^{]+)\\{instance=\"(?P<source>[^\"]+)\".*\\}",
+	})
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	recordChan := make(chan *catalog.Record)
+
+	go func() {
+		connector.Refresh("prometheus", recordChan)
+		close(recordChan)
+	}()
+
+	for _ = range recordChan {
+	}
+
+	query := &plot.Query{
+		Group: &plot.QueryGroup{
+			Type: OperGroupTypeNone,
+			Series: []*plot.QuerySeries{
+				&plot.QuerySeries{
+					Metric:  &plot.QueryMetric{Name: `node_load1{job="node"}`, Source: "host1"},
+					Options: map[string]interface{}{"scale": 2.0},
+				},
+			},
+		},
+		StartTime: time.Unix(0, 0),
+		EndTime:   time.Unix(240, 0),
+		Sample:    4,
+	}
+
+	series, err := connector.GetPlots(query)
+	if err != nil {
+		test.Fatal(err)
+	} else if len(series) != 1 {
+		test.Fatalf("\nExpected %d series\nbut got  %d", 1, len(series))
+	}
+
+	expected := []plot.Value{2, 4, 0, 8}
+
+	for i, entry := range series[0].Plots {
+		if i == 2 && entry.Value.IsNaN() {
+			continue
+		} else if entry.Value != expected[i] || entry.Time.Unix() != int64(i*60) {
+			test.Logf("\nExpected %v at %d\nbut got  %v at %d", expected[i], i*60, entry.Value, entry.Time.Unix())
+			test.Fail()
+		}
+	}
+
+	// Test operation group
+	query.Group.Type = OperGroupTypeSum
+	query.Group.Series = append(query.Group.Series, &plot.QuerySeries{
+		Metric: &plot.QueryMetric{Name: `node_load1{job="node"}`, Source: "host2"},
+	})
+
+	series, err = connector.GetPlots(query)
+	if err != nil {
+		test.Fatal(err)
+	} else if len(series) != 1 {
+		test.Fatalf("\nExpected %d series\nbut got  %d", 1, len(series))
+	}
+
+	expected = []plot.Value{5, 8, 5, 14}
+
+	for i, entry := range series[0].Plots {
+		if entry.Value != expected[i] {
+			test.Logf("\nExpected %v\nbut got  %v", expected[i], entry.Value)
+			test.Fail()
+		}
+	}
+
+	// Test unknown series
+	query.Group.Series[0].Metric.Source = "host3"
+
+	if _, err = connector.GetPlots(query); err == nil {
+		test.Logf("\nExpected error\nbut got  nil")
+		test.Fail()
+	}
+}
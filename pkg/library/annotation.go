@@ -0,0 +1,79 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Annotation represents a time-stamped event (e.g. a deployment or an incident) to be displayed along with graphs.
+type Annotation struct {
+	Item
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Tags  []string  `json:"tags"`
+}
+
+func (annotation *Annotation) String() string {
+	return fmt.Sprintf(
+		"Annotation{ID:\"%s\" Name:\"%s\" Start:%s End:%s Tags:%v}",
+		annotation.ID,
+		annotation.Name,
+		annotation.Start.Format(time.RFC3339),
+		annotation.End.Format(time.RFC3339),
+		annotation.Tags,
+	)
+}
+
+// HasTag returns whether or not an annotation is tagged with at least one of the given tags.
+func (annotation *Annotation) HasTag(tags ...string) bool {
+	for _, tag := range tags {
+		for _, annotationTag := range annotation.Tags {
+			if strings.EqualFold(annotationTag, tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// FilterAnnotations returns the annotations overlapping a time range, sorted by start time. If tags are given, only
+// the annotations having at least one of them are returned.
+func (library *Library) FilterAnnotations(startTime, endTime time.Time, tags []string) []*Annotation {
+	result := make([]*Annotation, 0)
+
+	for _, annotation := range library.Annotations {
+		if !startTime.IsZero() && annotation.End.Before(startTime) ||
+			!endTime.IsZero() && annotation.Start.After(endTime) {
+			continue
+		} else if len(tags) > 0 && !annotation.HasTag(tags...) {
+			continue
+		}
+
+		result = append(result, annotation)
+	}
+
+	sort.Sort(annotationList(result))
+
+	return result
+}
+
+type annotationList []*Annotation
+
+func (list annotationList) Len() int {
+	return len(list)
+}
+
+func (list annotationList) Less(i, j int) bool {
+	if !list[i].Start.Equal(list[j].Start) {
+		return list[i].Start.Before(list[j].Start)
+	}
+
+	return list[i].Name < list[j].Name
+}
+
+func (list annotationList) Swap(i, j int) {
+	list[i], list[j] = list[j], list[i]
+}
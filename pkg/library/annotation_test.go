@@ -0,0 +1,115 @@
+package library
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_AnnotationHasTag(test *testing.T) {
+	annotation := &Annotation{Tags: []string{"deploy", "Web"}}
+
+	for _, entry := range []struct {
+		Tags   []string
+		Result bool
+	}{
+		{[]string{"deploy"}, true},
+		{[]string{"DEPLOY"}, true},
+		{[]string{"web"}, true},
+		{[]string{"incident", "web"}, true},
+		{[]string{"incident"}, false},
+		{[]string{"dep"}, false},
+		{nil, false},
+	} {
+		if result := annotation.HasTag(entry.Tags...); result != entry.Result {
+			test.Logf("\nExpected %v\nbut got  %v for tags %v", entry.Result, result, entry.Tags)
+			test.Fail()
+		}
+	}
+}
+
+func Test_FilterAnnotations(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	refTime := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, annotation := range []*Annotation{
+		{Item: Item{Name: "deploy1"}, Start: refTime.Add(-2 * time.Hour), Tags: []string{"deploy"}},
+		{Item: Item{Name: "incident1"}, Start: refTime.Add(-90 * time.Minute), End: refTime.Add(-30 * time.Minute),
+			Tags: []string{"Incident", "web"}},
+		{Item: Item{Name: "deploy2"}, Start: refTime.Add(-time.Hour), Tags: []string{"deploy", "web"}},
+		{Item: Item{Name: "maintenance1"}, Start: refTime.Add(time.Hour), End: refTime.Add(2 * time.Hour)},
+	} {
+		if err := library.StoreItem(annotation, LibraryItemAnnotation, ""); err != nil {
+			test.Fatalf("unable to store annotation: %s", err)
+		}
+	}
+
+	for _, entry := range []struct {
+		Start  time.Time
+		End    time.Time
+		Tags   []string
+		Result []string
+	}{
+		{time.Time{}, time.Time{}, nil, []string{"deploy1", "incident1", "deploy2", "maintenance1"}},
+		// Point events only match ranges including their start time
+		{refTime.Add(-3 * time.Hour), refTime.Add(-2 * time.Hour), nil, []string{"deploy1"}},
+		{refTime.Add(-119 * time.Minute), refTime.Add(-100 * time.Minute), nil, []string{}},
+		// Range events match overlapping ranges
+		{refTime.Add(-45 * time.Minute), refTime.Add(-15 * time.Minute), nil, []string{"incident1"}},
+		{refTime.Add(-80 * time.Minute), refTime.Add(-70 * time.Minute), nil, []string{"incident1"}},
+		{refTime.Add(90 * time.Minute), time.Time{}, nil, []string{"maintenance1"}},
+		{time.Time{}, refTime.Add(-90 * time.Minute), nil, []string{"deploy1", "incident1"}},
+		// Tags match case-insensitively, any of them being enough
+		{time.Time{}, time.Time{}, []string{"incident"}, []string{"incident1"}},
+		{time.Time{}, time.Time{}, []string{"DEPLOY", "web"}, []string{"deploy1", "incident1", "deploy2"}},
+		{refTime.Add(-70 * time.Minute), refTime, []string{"deploy"}, []string{"deploy2"}},
+		{time.Time{}, time.Time{}, []string{"unknown"}, []string{}},
+	} {
+		result := []string{}
+		for _, annotation := range library.FilterAnnotations(entry.Start, entry.End, entry.Tags) {
+			result = append(result, annotation.Name)
+		}
+
+		if !reflect.DeepEqual(result, entry.Result) {
+			test.Logf("\nExpected %v\nbut got  %v for range [%s, %s] and tags %v", entry.Result, result, entry.Start,
+				entry.End, entry.Tags)
+			test.Fail()
+		}
+	}
+}
+
+func Test_StoreAnnotation(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	refTime := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Point events end at their start time
+	annotation := &Annotation{Item: Item{Name: "deploy1"}, Start: refTime, Tags: []string{" deploy", "deploy", ""}}
+	if err := library.StoreItem(annotation, LibraryItemAnnotation, ""); err != nil {
+		test.Fatalf("unable to store annotation: %s", err)
+	}
+
+	if !annotation.End.Equal(refTime) {
+		test.Logf("\nExpected %s\nbut got  %s", refTime, annotation.End)
+		test.Fail()
+	}
+
+	if !reflect.DeepEqual(annotation.Tags, []string{"deploy"}) {
+		test.Logf("\nExpected %v\nbut got  %v", []string{"deploy"}, annotation.Tags)
+		test.Fail()
+	}
+
+	for _, entry := range []*Annotation{
+		{Item: Item{Name: "missing1"}},
+		{Item: Item{Name: "reversed1"}, Start: refTime, End: refTime.Add(-time.Minute)},
+	} {
+		if err := library.StoreItem(entry, LibraryItemAnnotation, ""); err != os.ErrInvalid {
+			test.Logf("\nExpected %v\nbut got  %v for annotation %s", os.ErrInvalid, err, entry)
+			test.Fail()
+		}
+	}
+}
@@ -0,0 +1,521 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/logger"
+)
+
+const (
+	// BundleVersion represents the current version of the library bundle format.
+	BundleVersion = 1
+
+	// ImportPolicySkip represents the import policy keeping existing items on name conflict.
+	ImportPolicySkip = "skip"
+	// ImportPolicyRename represents the import policy storing items under a new name on name conflict.
+	ImportPolicyRename = "rename"
+	// ImportPolicyOverwrite represents the import policy replacing existing items on name conflict.
+	ImportPolicyOverwrite = "overwrite"
+)
+
+// Bundle represents a self-contained set of library items (i.e. a collection along with its sub-collections and all
+// the items they reference).
+type Bundle struct {
+	Version      int           `json:"version"`
+	Exported     time.Time     `json:"exported"`
+	SourceGroups []*Group      `json:"sourcegroups"`
+	MetricGroups []*Group      `json:"metricgroups"`
+	Scales       []*Scale      `json:"scales"`
+	Units        []*Unit       `json:"units"`
+	Graphs       []*Graph      `json:"graphs"`
+	Collections  []*Collection `json:"collections"`
+}
+
+// ImportResult represents the result of a bundle import.
+type ImportResult struct {
+	Created     int               `json:"created"`
+	Overwritten int               `json:"overwritten"`
+	Renamed     int               `json:"renamed"`
+	Skipped     int               `json:"skipped"`
+	IDs         map[string]string `json:"ids"`
+	rollback    []func()
+}
+
+// ExportCollection returns a bundle containing a collection, its sub-collections and all the graphs, groups, scales
+// and units they reference.
+func (library *Library) ExportCollection(id string) (*Bundle, error) {
+	if !library.ItemExists(id, LibraryItemCollection) {
+		return nil, os.ErrNotExist
+	}
+
+	bundle := &Bundle{Version: BundleVersion, Exported: time.Now()}
+	exported := make(map[string]bool)
+
+	// Export collections, parents coming first
+	collectionStack := []*Collection{library.Collections[id]}
+
+	for len(collectionStack) > 0 {
+		collection := collectionStack[0]
+		collectionStack = append(collectionStack[1:], collection.Children...)
+
+		collectionTemp := &Collection{}
+		if err := exportCopy(collection, collectionTemp); err != nil {
+			return nil, err
+		}
+
+		// Ownership is local to the instance
+		collectionTemp.Owner = ""
+
+		bundle.Collections = append(bundle.Collections, collectionTemp)
+
+		graphIDs := make([]string, 0, len(collection.Entries)+1)
+
+		for _, entry := range collection.Entries {
+			graphIDs = append(graphIDs, entry.ID)
+		}
+
+		if collection.Generator != nil {
+			graphIDs = append(graphIDs, collection.Generator.Graph)
+		}
+
+		for _, graphID := range graphIDs {
+			if exported[graphID] {
+				continue
+			} else if !library.ItemExists(graphID, LibraryItemGraph) {
+				logger.Log(logger.LevelWarning, "library", "unknown graph identifier `%s' in collection `%s'",
+					graphID, collection.ID)
+				continue
+			}
+
+			graphTemp := &Graph{}
+			if err := exportCopy(library.Graphs[graphID], graphTemp); err != nil {
+				return nil, err
+			}
+
+			bundle.Graphs = append(bundle.Graphs, graphTemp)
+			exported[graphID] = true
+
+			library.exportGraphReferences(graphTemp, bundle, exported)
+		}
+
+		if collection.Generator != nil {
+			library.exportGroup(LibraryGroupPrefix+strings.TrimPrefix(collection.Generator.Group, LibraryGroupPrefix),
+				collection.Generator.groupType(), bundle, exported)
+		}
+	}
+
+	return bundle, nil
+}
+
+// exportGraphReferences appends to a bundle the groups, scales and units referenced by a graph.
+func (library *Library) exportGraphReferences(graph *Graph, bundle *Bundle, exported map[string]bool) {
+	exportOptions := func(options map[string]interface{}) {
+		if unit, err := config.GetString(options, "unit", false); err == nil && unit != "" {
+			for _, item := range library.Units {
+				if item.Label == unit && !exported[item.ID] {
+					bundle.Units = append(bundle.Units, &Unit{Item: exportItem(item.Item), Label: item.Label})
+					exported[item.ID] = true
+				}
+			}
+		}
+
+		if scale, err := config.GetFloat(options, "scale", false); err == nil && scale != 0 {
+			for _, item := range library.Scales {
+				if item.Value == scale && !exported[item.ID] {
+					bundle.Scales = append(bundle.Scales, &Scale{Item: exportItem(item.Item), Value: item.Value})
+					exported[item.ID] = true
+				}
+			}
+		}
+	}
+
+	for _, group := range graph.Groups {
+		exportOptions(group.Options)
+
+		for _, series := range group.Series {
+			exportOptions(series.Options)
+			library.exportGroup(series.Source, LibraryItemSourceGroup, bundle, exported)
+			library.exportGroup(series.Metric, LibraryItemMetricGroup, bundle, exported)
+		}
+	}
+}
+
+// exportGroup appends to a bundle the source or metric group referenced by a `group:' prefixed name.
+func (library *Library) exportGroup(name string, groupType int, bundle *Bundle, exported map[string]bool) {
+	if !strings.HasPrefix(name, LibraryGroupPrefix) {
+		return
+	}
+
+	item, err := library.GetItemByName(strings.TrimPrefix(name, LibraryGroupPrefix), groupType)
+	if err != nil {
+		logger.Log(logger.LevelWarning, "library", "unknown group `%s'", name)
+		return
+	}
+
+	group := item.(*Group)
+	if exported[group.ID] {
+		return
+	}
+
+	groupTemp := &Group{}
+	if err := exportCopy(group, groupTemp); err != nil {
+		return
+	}
+
+	if groupType == LibraryItemSourceGroup {
+		bundle.SourceGroups = append(bundle.SourceGroups, groupTemp)
+	} else {
+		bundle.MetricGroups = append(bundle.MetricGroups, groupTemp)
+	}
+
+	exported[group.ID] = true
+}
+
+// ImportBundle stores the items of a bundle into the library, applying the given policy on name conflicts. Items are
+// stored under new identifiers, references between them being updated accordingly. If set, the checkOwner function is
+// called with the owner of every existing collection being either overwritten or used as parent. The import is atomic:
+// on failure, the already stored items are rolled back.
+func (library *Library) ImportBundle(bundle *Bundle, policy, author string,
+	checkOwner func(owner string) error) (*ImportResult, error) {
+
+	if policy != ImportPolicySkip && policy != ImportPolicyRename && policy != ImportPolicyOverwrite {
+		return nil, fmt.Errorf("unknown import policy `%s'", policy)
+	} else if bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version `%d'", bundle.Version)
+	}
+
+	if checkOwner == nil {
+		checkOwner = func(owner string) error { return nil }
+	}
+
+	result := &ImportResult{IDs: make(map[string]string)}
+
+	if err := library.importBundle(bundle, policy, author, checkOwner, result); err != nil {
+		for index := len(result.rollback) - 1; index >= 0; index-- {
+			result.rollback[index]()
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (library *Library) importBundle(bundle *Bundle, policy, author string, checkOwner func(owner string) error,
+	result *ImportResult) error {
+
+	// Check for null items before storing anything
+	for _, items := range []interface{}{bundle.SourceGroups, bundle.MetricGroups, bundle.Scales, bundle.Units,
+		bundle.Graphs, bundle.Collections} {
+		value := reflect.ValueOf(items)
+
+		for index := 0; index < value.Len(); index++ {
+			if value.Index(index).IsNil() {
+				return fmt.Errorf("found null item in bundle")
+			}
+		}
+	}
+
+	// Keep track of renamed groups to update graphs series
+	groupNames := map[int]map[string]string{
+		LibraryItemSourceGroup: make(map[string]string),
+		LibraryItemMetricGroup: make(map[string]string),
+	}
+
+	for _, itemType := range []int{LibraryItemSourceGroup, LibraryItemMetricGroup} {
+		groups := bundle.SourceGroups
+		if itemType == LibraryItemMetricGroup {
+			groups = bundle.MetricGroups
+		}
+
+		for _, group := range groups {
+			bundleID, name := group.ID, group.Name
+			group.Type = itemType
+
+			if err := library.importItem(group, itemType, policy, author, result); err != nil {
+				return err
+			}
+
+			if stored, ok := library.Groups[result.IDs[bundleID]]; ok {
+				groupNames[itemType][name] = stored.Name
+			}
+		}
+	}
+
+	for _, scale := range bundle.Scales {
+		if err := library.importItem(scale, LibraryItemScale, policy, author, result); err != nil {
+			return err
+		}
+	}
+
+	for _, unit := range bundle.Units {
+		if err := library.importItem(unit, LibraryItemUnit, policy, author, result); err != nil {
+			return err
+		}
+	}
+
+	for _, graph := range bundle.Graphs {
+		for _, group := range graph.Groups {
+			if group == nil {
+				continue
+			}
+
+			for _, series := range group.Series {
+				if series == nil {
+					continue
+				}
+
+				series.Source = renameGroupReference(series.Source, groupNames[LibraryItemSourceGroup])
+				series.Metric = renameGroupReference(series.Metric, groupNames[LibraryItemMetricGroup])
+			}
+		}
+
+		if err := library.importItem(graph, LibraryItemGraph, policy, author, result); err != nil {
+			return err
+		}
+	}
+
+	// Import collections once their parent has been imported
+	bundleIDs := make(map[string]bool)
+	for _, collection := range bundle.Collections {
+		bundleIDs[collection.ID] = true
+	}
+
+	pending := bundle.Collections
+
+	for len(pending) > 0 {
+		var deferred []*Collection
+
+		for _, collection := range pending {
+			if _, ok := result.IDs[collection.ParentID]; bundleIDs[collection.ParentID] && !ok {
+				deferred = append(deferred, collection)
+				continue
+			}
+
+			if err := library.importCollection(collection, policy, author, checkOwner, result); err != nil {
+				return err
+			}
+		}
+
+		if len(deferred) == len(pending) {
+			return fmt.Errorf("circular parent relation in bundle collections")
+		}
+
+		pending = deferred
+	}
+
+	return nil
+}
+
+// importItem stores a bundle item into the library according to the import policy.
+func (library *Library) importItem(item interface{}, itemType int, policy, author string,
+	result *ImportResult) error {
+
+	itemStruct := item.(interface {
+		GetItem() *Item
+	}).GetItem()
+
+	bundleID := itemStruct.ID
+
+	if !library.resolveImport(itemStruct, itemType, policy, result) {
+		return nil
+	}
+
+	current, _ := library.GetItem(itemStruct.ID, itemType)
+
+	if err := library.StoreItem(item, itemType, author); err != nil {
+		return fmt.Errorf("unable to import `%s': %s", itemStruct.Name, err)
+	}
+
+	result.IDs[bundleID] = itemStruct.ID
+
+	result.rollback = append(result.rollback, func() {
+		var err error
+
+		if current != nil {
+			err = library.StoreItem(current, itemType, author)
+		} else {
+			err = library.DeleteItem(itemStruct.ID, itemType, DeleteModeForce)
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelError, "library", "unable to roll back `%s' import: %s", itemStruct.Name, err)
+		}
+	})
+
+	return nil
+}
+
+// importCollection stores a bundle collection into the library according to the import policy, updating its entries
+// and parent-children relations.
+func (library *Library) importCollection(collection *Collection, policy, author string,
+	checkOwner func(owner string) error, result *ImportResult) error {
+
+	bundleID, parentID := collection.ID, collection.ParentID
+
+	if !library.resolveImport(&collection.Item, LibraryItemCollection, policy, result) {
+		return nil
+	}
+
+	entries := collection.Entries
+	collection.Entries = make([]*CollectionEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		} else if id, ok := result.IDs[entry.ID]; ok {
+			entry.ID = id
+		} else if !library.ItemExists(entry.ID, LibraryItemGraph) {
+			logger.Log(logger.LevelWarning, "library", "unknown graph identifier `%s' in collection `%s'",
+				entry.ID, collection.Name)
+			continue
+		}
+
+		collection.Entries = append(collection.Entries, entry)
+	}
+
+	if collection.Generator != nil {
+		if id, ok := result.IDs[collection.Generator.Graph]; ok {
+			collection.Generator.Graph = id
+		} else if !library.ItemExists(collection.Generator.Graph, LibraryItemGraph) {
+			logger.Log(logger.LevelWarning, "library", "unknown graph identifier `%s' in collection `%s' generator",
+				collection.Generator.Graph, collection.Name)
+			collection.Generator = nil
+		}
+	}
+
+	collection.Owner, collection.Parent, collection.Children = author, nil, nil
+
+	if id, ok := result.IDs[parentID]; ok {
+		collection.ParentID = id
+	} else {
+		collection.ParentID = ""
+	}
+
+	// Keep ownership, placement and children of overwritten collections
+	current := library.Collections[collection.ID]
+
+	if current != nil {
+		if err := checkOwner(current.Owner); err != nil {
+			return err
+		}
+
+		collection.Owner = current.Owner
+		collection.Children = current.Children
+
+		if _, ok := result.IDs[parentID]; !ok {
+			collection.ParentID = current.ParentID
+		}
+	}
+
+	// Check for parent collection ownership, skipped bundle collections resolving to existing ones
+	if parent, ok := library.Collections[collection.ParentID]; ok && (current == nil ||
+		current.ParentID != collection.ParentID) {
+		if err := checkOwner(parent.Owner); err != nil {
+			return err
+		}
+	}
+
+	if err := library.StoreItem(collection, LibraryItemCollection, author); err != nil {
+		return fmt.Errorf("unable to import `%s': %s", collection.Name, err)
+	}
+
+	library.restoreCollectionRelations(current, collection)
+
+	result.IDs[bundleID] = collection.ID
+
+	result.rollback = append(result.rollback, func() {
+		if current == nil {
+			library.detachCollection(collection)
+
+			if err := library.DeleteItem(collection.ID, LibraryItemCollection, DeleteModeForce); err != nil {
+				logger.Log(logger.LevelError, "library", "unable to roll back `%s' import: %s", collection.Name,
+					err)
+			}
+
+			return
+		}
+
+		if err := library.StoreItem(current, LibraryItemCollection, author); err != nil {
+			logger.Log(logger.LevelError, "library", "unable to roll back `%s' import: %s", collection.Name, err)
+			return
+		}
+
+		library.restoreCollectionRelations(collection, current)
+	})
+
+	return nil
+}
+
+// resolveImport applies the import policy to a bundle item, returning false if the item must not be stored.
+func (library *Library) resolveImport(itemStruct *Item, itemType int, policy string, result *ImportResult) bool {
+	bundleID := itemStruct.ID
+
+	itemStruct.ID = ""
+	itemStruct.Modified = time.Now()
+
+	item, err := library.GetItemByName(itemStruct.Name, itemType)
+	if err != nil {
+		result.Created++
+		return true
+	}
+
+	existing := item.(interface {
+		GetItem() *Item
+	}).GetItem()
+
+	switch policy {
+	case ImportPolicySkip:
+		result.IDs[bundleID] = existing.ID
+		result.Skipped++
+		return false
+
+	case ImportPolicyOverwrite:
+		itemStruct.ID = existing.ID
+		result.Overwritten++
+
+	case ImportPolicyRename:
+		for index := 2; ; index++ {
+			name := fmt.Sprintf("%s (%d)", itemStruct.Name, index)
+
+			if _, err := library.GetItemByName(name, itemType); err != nil {
+				itemStruct.Name = name
+				break
+			}
+		}
+
+		result.Renamed++
+	}
+
+	return true
+}
+
+// exportCopy copies a library item, leaving out its internal fields.
+func exportCopy(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+func exportItem(item Item) Item {
+	return Item{ID: item.ID, Name: item.Name, Description: item.Description}
+}
+
+func renameGroupReference(name string, groupNames map[string]string) string {
+	if !strings.HasPrefix(name, LibraryGroupPrefix) {
+		return name
+	} else if newName, ok := groupNames[strings.TrimPrefix(name, LibraryGroupPrefix)]; ok {
+		return LibraryGroupPrefix + newName
+	}
+
+	return name
+}
@@ -0,0 +1,93 @@
+package library
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_ImportBundleRollback(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	existing := &Graph{Item: Item{Name: "graph1", Description: "existing"}}
+	if err := library.StoreItem(existing, LibraryItemGraph, ""); err != nil {
+		test.Fatalf("unable to store graph: %s", err)
+	}
+
+	bundle := &Bundle{
+		Version: BundleVersion,
+		Scales:  []*Scale{{Item: Item{ID: "s1", Name: "scale1"}, Value: 0.1}},
+		Graphs: []*Graph{
+			{Item: Item{ID: "g1", Name: "graph1", Description: "imported"}},
+			{Item: Item{ID: "g2", Name: "graph2"}, Groups: []*OperGroup{nil}},
+		},
+	}
+
+	if _, err := library.ImportBundle(bundle, ImportPolicyOverwrite, "", nil); err == nil {
+		test.Logf("\nExpected import to fail")
+		test.Fail()
+	}
+
+	if len(library.Scales) != 0 {
+		test.Logf("\nExpected %d scales\nbut got  %d", 0, len(library.Scales))
+		test.Fail()
+	}
+
+	if len(library.Graphs) != 1 || library.Graphs[existing.ID].Description != "existing" {
+		test.Logf("\nExpected existing graph to be restored")
+		test.Fail()
+	}
+}
+
+func Test_ImportBundleOwnership(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	parent := &Collection{Item: Item{Name: "parent"}, Owner: "user1"}
+	if err := library.StoreItem(parent, LibraryItemCollection, "user1"); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	newBundle := func() *Bundle {
+		return &Bundle{
+			Version: BundleVersion,
+			Collections: []*Collection{
+				{Item: Item{ID: "c1", Name: "parent"}},
+				{Item: Item{ID: "c2", Name: "child"}, ParentID: "c1"},
+			},
+		}
+	}
+
+	checkOwner := func(owner string) error {
+		if owner != "" && owner != "user2" {
+			return os.ErrPermission
+		}
+
+		return nil
+	}
+
+	// Skipped collections resolve to existing ones, thus requiring ownership to attach children
+	for _, policy := range []string{ImportPolicySkip, ImportPolicyOverwrite} {
+		if _, err := library.ImportBundle(newBundle(), policy, "user2", checkOwner); !os.IsPermission(err) {
+			test.Logf("\nExpected %v\nbut got  %v", os.ErrPermission, err)
+			test.Fail()
+		}
+
+		if len(library.Collections) != 1 || len(parent.Children) != 0 {
+			test.Logf("\nExpected no collection to be imported")
+			test.Fail()
+		}
+	}
+
+	result, err := library.ImportBundle(newBundle(), ImportPolicyRename, "user2", checkOwner)
+	if err != nil {
+		test.Fatalf("unable to import bundle: %s", err)
+	}
+
+	child := library.Collections[result.IDs["c2"]]
+
+	if child == nil || child.Owner != "user2" || child.Parent == nil || child.Parent.Name != "parent (2)" {
+		test.Logf("\nExpected child collection to be attached to the renamed parent collection")
+		test.Fail()
+	}
+}
@@ -1,19 +1,31 @@
 package library
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/logger"
+)
+
+const (
+	// GeneratorTypeSource represents a collection generator expanding a source group.
+	GeneratorTypeSource = "source"
+	// GeneratorTypeMetric represents a collection generator expanding a metric group.
+	GeneratorTypeMetric = "metric"
 )
 
 // Collection represents a collection of graphs.
 type Collection struct {
 	Item
-	Entries  []*CollectionEntry     `json:"entries"`
-	Parent   *Collection            `json:"-"`
-	ParentID string                 `json:"parent"`
-	Options  map[string]interface{} `json:"options"`
-	Children []*Collection          `json:"-"`
+	Entries   []*CollectionEntry     `json:"entries"`
+	Parent    *Collection            `json:"-"`
+	ParentID  string                 `json:"parent"`
+	Options   map[string]interface{} `json:"options"`
+	Owner     string                 `json:"owner,omitempty"`
+	Generator *CollectionGenerator   `json:"generator,omitempty"`
+	Children  []*Collection          `json:"-"`
 }
 
 // CollectionEntry represents a collection entry.
@@ -22,6 +34,106 @@ type CollectionEntry struct {
 	Options map[string]interface{} `json:"options"`
 }
 
+// CollectionGenerator represents a collection entries generator, expanding a source or metric group against a templated
+// graph to produce one entry per group item.
+type CollectionGenerator struct {
+	Graph    string                 `json:"graph"`
+	Group    string                 `json:"group"`
+	Type     string                 `json:"type,omitempty"`
+	Variable string                 `json:"variable,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+func (generator *CollectionGenerator) String() string {
+	return fmt.Sprintf(
+		"CollectionGenerator{Graph:\"%s\" Group:\"%s\" Type:\"%s\" Variable:\"%s\"}",
+		generator.Graph,
+		generator.Group,
+		generator.Type,
+		generator.Variable,
+	)
+}
+
+// groupType returns the library item type of the generator group.
+func (generator *CollectionGenerator) groupType() int {
+	if generator.Type == GeneratorTypeMetric {
+		return LibraryItemMetricGroup
+	}
+
+	return LibraryItemSourceGroup
+}
+
+// variable returns the name of the graph variable set by the generator, defaulting to the generator type.
+func (generator *CollectionGenerator) variable() string {
+	if generator.Variable != "" {
+		return generator.Variable
+	} else if generator.Type != "" {
+		return generator.Type
+	}
+
+	return GeneratorTypeSource
+}
+
+// ExpandCollection returns a copy of a collection with its generated entries appended to the static ones, the
+// generator group being expanded against the current catalog.
+func (library *Library) ExpandCollection(collection *Collection) *Collection {
+	if collection.Generator == nil {
+		return collection
+	}
+
+	generator := collection.Generator
+
+	item, err := library.GetItem(generator.Graph, LibraryItemGraph)
+	if err != nil {
+		logger.Log(logger.LevelError, "library", "unknown graph identifier `%s' in collection `%s' generator",
+			generator.Graph, collection.ID)
+		return collection
+	}
+
+	graph := item.(*Graph)
+
+	collectionTemp := &Collection{}
+	*collectionTemp = *collection
+	collectionTemp.Entries = make([]*CollectionEntry, len(collection.Entries))
+	copy(collectionTemp.Entries, collection.Entries)
+
+	for _, name := range library.ExpandGroup(strings.TrimPrefix(generator.Group, LibraryGroupPrefix),
+		generator.groupType()) {
+		mapping := func(key string) string {
+			if key == generator.variable() {
+				return name
+			}
+
+			return "$" + key
+		}
+
+		entry := &CollectionEntry{
+			ID:      graph.ID,
+			Options: make(map[string]interface{}),
+		}
+
+		for key, value := range generator.Options {
+			entry.Options[key] = value
+		}
+
+		if title, err := config.GetString(entry.Options, "title", false); err == nil && title != "" {
+			entry.Options["title"] = os.Expand(title, mapping)
+		} else {
+			entry.Options["title"] = fmt.Sprintf("%s (%s)", graph.Name, name)
+		}
+
+		if _, err := config.GetBool(entry.Options, "enabled", true); err != nil {
+			entry.Options["enabled"] = true
+		}
+
+		entry.Options["variables"] = map[string]interface{}{generator.variable(): name}
+
+		collectionTemp.Entries = append(collectionTemp.Entries, entry)
+	}
+
+	return collectionTemp
+}
+
 // FilterCollection filters collection entries by graphs titles and enable state.
 func (library *Library) FilterCollection(collection *Collection, filter string) *Collection {
 	collectionTemp := &Collection{}
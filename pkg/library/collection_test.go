@@ -0,0 +1,90 @@
+package library
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/facette/facette/pkg/catalog"
+)
+
+func Test_ExpandCollection(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	library.Catalog = catalog.NewCatalog()
+
+	for _, source := range []string{"host1", "host2", "other1"} {
+		library.Catalog.Insert(&catalog.Record{Origin: "origin1", Source: source, Metric: "cpu.user"})
+	}
+
+	group := &Group{Item: Item{Name: "hosts"}, Type: LibraryItemSourceGroup,
+		Entries: []*GroupEntry{{Pattern: LibraryMatchPrefixGlob + "host*", Origin: "origin1"}}}
+	if err := library.StoreItem(group, LibraryItemSourceGroup, ""); err != nil {
+		test.Fatalf("unable to store group: %s", err)
+	}
+
+	graph := &Graph{Item: Item{Name: "cpu"}, Variables: []*Variable{{Name: "host"}}}
+	if err := library.StoreItem(graph, LibraryItemGraph, ""); err != nil {
+		test.Fatalf("unable to store graph: %s", err)
+	}
+
+	collection := &Collection{
+		Item:    Item{Name: "collection"},
+		Entries: []*CollectionEntry{{ID: graph.ID, Options: map[string]interface{}{"title": "static"}}},
+		Generator: &CollectionGenerator{
+			Graph:    graph.ID,
+			Group:    LibraryGroupPrefix + "hosts",
+			Variable: "host",
+			Options:  map[string]interface{}{"title": "CPU on $host ($unknown)", "enabled": false},
+		},
+	}
+	if err := library.StoreItem(collection, LibraryItemCollection, ""); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	result := library.ExpandCollection(collection)
+
+	expected := []*CollectionEntry{
+		{ID: graph.ID, Options: map[string]interface{}{"title": "static"}},
+		{ID: graph.ID, Options: map[string]interface{}{"title": "CPU on host1 ($unknown)", "enabled": false,
+			"variables": map[string]interface{}{"host": "host1"}}},
+		{ID: graph.ID, Options: map[string]interface{}{"title": "CPU on host2 ($unknown)", "enabled": false,
+			"variables": map[string]interface{}{"host": "host2"}}},
+	}
+
+	if !reflect.DeepEqual(result.Entries, expected) {
+		test.Logf("\nExpected %v\nbut got  %v", expected, result.Entries)
+		test.Fail()
+	}
+
+	if len(collection.Entries) != 1 || len(collection.Generator.Options) != 2 {
+		test.Logf("\nExpected original collection to be left untouched")
+		test.Fail()
+	}
+
+	// Check for default title and enable state
+	collection.Generator.Options = nil
+
+	result = library.ExpandCollection(collection)
+
+	if title := result.Entries[1].Options["title"]; title != "cpu (host1)" {
+		test.Logf("\nExpected %q\nbut got  %q", "cpu (host1)", title)
+		test.Fail()
+	} else if enabled := result.Entries[1].Options["enabled"]; enabled != true {
+		test.Logf("\nExpected %v\nbut got  %v", true, enabled)
+		test.Fail()
+	}
+
+	// Check for generator variable removal from graph
+	graphTemp := &Graph{Item: Item{ID: graph.ID, Name: "cpu"}, Variables: []*Variable{{Name: "source"}}}
+	if err := library.StoreItem(graphTemp, LibraryItemGraph, ""); err != os.ErrInvalid {
+		test.Logf("\nExpected %v\nbut got  %v", os.ErrInvalid, err)
+		test.Fail()
+	}
+
+	if library.Graphs[graph.ID] != graph {
+		test.Logf("\nExpected graph to be left unchanged")
+		test.Fail()
+	}
+}
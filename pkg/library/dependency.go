@@ -0,0 +1,343 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+)
+
+const (
+	// DeleteModeStrict represents the deletion mode refusing to delete still referenced items.
+	DeleteModeStrict = iota
+	// DeleteModeForce represents the deletion mode ignoring existing references.
+	DeleteModeForce
+	// DeleteModeCascade represents the deletion mode also removing existing references (i.e. collections entries and
+	// graphs options are removed, graphs series referencing a group are deleted along with their graph).
+	DeleteModeCascade
+)
+
+var itemTypeNames = map[int]string{
+	LibraryItemSourceGroup: "sourcegroup",
+	LibraryItemMetricGroup: "metricgroup",
+	LibraryItemUnit:        "unit",
+	LibraryItemScale:       "scale",
+	LibraryItemGraph:       "graph",
+	LibraryItemCollection:  "collection",
+	LibraryItemAnnotation:  "annotation",
+}
+
+// Reference represents a library item referencing another one.
+type Reference struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	itemType int
+}
+
+// DanglingReference represents a reference to a library item that does not exist.
+type DanglingReference struct {
+	*Reference
+	TargetType string `json:"target_type"`
+	Target     string `json:"target"`
+}
+
+func (dangling *DanglingReference) String() string {
+	return fmt.Sprintf("%s `%s' (%s) references unknown %s `%s'", dangling.Type, dangling.Name, dangling.ID,
+		dangling.TargetType, dangling.Target)
+}
+
+// ReferenceError represents the error returned when deleting a library item still referenced by other items.
+type ReferenceError struct {
+	References []*Reference
+}
+
+func (err *ReferenceError) Error() string {
+	return fmt.Sprintf("item is still referenced by %d other item(s)", len(err.References))
+}
+
+// dependencyKey represents a referenced item in the dependency index. Graphs are referenced by identifier, groups by
+// name, units by label and scales by value.
+type dependencyKey struct {
+	itemType int
+	key      string
+}
+
+// GetReferences returns the list of library items referencing a given item.
+func (library *Library) GetReferences(id string, itemType int) []*Reference {
+	key, ok := library.getDependencyKey(id, itemType)
+	if !ok {
+		return nil
+	}
+
+	return library.getDependencyIndex()[key]
+}
+
+// CheckReferences returns the list of references to non-existent library items (i.e. graphs referencing unknown
+// groups or collections referencing unknown graphs or parents). Units and scales being referenced by value, they are
+// not checked.
+func (library *Library) CheckReferences() []*DanglingReference {
+	result := []*DanglingReference{}
+
+	for key, references := range library.getDependencyIndex() {
+		exists := false
+
+		switch key.itemType {
+		case LibraryItemSourceGroup, LibraryItemMetricGroup:
+			_, err := library.GetItemByName(key.key, key.itemType)
+			exists = err == nil
+
+		case LibraryItemGraph, LibraryItemCollection:
+			exists = library.ItemExists(key.key, key.itemType)
+
+		default:
+			continue
+		}
+
+		if exists {
+			continue
+		}
+
+		for _, reference := range references {
+			result = append(result, &DanglingReference{
+				Reference:  reference,
+				TargetType: itemTypeNames[key.itemType],
+				Target:     key.key,
+			})
+		}
+	}
+
+	// Check for unknown collections parents
+	for _, collection := range library.Collections {
+		if collection.ParentID != "" && !library.ItemExists(collection.ParentID, LibraryItemCollection) {
+			result = append(result, &DanglingReference{
+				Reference:  newReference(collection.ID, collection.Name, LibraryItemCollection),
+				TargetType: itemTypeNames[LibraryItemCollection],
+				Target:     collection.ParentID,
+			})
+		}
+	}
+
+	sort.Sort(danglingReferenceList(result))
+
+	return result
+}
+
+// removeReferences removes the references to a library item from the items referencing it.
+func (library *Library) removeReferences(id string, itemType int, references []*Reference) error {
+	key, _ := library.getDependencyKey(id, itemType)
+
+	for _, reference := range references {
+		switch reference.itemType {
+		case LibraryItemCollection:
+			collection, ok := library.Collections[reference.ID]
+			if !ok {
+				continue
+			}
+
+			entries := collection.Entries
+			collection.Entries = make([]*CollectionEntry, 0, len(entries))
+
+			for _, entry := range entries {
+				if entry.ID != id {
+					collection.Entries = append(collection.Entries, entry)
+				}
+			}
+
+			// Generated entries can't go without their graph or group
+			if collection.Generator != nil && (itemType != LibraryItemGraph || collection.Generator.Graph == id) {
+				collection.Generator = nil
+			}
+
+			collection.Modified = time.Now()
+
+			if err := library.StoreItem(collection, LibraryItemCollection, ""); err != nil {
+				return err
+			}
+
+		case LibraryItemGraph:
+			graph, ok := library.Graphs[reference.ID]
+			if !ok {
+				continue
+			}
+
+			// Graphs can't go without their series
+			if itemType == LibraryItemSourceGroup || itemType == LibraryItemMetricGroup {
+				if err := library.DeleteItem(graph.ID, LibraryItemGraph, DeleteModeCascade); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			for _, group := range graph.Groups {
+				removeOptionReference(group.Options, key)
+
+				for _, series := range group.Series {
+					removeOptionReference(series.Options, key)
+				}
+			}
+
+			graph.Modified = time.Now()
+
+			if err := library.StoreItem(graph, LibraryItemGraph, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getDependencyIndex returns the index of library items references, mapping referenced items to the list of items
+// referencing them.
+func (library *Library) getDependencyIndex() map[dependencyKey][]*Reference {
+	index := make(map[dependencyKey][]*Reference)
+
+	add := func(key dependencyKey, reference *Reference) {
+		for _, entry := range index[key] {
+			if entry.ID == reference.ID {
+				return
+			}
+		}
+
+		index[key] = append(index[key], reference)
+	}
+
+	addOptions := func(options map[string]interface{}, reference *Reference) {
+		if unit, err := config.GetString(options, "unit", false); err == nil && unit != "" {
+			add(dependencyKey{LibraryItemUnit, unit}, reference)
+		}
+
+		if scale, err := config.GetFloat(options, "scale", false); err == nil && scale != 0 {
+			add(dependencyKey{LibraryItemScale, formatScaleKey(scale)}, reference)
+		}
+	}
+
+	for _, graph := range library.Graphs {
+		reference := newReference(graph.ID, graph.Name, LibraryItemGraph)
+
+		for _, group := range graph.Groups {
+			addOptions(group.Options, reference)
+
+			for _, series := range group.Series {
+				addOptions(series.Options, reference)
+
+				if strings.HasPrefix(series.Source, LibraryGroupPrefix) {
+					add(dependencyKey{LibraryItemSourceGroup, strings.TrimPrefix(series.Source, LibraryGroupPrefix)},
+						reference)
+				}
+
+				if strings.HasPrefix(series.Metric, LibraryGroupPrefix) {
+					add(dependencyKey{LibraryItemMetricGroup, strings.TrimPrefix(series.Metric, LibraryGroupPrefix)},
+						reference)
+				}
+			}
+		}
+	}
+
+	for _, collection := range library.Collections {
+		reference := newReference(collection.ID, collection.Name, LibraryItemCollection)
+
+		for _, entry := range collection.Entries {
+			add(dependencyKey{LibraryItemGraph, entry.ID}, reference)
+		}
+
+		if collection.Generator != nil {
+			add(dependencyKey{LibraryItemGraph, collection.Generator.Graph}, reference)
+			add(dependencyKey{collection.Generator.groupType(),
+				strings.TrimPrefix(collection.Generator.Group, LibraryGroupPrefix)}, reference)
+		}
+	}
+
+	for _, references := range index {
+		sort.Sort(referenceList(references))
+	}
+
+	return index
+}
+
+func (library *Library) getDependencyKey(id string, itemType int) (dependencyKey, bool) {
+	if !library.ItemExists(id, itemType) {
+		return dependencyKey{}, false
+	}
+
+	switch itemType {
+	case LibraryItemSourceGroup, LibraryItemMetricGroup:
+		return dependencyKey{itemType, library.Groups[id].Name}, true
+
+	case LibraryItemUnit:
+		return dependencyKey{itemType, library.Units[id].Label}, true
+
+	case LibraryItemScale:
+		return dependencyKey{itemType, formatScaleKey(library.Scales[id].Value)}, true
+
+	case LibraryItemGraph:
+		return dependencyKey{itemType, id}, true
+	}
+
+	return dependencyKey{}, false
+}
+
+func newReference(id, name string, itemType int) *Reference {
+	return &Reference{ID: id, Name: name, Type: itemTypeNames[itemType], itemType: itemType}
+}
+
+func formatScaleKey(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func removeOptionReference(options map[string]interface{}, key dependencyKey) {
+	switch key.itemType {
+	case LibraryItemUnit:
+		if unit, err := config.GetString(options, "unit", false); err == nil && unit == key.key {
+			delete(options, "unit")
+		}
+
+	case LibraryItemScale:
+		if scale, err := config.GetFloat(options, "scale", false); err == nil && formatScaleKey(scale) == key.key {
+			delete(options, "scale")
+		}
+	}
+}
+
+type referenceList []*Reference
+
+func (list referenceList) Len() int {
+	return len(list)
+}
+
+func (list referenceList) Less(i, j int) bool {
+	if list[i].Type != list[j].Type {
+		return list[i].Type < list[j].Type
+	}
+
+	return list[i].Name < list[j].Name
+}
+
+func (list referenceList) Swap(i, j int) {
+	list[i], list[j] = list[j], list[i]
+}
+
+type danglingReferenceList []*DanglingReference
+
+func (list danglingReferenceList) Len() int {
+	return len(list)
+}
+
+func (list danglingReferenceList) Less(i, j int) bool {
+	if list[i].Type != list[j].Type {
+		return list[i].Type < list[j].Type
+	} else if list[i].Name != list[j].Name {
+		return list[i].Name < list[j].Name
+	}
+
+	return list[i].Target < list[j].Target
+}
+
+func (list danglingReferenceList) Swap(i, j int) {
+	list[i], list[j] = list[j], list[i]
+}
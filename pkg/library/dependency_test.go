@@ -0,0 +1,224 @@
+package library
+
+import (
+	"reflect"
+	"testing"
+)
+
+type dependencyTestItems struct {
+	group      *Group
+	unit       *Unit
+	scale      *Scale
+	graph1     *Graph
+	graph2     *Graph
+	collection *Collection
+}
+
+func Test_DeleteItemStrict(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	items := storeDependencyTestItems(test, library)
+
+	for _, entry := range []struct {
+		ID         string
+		ItemType   int
+		References []string
+	}{
+		{items.group.ID, LibraryItemSourceGroup, []string{items.graph1.ID}},
+		{items.unit.ID, LibraryItemUnit, []string{items.graph1.ID, items.graph2.ID}},
+		{items.scale.ID, LibraryItemScale, []string{items.graph2.ID}},
+		{items.graph1.ID, LibraryItemGraph, []string{items.collection.ID}},
+	} {
+		err := library.DeleteItem(entry.ID, entry.ItemType, DeleteModeStrict)
+
+		refErr, ok := err.(*ReferenceError)
+		if !ok {
+			test.Logf("\nExpected reference error\nbut got  %v", err)
+			test.Fail()
+			continue
+		}
+
+		if result := referenceIDs(refErr.References); !reflect.DeepEqual(result, entry.References) {
+			test.Logf("\nExpected %v\nbut got  %v", entry.References, result)
+			test.Fail()
+		}
+
+		if !library.ItemExists(entry.ID, entry.ItemType) {
+			test.Logf("\nExpected item `%s' to be left", entry.ID)
+			test.Fail()
+		}
+	}
+
+	if len(library.CheckReferences()) != 0 {
+		test.Logf("\nExpected no dangling reference\nbut got  %v", library.CheckReferences())
+		test.Fail()
+	}
+}
+
+func Test_DeleteItemForce(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	items := storeDependencyTestItems(test, library)
+
+	for _, entry := range []struct {
+		ID       string
+		ItemType int
+	}{
+		{items.group.ID, LibraryItemSourceGroup},
+		{items.graph2.ID, LibraryItemGraph},
+		{items.unit.ID, LibraryItemUnit},
+	} {
+		if err := library.DeleteItem(entry.ID, entry.ItemType, DeleteModeForce); err != nil {
+			test.Fatalf("unable to delete item: %s", err)
+		}
+	}
+
+	// Referencing items are left untouched
+	if !library.ItemExists(items.graph1.ID, LibraryItemGraph) || len(items.collection.Entries) != 2 {
+		test.Logf("\nExpected referencing items to be left untouched")
+		test.Fail()
+	}
+
+	if unit := items.graph1.Groups[0].Options["unit"]; unit != "B" {
+		test.Logf("\nExpected %q\nbut got  %v", "B", unit)
+		test.Fail()
+	}
+
+	// Set unknown parent to collection
+	items.collection.ParentID = "unknown"
+
+	// Units being referenced by value, they are not reported
+	expected := []string{
+		"collection `collection' references unknown graph `" + items.graph2.ID + "'",
+		"collection `collection' references unknown collection `unknown'",
+		"graph `graph1' references unknown sourcegroup `hosts'",
+	}
+
+	result := []string{}
+	for _, dangling := range library.CheckReferences() {
+		result = append(result, dangling.Type+" `"+dangling.Name+"' references unknown "+dangling.TargetType+" `"+
+			dangling.Target+"'")
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		test.Logf("\nExpected %v\nbut got  %v", expected, result)
+		test.Fail()
+	}
+}
+
+func Test_DeleteItemCascade(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	items := storeDependencyTestItems(test, library)
+
+	// Units and scales are removed from graphs options
+	if err := library.DeleteItem(items.unit.ID, LibraryItemUnit, DeleteModeCascade); err != nil {
+		test.Fatalf("unable to delete unit: %s", err)
+	}
+
+	if err := library.DeleteItem(items.scale.ID, LibraryItemScale, DeleteModeCascade); err != nil {
+		test.Fatalf("unable to delete scale: %s", err)
+	}
+
+	if options := items.graph1.Groups[0].Options; !reflect.DeepEqual(options, map[string]interface{}{}) {
+		test.Logf("\nExpected %v\nbut got  %v", map[string]interface{}{}, options)
+		test.Fail()
+	}
+
+	expected := map[string]interface{}{"color": "#ff0000"}
+	if options := items.graph2.Groups[0].Series[0].Options; !reflect.DeepEqual(options, expected) {
+		test.Logf("\nExpected %v\nbut got  %v", expected, options)
+		test.Fail()
+	}
+
+	if !library.ItemExists(items.graph1.ID, LibraryItemGraph) || !library.ItemExists(items.graph2.ID,
+		LibraryItemGraph) {
+		test.Logf("\nExpected graphs to be left")
+		test.Fail()
+	}
+
+	// Graphs using a deleted group are deleted along with it, and removed from collections
+	if err := library.DeleteItem(items.group.ID, LibraryItemSourceGroup, DeleteModeCascade); err != nil {
+		test.Fatalf("unable to delete group: %s", err)
+	}
+
+	if library.ItemExists(items.graph1.ID, LibraryItemGraph) {
+		test.Logf("\nExpected graph `%s' to be deleted", items.graph1.Name)
+		test.Fail()
+	}
+
+	if !library.ItemExists(items.graph2.ID, LibraryItemGraph) {
+		test.Logf("\nExpected graph `%s' to be left", items.graph2.Name)
+		test.Fail()
+	}
+
+	collection := library.Collections[items.collection.ID]
+	if len(collection.Entries) != 1 || collection.Entries[0].ID != items.graph2.ID {
+		test.Logf("\nExpected only graph `%s' entry to be left", items.graph2.Name)
+		test.Fail()
+	}
+
+	if len(library.CheckReferences()) != 0 {
+		test.Logf("\nExpected no dangling reference\nbut got  %v", library.CheckReferences())
+		test.Fail()
+	}
+}
+
+func storeDependencyTestItems(test *testing.T, library *Library) *dependencyTestItems {
+	items := &dependencyTestItems{
+		group: &Group{Item: Item{Name: "hosts"}, Type: LibraryItemSourceGroup,
+			Entries: []*GroupEntry{{Pattern: LibraryMatchPrefixGlob + "host*", Origin: "origin1"}}},
+		unit:  &Unit{Item: Item{Name: "bytes"}, Label: "B"},
+		scale: &Scale{Item: Item{Name: "kibi"}, Value: 1024},
+	}
+
+	items.graph1 = &Graph{Item: Item{Name: "graph1"}, Groups: []*OperGroup{{
+		Name:    "group1",
+		Series:  []*Series{{Name: "series1", Origin: "origin1", Source: LibraryGroupPrefix + "hosts", Metric: "m1"}},
+		Options: map[string]interface{}{"unit": "B"},
+	}}}
+
+	items.graph2 = &Graph{Item: Item{Name: "graph2"}, Groups: []*OperGroup{{
+		Name: "group1",
+		Series: []*Series{{Name: "series1", Origin: "origin1", Source: "host1", Metric: "m1",
+			Options: map[string]interface{}{"unit": "B", "scale": 1024.0, "color": "#ff0000"}}},
+	}}}
+
+	for _, entry := range []struct {
+		Item     interface{}
+		ItemType int
+	}{
+		{items.group, LibraryItemSourceGroup},
+		{items.unit, LibraryItemUnit},
+		{items.scale, LibraryItemScale},
+		{items.graph1, LibraryItemGraph},
+		{items.graph2, LibraryItemGraph},
+	} {
+		if err := library.StoreItem(entry.Item, entry.ItemType, ""); err != nil {
+			test.Fatalf("unable to store item: %s", err)
+		}
+	}
+
+	items.collection = &Collection{Item: Item{Name: "collection"}, Entries: []*CollectionEntry{
+		{ID: items.graph1.ID},
+		{ID: items.graph2.ID},
+	}}
+
+	if err := library.StoreItem(items.collection, LibraryItemCollection, ""); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	return items
+}
+
+func referenceIDs(references []*Reference) []string {
+	result := []string{}
+	for _, reference := range references {
+		result = append(result, reference.ID)
+	}
+
+	return result
+}
@@ -2,9 +2,13 @@ package library
 
 import (
 	"fmt"
+	"os"
+	"regexp"
 	"strings"
 )
 
+var variableNameRegexp = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
 const (
 	_ = iota
 	// GraphTypeArea represents an area graph type.
@@ -34,11 +38,14 @@ const (
 // Graph represents a graph containing list of series.
 type Graph struct {
 	Item
-	Type       int          `json:"type"`
-	StackMode  int          `json:"stack_mode"`
-	UnitType   int          `json:"unit_type"`
-	UnitLegend string       `json:"unit_legend"`
-	Groups     []*OperGroup `json:"groups"`
+	Type          int          `json:"type"`
+	StackMode     int          `json:"stack_mode"`
+	UnitType      int          `json:"unit_type"`
+	UnitLegend    string       `json:"unit_legend"`
+	Consolidation string       `json:"consolidation,omitempty"`
+	Groups        []*OperGroup `json:"groups"`
+	Constants     []*Constant  `json:"constants,omitempty"`
+	Variables     []*Variable  `json:"variables,omitempty"`
 }
 
 func (graph *Graph) String() string {
@@ -58,20 +65,67 @@ func (graph *Graph) String() string {
 	)
 }
 
+// Expand returns a copy of a templated graph, its variables being replaced in series sources and metrics either by
+// the given values or by their default values.
+func (graph *Graph) Expand(values map[string]string) (*Graph, error) {
+	variables := make(map[string]string)
+
+	for _, variable := range graph.Variables {
+		if value, ok := values[variable.Name]; ok && value != "" {
+			variables[variable.Name] = value
+		} else if variable.Default != "" {
+			variables[variable.Name] = variable.Default
+		} else {
+			return nil, fmt.Errorf("missing value for variable `%s'", variable.Name)
+		}
+	}
+
+	// Only replace declared variables, leaving other dollar signs untouched
+	mapping := func(name string) string {
+		if value, ok := variables[name]; ok {
+			return value
+		}
+
+		return "$" + name
+	}
+
+	result := &Graph{}
+	*result = *graph
+	result.Groups = make([]*OperGroup, len(graph.Groups))
+
+	for i, group := range graph.Groups {
+		result.Groups[i] = &OperGroup{}
+		*result.Groups[i] = *group
+		result.Groups[i].Series = make([]*Series, len(group.Series))
+
+		for j, series := range group.Series {
+			result.Groups[i].Series[j] = &Series{}
+			*result.Groups[i].Series[j] = *series
+			result.Groups[i].Series[j].Source = os.Expand(series.Source, mapping)
+			result.Groups[i].Series[j].Metric = os.Expand(series.Metric, mapping)
+		}
+	}
+
+	return result, nil
+}
+
 // OperGroup represents an operation group entry.
 type OperGroup struct {
-	Name    string                 `json:"name"`
-	Type    int                    `json:"type"`
-	StackID int                    `json:"stack_id"`
-	Series  []*Series              `json:"series"`
-	Options map[string]interface{} `json:"options"`
+	Name          string                 `json:"name"`
+	Type          int                    `json:"type"`
+	StackID       int                    `json:"stack_id"`
+	Series        []*Series              `json:"series"`
+	Options       map[string]interface{} `json:"options"`
+	Expr          string                 `json:"expr,omitempty"`
+	Consolidation string                 `json:"consolidation,omitempty"`
 }
 
 func (group *OperGroup) String() string {
 	return fmt.Sprintf(
-		"OperGroup{Name:\"%s\" Type:%d StackID:%d Series:[%s] Options:%v}",
+		"OperGroup{Name:\"%s\" Type:%d StackID:%d Series:[%s] Options:%v Expr:\"%s\"}",
 		group.Name,
 		group.Type,
+		group.StackID,
 		func(series []*Series) string {
 			seriesStrings := make([]string, len(series))
 			for i, entry := range series {
@@ -81,6 +135,37 @@ func (group *OperGroup) String() string {
 			return strings.Join(seriesStrings, ", ")
 		}(group.Series),
 		group.Options,
+		group.Expr,
+	)
+}
+
+// Constant represents a constant value entry (e.g. a threshold or a capacity limit).
+type Constant struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Color string  `json:"color,omitempty"`
+}
+
+func (constant *Constant) String() string {
+	return fmt.Sprintf(
+		"Constant{Label:\"%s\" Value:%g Color:\"%s\"}",
+		constant.Label,
+		constant.Value,
+		constant.Color,
+	)
+}
+
+// Variable represents a graph template variable, referenced as `$name' or `${name}' in series sources and metrics.
+type Variable struct {
+	Name    string `json:"name"`
+	Default string `json:"default,omitempty"`
+}
+
+func (variable *Variable) String() string {
+	return fmt.Sprintf(
+		"Variable{Name:\"%s\" Default:\"%s\"}",
+		variable.Name,
+		variable.Default,
 	)
 }
 
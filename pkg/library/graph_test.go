@@ -0,0 +1,64 @@
+package library
+
+import "testing"
+
+func Test_GraphExpand(test *testing.T) {
+	graph := &Graph{
+		Item: Item{Name: "graph"},
+		Variables: []*Variable{
+			{Name: "host", Default: "host1"},
+			{Name: "cpu"},
+		},
+	}
+
+	for _, entry := range []struct {
+		Source string
+		Metric string
+		Values map[string]string
+		Result [2]string
+		Error  bool
+	}{
+		{"$host", "cpu.$cpu.user", map[string]string{"cpu": "0"}, [2]string{"host1", "cpu.0.user"}, false},
+		{"$host", "cpu.$cpu.user", map[string]string{"host": "host2", "cpu": "1"},
+			[2]string{"host2", "cpu.1.user"}, false},
+		{"$host", "cpu.$cpu.user", map[string]string{"host": "", "cpu": "1"}, [2]string{"host1", "cpu.1.user"}, false},
+		{"${host}.example.net", "cpu${cpu}", map[string]string{"cpu": "2"},
+			[2]string{"host1.example.net", "cpu2"}, false},
+		{"$unknown", "$5", map[string]string{"cpu": "0"}, [2]string{"$unknown", "$5"}, false},
+		{"host$", "cost $$ $", map[string]string{"cpu": "0"}, [2]string{"host$", "cost $$ $"}, false},
+		{"$host", "cpu.$cpu.user", nil, [2]string{}, true},
+		{"$host", "cpu.$cpu.user", map[string]string{"cpu": ""}, [2]string{}, true},
+	} {
+		graph.Groups = []*OperGroup{{
+			Name:   "group",
+			Series: []*Series{{Name: "series", Origin: "origin", Source: entry.Source, Metric: entry.Metric}},
+		}}
+
+		result, err := graph.Expand(entry.Values)
+		if entry.Error {
+			if err == nil {
+				test.Logf("\nExpected error for values %v", entry.Values)
+				test.Fail()
+			}
+
+			continue
+		} else if err != nil {
+			test.Logf("\nExpected %v\nbut got  error %s", entry.Result, err)
+			test.Fail()
+			continue
+		}
+
+		series := result.Groups[0].Series[0]
+
+		if [2]string{series.Source, series.Metric} != entry.Result {
+			test.Logf("\nExpected %q\nbut got  %q", entry.Result, [2]string{series.Source, series.Metric})
+			test.Fail()
+		}
+
+		// Check that the original graph is left untouched
+		if series := graph.Groups[0].Series[0]; series.Source != entry.Source || series.Metric != entry.Metric {
+			test.Logf("\nExpected original series to be left untouched")
+			test.Fail()
+		}
+	}
+}
@@ -1,13 +1,19 @@
 package library
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/facette/facette/pkg/config"
 	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/plot"
 	"github.com/facette/facette/pkg/utils"
 	"github.com/facette/facette/thirdparty/github.com/fatih/set"
 	uuid "github.com/facette/facette/thirdparty/github.com/nu7hatch/gouuid"
@@ -27,20 +33,32 @@ func (item *Item) GetItem() *Item {
 	return item
 }
 
-// DeleteItem removes an existing item from the library.
-func (library *Library) DeleteItem(id string, itemType int) error {
+// DeleteItem removes an existing item from the library. Depending on the deletion mode, a *ReferenceError is returned
+// if the item is still referenced by other items, or these references are either ignored or removed.
+func (library *Library) DeleteItem(id string, itemType int, mode int) error {
 	if !library.ItemExists(id, itemType) {
 		return os.ErrNotExist
 	}
 
+	// Check for items still referencing the item
+	if mode != DeleteModeForce {
+		if references := library.GetReferences(id, itemType); len(references) > 0 {
+			if mode != DeleteModeCascade {
+				return &ReferenceError{References: references}
+			} else if err := library.removeReferences(id, itemType, references); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Delete sub-collections
 	if itemType == LibraryItemCollection {
 		for _, child := range library.Collections[id].Children {
-			library.DeleteItem(child.ID, LibraryItemCollection)
+			library.DeleteItem(child.ID, LibraryItemCollection, mode)
 		}
 	}
 
-	// Remove stored JSON
+	// Remove stored JSON, keeping revisions to allow restoring the item later
 	if err := syscall.Unlink(library.getFilePath(id, itemType)); err != nil {
 		return err
 	}
@@ -61,6 +79,9 @@ func (library *Library) DeleteItem(id string, itemType int) error {
 
 	case LibraryItemCollection:
 		delete(library.Collections, id)
+
+	case LibraryItemAnnotation:
+		delete(library.Annotations, id)
 	}
 
 	return nil
@@ -87,6 +108,9 @@ func (library *Library) GetItem(id string, itemType int) (interface{}, error) {
 
 	case LibraryItemCollection:
 		return library.Collections[id], nil
+
+	case LibraryItemAnnotation:
+		return library.Annotations[id], nil
 	}
 
 	return nil, fmt.Errorf("no item found")
@@ -137,6 +161,15 @@ func (library *Library) GetItemByName(name string, itemType int) (interface{}, e
 				continue
 			}
 
+			return item, nil
+		}
+
+	case LibraryItemAnnotation:
+		for _, item := range library.Annotations {
+			if item.Name != name {
+				continue
+			}
+
 			return item, nil
 		}
 	}
@@ -165,6 +198,9 @@ func (library *Library) ItemExists(id string, itemType int) bool {
 
 	case LibraryItemCollection:
 		_, exists = library.Collections[id]
+
+	case LibraryItemAnnotation:
+		_, exists = library.Annotations[id]
 	}
 
 	return exists
@@ -251,13 +287,33 @@ func (library *Library) LoadItem(id string, itemType int) error {
 		}
 
 		library.Collections[id].Modified = fileInfo.ModTime()
+
+	case LibraryItemAnnotation:
+		tmpAnnotation := &Annotation{}
+
+		filePath := library.getFilePath(id, itemType)
+
+		fileInfo, err := utils.JSONLoad(filePath, &tmpAnnotation)
+		if err != nil {
+			return fmt.Errorf("in %s, %s", filePath, err)
+		}
+
+		library.Annotations[id] = tmpAnnotation
+		library.Annotations[id].Modified = fileInfo.ModTime()
 	}
 
 	return nil
 }
 
-// StoreItem stores an item into the library.
-func (library *Library) StoreItem(item interface{}, itemType int) error {
+// StoreItem stores an item into the library, keeping track of its previous versions. The author is the name of the
+// user performing the change, if known.
+func (library *Library) StoreItem(item interface{}, itemType int, author string) error {
+	return library.storeItem(item, itemType, author, false)
+}
+
+// storeItem stores an item into the library. Unless restoring a revision, the item identifier must either be empty or
+// match an existing item.
+func (library *Library) storeItem(item interface{}, itemType int, author string, restore bool) error {
 	var itemStruct *Item
 
 	switch itemType {
@@ -276,6 +332,9 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 	case LibraryItemCollection:
 		itemStruct = item.(*Collection).GetItem()
 
+	case LibraryItemAnnotation:
+		itemStruct = item.(*Annotation).GetItem()
+
 	default:
 		return os.ErrInvalid
 	}
@@ -287,7 +346,7 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 		}
 
 		itemStruct.ID = uuidTemp.String()
-	} else if !library.ItemExists(itemStruct.ID, itemType) {
+	} else if !restore && !library.ItemExists(itemStruct.ID, itemType) {
 		return os.ErrNotExist
 	}
 
@@ -298,8 +357,8 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 
 	itemTemp, err := library.GetItemByName(itemStruct.Name, itemType)
 
-	// Item exists, check for duplicates
-	if err == nil {
+	// Item exists, check for duplicates (annotations names being free-form event titles)
+	if err == nil && itemType != LibraryItemAnnotation {
 		switch itemType {
 		case LibraryItemSourceGroup, LibraryItemMetricGroup:
 			if itemTemp.(*Group).ID != itemStruct.ID {
@@ -352,6 +411,51 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 		groupSet := set.New(set.ThreadSafe)
 		seriesSet := set.New(set.ThreadSafe)
 
+		if graph := item.(*Graph); graph.Consolidation != "" {
+			if _, _, err := plot.ParseConsolidation(graph.Consolidation); err != nil {
+				logger.Log(logger.LevelError, "library", "%s", err)
+				return os.ErrInvalid
+			}
+		}
+
+		variableSet := set.New(set.ThreadSafe)
+
+		for _, variable := range item.(*Graph).Variables {
+			if variable == nil {
+				logger.Log(logger.LevelError, "library", "found null variable")
+				return os.ErrInvalid
+			}
+
+			variable.Name = strings.TrimPrefix(variable.Name, "$")
+
+			if !variableNameRegexp.MatchString(variable.Name) {
+				logger.Log(logger.LevelError, "library", "invalid variable name `%s'", variable.Name)
+				return os.ErrInvalid
+			} else if variableSet.Has(variable.Name) {
+				logger.Log(logger.LevelError, "library", "duplicate variable name `%s'", variable.Name)
+				return os.ErrExist
+			}
+
+			variableSet.Add(variable.Name)
+		}
+
+		// Check for variables still being set by collection generators
+		for _, collection := range library.Collections {
+			if generator := collection.Generator; generator != nil && generator.Graph == itemStruct.ID &&
+				!variableSet.Has(generator.variable()) {
+				logger.Log(logger.LevelError, "library", "missing variable `%s' used by collection `%s' generator",
+					generator.variable(), collection.ID)
+				return os.ErrInvalid
+			}
+		}
+
+		for _, constant := range item.(*Graph).Constants {
+			if constant == nil {
+				logger.Log(logger.LevelError, "library", "found null constant")
+				return os.ErrInvalid
+			}
+		}
+
 		for _, group := range item.(*Graph).Groups {
 			if group == nil {
 				logger.Log(logger.LevelError, "library", "found null group")
@@ -363,6 +467,13 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 
 			groupSet.Add(group.Name)
 
+			if group.Consolidation != "" {
+				if _, _, err := plot.ParseConsolidation(group.Consolidation); err != nil {
+					logger.Log(logger.LevelError, "library", "group `%s': %s", group.Name, err)
+					return os.ErrInvalid
+				}
+			}
+
 			for _, series := range group.Series {
 				if series == nil {
 					logger.Log(logger.LevelError, "library", "found null series in group `%s'", group.Name)
@@ -373,6 +484,49 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 				}
 
 				seriesSet.Add(series.Name)
+
+				// Check for series transform validity
+				if transform, _ := config.GetString(series.Options, "transform", false); transform != "" {
+					if _, err := plot.ParseTransform(transform); err != nil {
+						logger.Log(logger.LevelError, "library", "series `%s': %s", series.Name, err)
+						return os.ErrInvalid
+					}
+				}
+
+				if counterMax, err := config.GetFloat(series.Options, "counter_max", false); err != nil ||
+					counterMax < 0 {
+					logger.Log(logger.LevelError, "library", "series `%s': invalid counter maximal value",
+						series.Name)
+					return os.ErrInvalid
+				}
+			}
+
+			// Check for group expression validity
+			if group.Expr == "" {
+				continue
+			}
+
+			expr, err := plot.ParseExpr(group.Expr)
+			if err != nil {
+				logger.Log(logger.LevelError, "library", "invalid expression in group `%s': %s", group.Name, err)
+				return os.ErrInvalid
+			}
+
+			for _, name := range expr.Names() {
+				found := false
+
+				for _, series := range group.Series {
+					if series.Name == name {
+						found = true
+						break
+					}
+				}
+
+				if !found {
+					logger.Log(logger.LevelError, "library", "unknown series `%s' in group `%s' expression", name,
+						group.Name)
+					return os.ErrInvalid
+				}
 			}
 		}
 
@@ -380,15 +534,100 @@ func (library *Library) StoreItem(item interface{}, itemType int) error {
 		library.Graphs[itemStruct.ID].ID = itemStruct.ID
 
 	case LibraryItemCollection:
+		if generator := item.(*Collection).Generator; generator != nil {
+			if generator.Type != "" && generator.Type != GeneratorTypeSource && generator.Type != GeneratorTypeMetric {
+				logger.Log(logger.LevelError, "library", "unknown generator type `%s'", generator.Type)
+				return os.ErrInvalid
+			} else if generator.Group == "" {
+				logger.Log(logger.LevelError, "library", "missing generator group")
+				return os.ErrInvalid
+			} else if !library.ItemExists(generator.Graph, LibraryItemGraph) {
+				logger.Log(logger.LevelError, "library", "unknown generator graph identifier `%s'", generator.Graph)
+				return os.ErrInvalid
+			}
+
+			found := false
+
+			for _, variable := range library.Graphs[generator.Graph].Variables {
+				if variable.Name == generator.variable() {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				logger.Log(logger.LevelError, "library", "unknown variable `%s' in generator graph `%s'",
+					generator.variable(), generator.Graph)
+				return os.ErrInvalid
+			}
+		}
+
 		library.Collections[itemStruct.ID] = item.(*Collection)
 		library.Collections[itemStruct.ID].ID = itemStruct.ID
+
+	case LibraryItemAnnotation:
+		annotation := item.(*Annotation)
+
+		if annotation.Start.IsZero() {
+			logger.Log(logger.LevelError, "library", "missing annotation start time")
+			return os.ErrInvalid
+		} else if annotation.End.IsZero() {
+			annotation.End = annotation.Start
+		} else if annotation.End.Before(annotation.Start) {
+			logger.Log(logger.LevelError, "library", "annotation end time before start time")
+			return os.ErrInvalid
+		}
+
+		tagSet := set.New(set.ThreadSafe)
+
+		for _, tag := range annotation.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || tagSet.Has(tag) {
+				continue
+			}
+
+			tagSet.Add(tag)
+		}
+
+		annotation.Tags = set.StringSlice(tagSet)
+		sort.Strings(annotation.Tags)
+
+		library.Annotations[itemStruct.ID] = annotation
+		library.Annotations[itemStruct.ID].ID = itemStruct.ID
+	}
+
+	filePath := library.getFilePath(itemStruct.ID, itemType)
+
+	// Keep existing item definition as initial revision if none has been recorded yet
+	if len(library.getRevisionIDs(itemStruct.ID, itemType)) == 0 {
+		if fileInfo, err := os.Stat(filePath); err == nil {
+			if data, err := ioutil.ReadFile(filePath); err == nil {
+				if err := library.storeRevision(itemStruct.ID, itemType, data, fileInfo.ModTime(), ""); err != nil {
+					logger.Log(logger.LevelError, "library", "unable to store revision: %s", err)
+				}
+			}
+		}
 	}
 
 	// Store JSON data
-	if err := utils.JSONDump(library.getFilePath(itemStruct.ID, itemType), item, itemStruct.Modified); err != nil {
+	if err := utils.JSONDump(filePath, item, itemStruct.Modified); err != nil {
 		return err
 	}
 
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	modified := itemStruct.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	if err := library.storeRevision(itemStruct.ID, itemType, data, modified, author); err != nil {
+		logger.Log(logger.LevelError, "library", "unable to store revision: %s", err)
+	}
+
 	return nil
 }
 
@@ -413,6 +652,9 @@ func (library *Library) getDirPath(itemType int) string {
 
 	case LibraryItemCollection:
 		dirName = "collections"
+
+	case LibraryItemAnnotation:
+		dirName = "annotations"
 	}
 
 	return path.Join(library.Config.DataDir, dirName)
@@ -28,6 +28,8 @@ const (
 	LibraryItemGraph
 	// LibraryItemCollection represents a collection item.
 	LibraryItemCollection
+	// LibraryItemAnnotation represents an annotation item.
+	LibraryItemAnnotation
 )
 
 const (
@@ -44,6 +46,7 @@ type Library struct {
 	Units       map[string]*Unit
 	Graphs      map[string]*Graph
 	Collections map[string]*Collection
+	Annotations map[string]*Annotation
 	idRegexp    *regexp.Regexp
 }
 
@@ -66,6 +69,7 @@ func (library *Library) Refresh() error {
 	library.Units = make(map[string]*Unit)
 	library.Graphs = make(map[string]*Graph)
 	library.Collections = make(map[string]*Collection)
+	library.Annotations = make(map[string]*Annotation)
 
 	walkFunc := func(filePath string, fileInfo os.FileInfo, fileError error) error {
 		mode := fileInfo.Mode() & os.ModeType
@@ -89,6 +93,7 @@ func (library *Library) Refresh() error {
 		LibraryItemUnit,
 		LibraryItemGraph,
 		LibraryItemCollection,
+		LibraryItemAnnotation,
 	} {
 		dirPath := library.getDirPath(itemType)
 
@@ -0,0 +1,261 @@
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/utils"
+	uuid "github.com/facette/facette/thirdparty/github.com/nu7hatch/gouuid"
+)
+
+// Revision represents a stored version of a library item.
+type Revision struct {
+	ID       int             `json:"id"`
+	Modified time.Time       `json:"modified"`
+	Author   string          `json:"author,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// GetRevisions returns the list of revisions of a library item, sorted from the oldest to the newest. Revisions of
+// deleted items remain available.
+func (library *Library) GetRevisions(id string, itemType int) ([]*Revision, error) {
+	if !library.revisionsExist(id, itemType) {
+		return nil, os.ErrNotExist
+	}
+
+	revisions := []*Revision{}
+
+	for _, revisionID := range library.getRevisionIDs(id, itemType) {
+		revision, err := library.GetRevision(id, itemType, revisionID)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns a specific revision of a library item.
+func (library *Library) GetRevision(id string, itemType, revisionID int) (*Revision, error) {
+	if !library.revisionsExist(id, itemType) {
+		return nil, os.ErrNotExist
+	}
+
+	revision := &Revision{}
+
+	if _, err := utils.JSONLoad(library.getRevisionFilePath(id, itemType, revisionID), revision); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// DiffRevisions returns the unified diff between two revisions of a library item.
+func (library *Library) DiffRevisions(id string, itemType, fromID, toID int) (string, error) {
+	var data [2]bytes.Buffer
+
+	for index, revisionID := range []int{fromID, toID} {
+		revision, err := library.GetRevision(id, itemType, revisionID)
+		if err != nil {
+			return "", err
+		}
+
+		if err := json.Indent(&data[index], revision.Data, "", "    "); err != nil {
+			return "", err
+		}
+	}
+
+	return utils.Diff(fmt.Sprintf("revision %d", fromID), fmt.Sprintf("revision %d", toID), data[0].String(),
+		data[1].String(), 3), nil
+}
+
+// RestoreRevision stores again a previous revision of a library item, re-creating the item if it has been deleted.
+func (library *Library) RestoreRevision(id string, itemType, revisionID int, author string) error {
+	var item interface{}
+
+	revision, err := library.GetRevision(id, itemType, revisionID)
+	if err != nil {
+		return err
+	}
+
+	switch itemType {
+	case LibraryItemSourceGroup, LibraryItemMetricGroup:
+		item = &Group{Type: itemType}
+
+	case LibraryItemScale:
+		item = &Scale{}
+
+	case LibraryItemUnit:
+		item = &Unit{}
+
+	case LibraryItemGraph:
+		item = &Graph{}
+
+	case LibraryItemCollection:
+		item = &Collection{}
+
+	case LibraryItemAnnotation:
+		item = &Annotation{}
+
+	default:
+		return os.ErrInvalid
+	}
+
+	if err := json.Unmarshal(revision.Data, item); err != nil {
+		return err
+	}
+
+	itemStruct := item.(interface {
+		GetItem() *Item
+	}).GetItem()
+
+	itemStruct.ID = id
+	itemStruct.Modified = time.Now()
+
+	// Keep current collection children list and owner
+	current := library.Collections[id]
+
+	if itemType == LibraryItemCollection && current != nil {
+		item.(*Collection).Children = current.Children
+		item.(*Collection).Owner = current.Owner
+	}
+
+	if err := library.storeItem(item, itemType, author, true); err != nil {
+		return err
+	}
+
+	if itemType == LibraryItemCollection {
+		library.restoreCollectionRelations(current, item.(*Collection))
+	}
+
+	return nil
+}
+
+// restoreCollectionRelations moves the parent-children relations of a collection onto its new version (current being
+// nil if the collection did not exist before).
+func (library *Library) restoreCollectionRelations(current, collection *Collection) {
+	for _, child := range collection.Children {
+		child.Parent = collection
+	}
+
+	if current != nil {
+		library.detachCollection(current)
+	}
+
+	if parent, ok := library.Collections[collection.ParentID]; ok && parent != collection {
+		collection.Parent = parent
+		parent.Children = append(parent.Children, collection)
+	} else {
+		collection.ParentID = ""
+	}
+}
+
+// detachCollection removes a collection from its parent children list.
+func (library *Library) detachCollection(collection *Collection) {
+	if collection.Parent == nil {
+		return
+	}
+
+	children := collection.Parent.Children
+
+	for index, child := range children {
+		if child == collection {
+			collection.Parent.Children = append(children[:index], children[index+1:]...)
+			break
+		}
+	}
+}
+
+func (library *Library) storeRevision(id string, itemType int, data []byte, modified time.Time, author string) error {
+	revisionID := 1
+
+	if revisionIDs := library.getRevisionIDs(id, itemType); len(revisionIDs) > 0 {
+		revisionID = revisionIDs[len(revisionIDs)-1] + 1
+	}
+
+	revision := &Revision{
+		ID:       revisionID,
+		Modified: modified,
+		Author:   author,
+		Data:     json.RawMessage(data),
+	}
+
+	if err := utils.JSONDump(library.getRevisionFilePath(id, itemType, revisionID), revision, modified); err != nil {
+		return err
+	}
+
+	// Discard oldest revisions exceeding the retention limit
+	if library.Config.RevisionsLimit <= 0 {
+		return nil
+	}
+
+	revisionIDs := library.getRevisionIDs(id, itemType)
+
+	for len(revisionIDs) > library.Config.RevisionsLimit {
+		if err := os.Remove(library.getRevisionFilePath(id, itemType, revisionIDs[0])); err != nil {
+			logger.Log(logger.LevelError, "library", "unable to discard revision: %s", err)
+		}
+
+		revisionIDs = revisionIDs[1:]
+	}
+
+	return nil
+}
+
+// revisionsExist returns whether revisions can be found for a library item, either existing or deleted.
+func (library *Library) revisionsExist(id string, itemType int) bool {
+	if library.ItemExists(id, itemType) {
+		return true
+	}
+
+	// Only accept canonical identifiers as they are used to build the revisions directory path
+	if uuidTemp, err := uuid.ParseHex(id); err != nil || uuidTemp.String() != id {
+		return false
+	}
+
+	return len(library.getRevisionIDs(id, itemType)) > 0
+}
+
+func (library *Library) getRevisionIDs(id string, itemType int) []int {
+	var revisionIDs []int
+
+	entries, err := ioutil.ReadDir(library.getRevisionsDirPath(id, itemType))
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		if revisionID, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json")); err == nil {
+			revisionIDs = append(revisionIDs, revisionID)
+		}
+	}
+
+	sort.Ints(revisionIDs)
+
+	return revisionIDs
+}
+
+func (library *Library) getRevisionsDirPath(id string, itemType int) string {
+	_, dirName := path.Split(library.getDirPath(itemType))
+
+	return path.Join(library.Config.DataDir, "revisions", dirName, id[0:2], id[2:4], id)
+}
+
+func (library *Library) getRevisionFilePath(id string, itemType, revisionID int) string {
+	return path.Join(library.getRevisionsDirPath(id, itemType), strconv.Itoa(revisionID)+".json")
+}
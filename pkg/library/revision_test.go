@@ -0,0 +1,191 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/facette/facette/pkg/config"
+)
+
+func Test_Revisions(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 3)
+	defer cleanup()
+
+	graph := &Graph{Item: Item{Name: "graph1"}}
+
+	if err := library.StoreItem(graph, LibraryItemGraph, "user1"); err != nil {
+		test.Fatalf("unable to store graph: %s", err)
+	}
+
+	for _, name := range []string{"graph2", "graph3", "graph4"} {
+		if err := library.StoreItem(&Graph{Item: Item{ID: graph.ID, Name: name}}, LibraryItemGraph,
+			"user2"); err != nil {
+			test.Fatalf("unable to store graph: %s", err)
+		}
+	}
+
+	// Check for revisions retention
+	revisions, err := library.GetRevisions(graph.ID, LibraryItemGraph)
+	if err != nil {
+		test.Fatalf("unable to get revisions: %s", err)
+	}
+
+	if ids := revisionIDs(revisions); !reflect.DeepEqual(ids, []int{2, 3, 4}) {
+		test.Logf("\nExpected %v\nbut got  %v", []int{2, 3, 4}, ids)
+		test.Fail()
+	}
+
+	if revisions[0].Author != "user2" {
+		test.Logf("\nExpected %q\nbut got  %q", "user2", revisions[0].Author)
+		test.Fail()
+	}
+
+	if _, err := library.GetRevision(graph.ID, LibraryItemGraph, 1); !os.IsNotExist(err) {
+		test.Logf("\nExpected %v\nbut got  %v", os.ErrNotExist, err)
+		test.Fail()
+	}
+
+	// Check for revisions diff
+	diff, err := library.DiffRevisions(graph.ID, LibraryItemGraph, 2, 4)
+	if err != nil {
+		test.Fatalf("unable to diff revisions: %s", err)
+	}
+
+	if !strings.HasPrefix(diff, "--- revision 2\n+++ revision 4\n") ||
+		!strings.Contains(diff, "\n-    \"name\": \"graph2\",\n+    \"name\": \"graph4\",\n") {
+		test.Logf("\nUnexpected diff %q", diff)
+		test.Fail()
+	}
+
+	if _, err := library.DiffRevisions(graph.ID, LibraryItemGraph, 1, 4); !os.IsNotExist(err) {
+		test.Logf("\nExpected %v\nbut got  %v", os.ErrNotExist, err)
+		test.Fail()
+	}
+
+	// Check for revision restoration
+	if err := library.RestoreRevision(graph.ID, LibraryItemGraph, 2, "user3"); err != nil {
+		test.Fatalf("unable to restore revision: %s", err)
+	}
+
+	if name := library.Graphs[graph.ID].Name; name != "graph2" {
+		test.Logf("\nExpected %q\nbut got  %q", "graph2", name)
+		test.Fail()
+	}
+
+	revisions, _ = library.GetRevisions(graph.ID, LibraryItemGraph)
+	if ids := revisionIDs(revisions); !reflect.DeepEqual(ids, []int{3, 4, 5}) {
+		test.Logf("\nExpected %v\nbut got  %v", []int{3, 4, 5}, ids)
+		test.Fail()
+	} else if revisions[2].Author != "user3" {
+		test.Logf("\nExpected %q\nbut got  %q", "user3", revisions[2].Author)
+		test.Fail()
+	}
+
+	// Check for deleted item revisions restoration
+	if err := library.DeleteItem(graph.ID, LibraryItemGraph, DeleteModeForce); err != nil {
+		test.Fatalf("unable to delete graph: %s", err)
+	}
+
+	if revisions, err = library.GetRevisions(graph.ID, LibraryItemGraph); err != nil || len(revisions) != 3 {
+		test.Logf("\nExpected %d revisions\nbut got  %d (%v)", 3, len(revisions), err)
+		test.Fail()
+	}
+
+	if err := library.RestoreRevision(graph.ID, LibraryItemGraph, 4, "user1"); err != nil {
+		test.Fatalf("unable to restore deleted graph: %s", err)
+	}
+
+	if !library.ItemExists(graph.ID, LibraryItemGraph) || library.Graphs[graph.ID].Name != "graph4" {
+		test.Logf("\nExpected restored graph %q", "graph4")
+		test.Fail()
+	}
+
+	// Check for unknown items
+	for _, id := range []string{"00000000-0000-0000-0000-000000000000", "../..", ""} {
+		if _, err := library.GetRevisions(id, LibraryItemGraph); !os.IsNotExist(err) {
+			test.Logf("\nExpected %v\nbut got  %v", os.ErrNotExist, err)
+			test.Fail()
+		}
+	}
+}
+
+func Test_RestoreCollectionRevision(test *testing.T) {
+	library, cleanup := newTestLibrary(test, 0)
+	defer cleanup()
+
+	parent := &Collection{Item: Item{Name: "parent"}, Owner: "user1"}
+	if err := library.StoreItem(parent, LibraryItemCollection, "user1"); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	collection := &Collection{Item: Item{Name: "collection1"}, Owner: "user1"}
+	if err := library.StoreItem(collection, LibraryItemCollection, "user1"); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	child := &Collection{Item: Item{Name: "child"}, ParentID: collection.ID, Parent: collection}
+	if err := library.StoreItem(child, LibraryItemCollection, "user1"); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	collection.Children = append(collection.Children, child)
+
+	// Change collection owner and parent, then restore its first revision
+	updated := &Collection{Item: Item{ID: collection.ID, Name: "collection2"}, Owner: "user2",
+		ParentID: parent.ID, Parent: parent, Children: collection.Children}
+	if err := library.StoreItem(updated, LibraryItemCollection, "user2"); err != nil {
+		test.Fatalf("unable to store collection: %s", err)
+	}
+
+	parent.Children = append(parent.Children, updated)
+
+	if err := library.RestoreRevision(collection.ID, LibraryItemCollection, 1, "user1"); err != nil {
+		test.Fatalf("unable to restore revision: %s", err)
+	}
+
+	restored := library.Collections[collection.ID]
+
+	if restored.Name != "collection1" {
+		test.Logf("\nExpected %q\nbut got  %q", "collection1", restored.Name)
+		test.Fail()
+	}
+
+	if restored.Owner != "user2" {
+		test.Logf("\nExpected %q\nbut got  %q", "user2", restored.Owner)
+		test.Fail()
+	}
+
+	if restored.Parent != nil || len(parent.Children) != 0 {
+		test.Logf("\nExpected collection to be detached from its parent")
+		test.Fail()
+	}
+
+	if len(restored.Children) != 1 || restored.Children[0] != child || child.Parent != restored {
+		test.Logf("\nExpected collection children to be kept")
+		test.Fail()
+	}
+}
+
+func newTestLibrary(test *testing.T, revisionsLimit int) (*Library, func()) {
+	dirPath, err := ioutil.TempDir("", "facette")
+	if err != nil {
+		test.Fatalf("unable to create temporary directory: %s", err)
+	}
+
+	library := NewLibrary(&config.Config{DataDir: dirPath, RevisionsLimit: revisionsLimit}, nil)
+	library.Refresh()
+
+	return library, func() { os.RemoveAll(dirPath) }
+}
+
+func revisionIDs(revisions []*Revision) []int {
+	result := make([]int, len(revisions))
+	for index, revision := range revisions {
+		result[index] = revision.ID
+	}
+
+	return result
+}
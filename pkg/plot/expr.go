@@ -0,0 +1,601 @@
+package plot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	_ = iota
+	exprTokenNumber
+	exprTokenIdent
+	exprTokenString
+	exprTokenOperator
+	exprTokenLeftParen
+	exprTokenRightParen
+	exprTokenComma
+	exprTokenEOF
+)
+
+var exprFunctions = map[string]bool{
+	"abs":   true,
+	"avg":   true,
+	"max":   true,
+	"min":   true,
+	"rate":  true,
+	"scale": true,
+	"sum":   true,
+}
+
+// Expr represents a parsed series expression (e.g. `rate(a) / scale(b, 8)').
+type Expr struct {
+	root  exprNode
+	names []string
+}
+
+type exprToken struct {
+	kind  int
+	text  string
+	value float64
+}
+
+type exprValue struct {
+	scalar bool
+	value  Value
+	values []Value
+}
+
+type exprContext struct {
+	series map[string][]Value
+	count  int
+	step   int
+}
+
+type exprNode interface {
+	eval(*exprContext) (exprValue, error)
+}
+
+type exprNumberNode struct {
+	value Value
+}
+
+type exprIdentNode struct {
+	name string
+}
+
+type exprUnaryNode struct {
+	operand exprNode
+}
+
+type exprBinaryNode struct {
+	operator byte
+	left     exprNode
+	right    exprNode
+}
+
+type exprFuncNode struct {
+	name string
+	args []exprNode
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	names  map[string]bool
+}
+
+// ParseExpr parses a series expression string.
+func ParseExpr(input string) (*Expr, error) {
+	tokens, err := exprTokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &exprParser{tokens: tokens, names: make(map[string]bool)}
+
+	root, err := parser.parseExpr()
+	if err != nil {
+		return nil, err
+	} else if token := parser.peek(); token.kind != exprTokenEOF {
+		return nil, fmt.Errorf("unexpected token `%s'", token.text)
+	}
+
+	expr := &Expr{root: root}
+
+	for name := range parser.names {
+		expr.names = append(expr.names, name)
+	}
+
+	sort.Strings(expr.names)
+
+	return expr, nil
+}
+
+// Names returns the list of series names referenced in the expression.
+func (expr *Expr) Names() []string {
+	return expr.names
+}
+
+// Eval evaluates the expression against a set of named series. Series are normalized prior to evaluation.
+func (expr *Expr) Eval(series map[string]Series) (Series, error) {
+	var (
+		seriesList []Series
+		refSeries  Series
+	)
+
+	for _, name := range expr.names {
+		if _, ok := series[name]; !ok {
+			return Series{}, fmt.Errorf("unknown series `%s'", name)
+		}
+
+		seriesList = append(seriesList, series[name])
+	}
+
+	ctx := &exprContext{series: make(map[string][]Value)}
+
+	if len(seriesList) > 0 {
+		normalizedSeriesList, err := NormalizeSeries(seriesList, ConsolidateAverage)
+		if err != nil {
+			return Series{}, fmt.Errorf("unable to normalize series: %s", err)
+		}
+
+		for i := range expr.names {
+			if len(normalizedSeriesList[i].Plots) > ctx.count {
+				ctx.count = len(normalizedSeriesList[i].Plots)
+				refSeries = normalizedSeriesList[i]
+			}
+		}
+
+		ctx.step = refSeries.Step
+
+		// Pad shorter series with NaN values
+		for i, name := range expr.names {
+			ctx.series[name] = make([]Value, ctx.count)
+
+			for j := range ctx.series[name] {
+				if j < len(normalizedSeriesList[i].Plots) {
+					ctx.series[name][j] = normalizedSeriesList[i].Plots[j].Value
+				} else {
+					ctx.series[name][j] = Value(math.NaN())
+				}
+			}
+		}
+	}
+
+	result, err := expr.root.eval(ctx)
+	if err != nil {
+		return Series{}, err
+	}
+
+	resultSeries := Series{
+		Plots:   make([]Plot, ctx.count),
+		Step:    ctx.step,
+		Summary: make(map[string]Value),
+	}
+
+	for i := range resultSeries.Plots {
+		resultSeries.Plots[i].Time = refSeries.Plots[i].Time
+		resultSeries.Plots[i].Value = result.at(i)
+	}
+
+	return resultSeries, nil
+}
+
+func (value exprValue) at(index int) Value {
+	if value.scalar {
+		return value.value
+	}
+
+	return value.values[index]
+}
+
+func (node exprNumberNode) eval(ctx *exprContext) (exprValue, error) {
+	return exprValue{scalar: true, value: node.value}, nil
+}
+
+func (node exprIdentNode) eval(ctx *exprContext) (exprValue, error) {
+	values := make([]Value, ctx.count)
+	copy(values, ctx.series[node.name])
+
+	return exprValue{values: values}, nil
+}
+
+func (node exprUnaryNode) eval(ctx *exprContext) (exprValue, error) {
+	operand, err := node.operand.eval(ctx)
+	if err != nil {
+		return exprValue{}, err
+	}
+
+	return exprApply(ctx, []exprValue{operand}, func(args []Value) Value {
+		return -args[0]
+	}), nil
+}
+
+func (node exprBinaryNode) eval(ctx *exprContext) (exprValue, error) {
+	left, err := node.left.eval(ctx)
+	if err != nil {
+		return exprValue{}, err
+	}
+
+	right, err := node.right.eval(ctx)
+	if err != nil {
+		return exprValue{}, err
+	}
+
+	return exprApply(ctx, []exprValue{left, right}, func(args []Value) Value {
+		switch node.operator {
+		case '+':
+			return args[0] + args[1]
+		case '-':
+			return args[0] - args[1]
+		case '*':
+			return args[0] * args[1]
+		case '/':
+			if args[1] == 0 {
+				return Value(math.NaN())
+			}
+
+			return args[0] / args[1]
+		}
+
+		return Value(math.NaN())
+	}), nil
+}
+
+func (node exprFuncNode) eval(ctx *exprContext) (exprValue, error) {
+	args := make([]exprValue, len(node.args))
+
+	for i := range node.args {
+		value, err := node.args[i].eval(ctx)
+		if err != nil {
+			return exprValue{}, err
+		}
+
+		args[i] = value
+	}
+
+	switch node.name {
+	case "abs":
+		if len(args) != 1 {
+			return exprValue{}, fmt.Errorf("function `%s' expects 1 argument", node.name)
+		}
+
+		return exprApply(ctx, args, func(args []Value) Value {
+			return Value(math.Abs(float64(args[0])))
+		}), nil
+
+	case "scale":
+		if len(args) != 2 {
+			return exprValue{}, fmt.Errorf("function `%s' expects 2 arguments", node.name)
+		} else if !args[1].scalar {
+			return exprValue{}, fmt.Errorf("function `%s' expects a numeric factor", node.name)
+		}
+
+		return exprApply(ctx, args, func(args []Value) Value {
+			return args[0] * args[1]
+		}), nil
+
+	case "rate":
+		if len(args) != 1 {
+			return exprValue{}, fmt.Errorf("function `%s' expects 1 argument", node.name)
+		} else if args[0].scalar {
+			return exprValue{}, fmt.Errorf("function `%s' expects a series argument", node.name)
+		} else if ctx.step <= 0 {
+			return exprValue{}, fmt.Errorf("function `%s' requires series step information", node.name)
+		}
+
+		values := make([]Value, ctx.count)
+
+		for i := range values {
+			if i == 0 {
+				values[i] = Value(math.NaN())
+				continue
+			}
+
+			values[i] = (args[0].values[i] - args[0].values[i-1]) / Value(ctx.step)
+		}
+
+		return exprValue{values: values}, nil
+
+	case "avg", "max", "min", "sum":
+		if len(args) == 0 {
+			return exprValue{}, fmt.Errorf("function `%s' expects at least 1 argument", node.name)
+		}
+
+		return exprApply(ctx, args, func(args []Value) Value {
+			return exprAggregate(node.name, args)
+		}), nil
+	}
+
+	return exprValue{}, fmt.Errorf("unknown function `%s'", node.name)
+}
+
+func exprAggregate(name string, values []Value) Value {
+	var (
+		result Value
+		count  int
+	)
+
+	for _, value := range values {
+		if value.IsNaN() {
+			continue
+		}
+
+		switch {
+		case count == 0:
+			result = value
+		case name == "max" && value > result, name == "min" && value < result:
+			result = value
+		case name == "avg" || name == "sum":
+			result += value
+		}
+
+		count++
+	}
+
+	if count == 0 {
+		return Value(math.NaN())
+	} else if name == "avg" {
+		return result / Value(count)
+	}
+
+	return result
+}
+
+func exprApply(ctx *exprContext, args []exprValue, callback func([]Value) Value) exprValue {
+	scalar := true
+
+	for _, arg := range args {
+		if !arg.scalar {
+			scalar = false
+			break
+		}
+	}
+
+	values := make([]Value, len(args))
+
+	if scalar {
+		for i := range args {
+			values[i] = args[i].value
+		}
+
+		return exprValue{scalar: true, value: exprSanitize(callback(values))}
+	}
+
+	result := exprValue{values: make([]Value, ctx.count)}
+
+	for i := range result.values {
+		for j := range args {
+			values[j] = args[j].at(i)
+		}
+
+		result.values[i] = exprSanitize(callback(values))
+	}
+
+	return result
+}
+
+func exprSanitize(value Value) Value {
+	if math.IsInf(float64(value), 0) {
+		return Value(math.NaN())
+	}
+
+	return value
+}
+
+func exprTokenize(input string) ([]exprToken, error) {
+	var tokens []exprToken
+
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				(runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E')) {
+				i++
+			}
+
+			value, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number `%s'", string(runes[start:i]))
+			}
+
+			tokens = append(tokens, exprToken{kind: exprTokenNumber, text: string(runes[start:i]), value: value})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			tokens = append(tokens, exprToken{kind: exprTokenIdent, text: string(runes[start:i])})
+
+		case r == '"':
+			start := i
+			i++
+
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+
+			tokens = append(tokens, exprToken{kind: exprTokenString, text: string(runes[start+1 : i])})
+			i++
+
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, exprToken{kind: exprTokenOperator, text: string(r)})
+			i++
+
+		case r == '(':
+			tokens = append(tokens, exprToken{kind: exprTokenLeftParen, text: "("})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, exprToken{kind: exprTokenRightParen, text: ")"})
+			i++
+
+		case r == ',':
+			tokens = append(tokens, exprToken{kind: exprTokenComma, text: ","})
+			i++
+
+		default:
+			return nil, fmt.Errorf("unexpected character `%c' at position %d", r, i)
+		}
+	}
+
+	return append(tokens, exprToken{kind: exprTokenEOF, text: "EOF"}), nil
+}
+
+func (parser *exprParser) peek() exprToken {
+	return parser.tokens[parser.pos]
+}
+
+func (parser *exprParser) next() exprToken {
+	token := parser.tokens[parser.pos]
+
+	if token.kind != exprTokenEOF {
+		parser.pos++
+	}
+
+	return token
+}
+
+func (parser *exprParser) parseExpr() (exprNode, error) {
+	left, err := parser.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token := parser.peek()
+		if token.kind != exprTokenOperator || token.text != "+" && token.text != "-" {
+			return left, nil
+		}
+
+		parser.next()
+
+		right, err := parser.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		left = exprBinaryNode{operator: token.text[0], left: left, right: right}
+	}
+}
+
+func (parser *exprParser) parseTerm() (exprNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token := parser.peek()
+		if token.kind != exprTokenOperator || token.text != "*" && token.text != "/" {
+			return left, nil
+		}
+
+		parser.next()
+
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = exprBinaryNode{operator: token.text[0], left: left, right: right}
+	}
+}
+
+func (parser *exprParser) parseUnary() (exprNode, error) {
+	if token := parser.peek(); token.kind == exprTokenOperator && token.text == "-" {
+		parser.next()
+
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return exprUnaryNode{operand: operand}, nil
+	}
+
+	return parser.parsePrimary()
+}
+
+func (parser *exprParser) parsePrimary() (exprNode, error) {
+	token := parser.next()
+
+	switch token.kind {
+	case exprTokenNumber:
+		return exprNumberNode{value: Value(token.value)}, nil
+
+	case exprTokenString:
+		parser.names[token.text] = true
+		return exprIdentNode{name: token.text}, nil
+
+	case exprTokenIdent:
+		if parser.peek().kind != exprTokenLeftParen {
+			parser.names[token.text] = true
+			return exprIdentNode{name: token.text}, nil
+		}
+
+		if !exprFunctions[token.text] {
+			return nil, fmt.Errorf("unknown function `%s'", token.text)
+		}
+
+		parser.next()
+
+		node := exprFuncNode{name: token.text}
+
+		if parser.peek().kind == exprTokenRightParen {
+			parser.next()
+			return node, nil
+		}
+
+		for {
+			arg, err := parser.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			node.args = append(node.args, arg)
+
+			if token := parser.next(); token.kind == exprTokenRightParen {
+				break
+			} else if token.kind != exprTokenComma {
+				return nil, fmt.Errorf("unexpected token `%s', expected `,' or `)'", token.text)
+			}
+		}
+
+		return node, nil
+
+	case exprTokenLeftParen:
+		node, err := parser.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if token := parser.next(); token.kind != exprTokenRightParen {
+			return nil, fmt.Errorf("unexpected token `%s', expected `)'", token.text)
+		}
+
+		return node, nil
+	}
+
+	return nil, fmt.Errorf("unexpected token `%s'", token.text)
+}
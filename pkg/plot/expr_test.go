@@ -0,0 +1,111 @@
+package plot
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var exprSeries = map[string]Series{
+	"a": {Step: 10, Plots: []Plot{{Value: 10}, {Value: 30}, {Value: 60}, {Value: Value(math.NaN())}, {Value: 100}}},
+	"b": {Step: 10, Plots: []Plot{{Value: 2}, {Value: 4}, {Value: 0}, {Value: 8}, {Value: 10}}},
+	"c": {Step: 20, Plots: []Plot{{Value: 1}, {Value: 2}, {Value: 3}}},
+}
+
+func Test_ExprParse(test *testing.T) {
+	for _, entry := range []struct {
+		Input string
+		Names []string
+	}{
+		{"a", []string{"a"}},
+		{"a - b", []string{"a", "b"}},
+		{"rate(a) / scale(b, 8)", []string{"a", "b"}},
+		{"max(a, b, 2)", []string{"a", "b"}},
+		{"-(a + \"b\") * 2.5e1", []string{"a", "b"}},
+		{"1 + 2", nil},
+	} {
+		expr, err := ParseExpr(entry.Input)
+		if err != nil {
+			test.Logf("ParseExpr(%q) returned an error: %s", entry.Input, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(entry.Names, expr.Names()) {
+			test.Logf("\nExpected %#v\nbut got  %#v", entry.Names, expr.Names())
+			test.Fail()
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		"a +",
+		"(a - b",
+		"a b",
+		"foo(a)",
+		"a $ b",
+		"max(a b)",
+		"\"a",
+	} {
+		if _, err := ParseExpr(input); err == nil {
+			test.Logf("ParseExpr(%q) should have returned an error", input)
+			test.Fail()
+		}
+	}
+}
+
+func Test_ExprEval(test *testing.T) {
+	for _, entry := range []struct {
+		Input  string
+		Result []Value
+	}{
+		{"a - b", []Value{8, 26, 60, Value(math.NaN()), 90}},
+		{"a / b", []Value{5, 7.5, Value(math.NaN()), Value(math.NaN()), 10}},
+		{"-a + 2 * b", []Value{-6, -22, -60, Value(math.NaN()), -80}},
+		{"scale(b, 8)", []Value{16, 32, 0, 64, 80}},
+		{"rate(a)", []Value{Value(math.NaN()), 2, 3, Value(math.NaN()), Value(math.NaN())}},
+		{"max(a, b)", []Value{10, 30, 60, 8, 100}},
+		{"min(a, b, 3)", []Value{2, 3, 0, 3, 3}},
+		{"avg(a, b)", []Value{6, 17, 30, 8, 55}},
+		{"sum(a, b)", []Value{12, 34, 60, 8, 110}},
+		{"abs(b - a)", []Value{8, 26, 60, Value(math.NaN()), 90}},
+		{"a + c", []Value{21, 62, 103}},
+	} {
+		expr, err := ParseExpr(entry.Input)
+		if err != nil {
+			test.Logf("ParseExpr(%q) returned an error: %s", entry.Input, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expr.Eval(exprSeries)
+		if err != nil {
+			test.Logf("Eval(%q) returned an error: %s", entry.Input, err)
+			test.Fail()
+			continue
+		}
+
+		if len(result.Plots) != len(entry.Result) {
+			test.Logf("Eval(%q):\nExpected %v\nbut got  %v", entry.Input, entry.Result, result.Plots)
+			test.Fail()
+			continue
+		}
+
+		for i := range entry.Result {
+			if entry.Result[i].IsNaN() && !result.Plots[i].Value.IsNaN() ||
+				!entry.Result[i].IsNaN() && entry.Result[i] != result.Plots[i].Value {
+				test.Logf("Eval(%q):\nExpected %v\nbut got  %v", entry.Input, entry.Result, result.Plots)
+				test.Fail()
+				break
+			}
+		}
+	}
+
+	// Unknown series
+	expr, _ := ParseExpr("a + d")
+
+	if _, err := expr.Eval(exprSeries); err == nil {
+		test.Logf("Eval(%q) should have returned an error", "a + d")
+		test.Fail()
+	}
+}
@@ -2,6 +2,11 @@ package plot
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/facette/facette/pkg/utils"
 )
@@ -16,8 +21,72 @@ const (
 	ConsolidateMin
 	// ConsolidateSum represents a sum consolidation type.
 	ConsolidateSum
+	// ConsolidateMedian represents a median value consolidation type.
+	ConsolidateMedian
+	// ConsolidateFirst represents a first value consolidation type.
+	ConsolidateFirst
+	// ConsolidateLast represents a last value consolidation type.
+	ConsolidateLast
+	// ConsolidateCount represents a values count consolidation type.
+	ConsolidateCount
+	// ConsolidatePercentile represents a percentile value consolidation type.
+	ConsolidatePercentile
 )
 
+var consolidationTypes = map[string]int{
+	"average": ConsolidateAverage,
+	"max":     ConsolidateMax,
+	"min":     ConsolidateMin,
+	"sum":     ConsolidateSum,
+	"median":  ConsolidateMedian,
+	"first":   ConsolidateFirst,
+	"last":    ConsolidateLast,
+	"count":   ConsolidateCount,
+}
+
+// ParseConsolidation returns the consolidation type matching a consolidation name, along with the percentile value
+// if the name is a percentile one (e.g. `p99').
+func ParseConsolidation(name string) (int, float64, error) {
+	if consolidationType, ok := consolidationTypes[name]; ok {
+		return consolidationType, 0, nil
+	}
+
+	if strings.HasPrefix(name, "p") {
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64)
+		if err == nil && percentile > 0 && percentile <= 100 {
+			return ConsolidatePercentile, percentile, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("unknown consolidation `%s'", name)
+}
+
+const (
+	_ = iota
+	// TransformDerivative represents a point-to-point difference transform type.
+	TransformDerivative
+	// TransformPerSecond represents a per-second rate transform type.
+	TransformPerSecond
+	// TransformNonNegativeDerivative represents a counter difference transform type, handling counter wraps.
+	TransformNonNegativeDerivative
+)
+
+var transformTypes = map[string]int{
+	"derivative":              TransformDerivative,
+	"per_second":              TransformPerSecond,
+	"non_negative_derivative": TransformNonNegativeDerivative,
+}
+
+// ParseTransform returns the transform type matching a transform name.
+func ParseTransform(name string) (int, error) {
+	transformType, ok := transformTypes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown transform `%s'", name)
+	}
+
+	return transformType, nil
+}
+
 // NormalizeSeries aligns series steps to the less precise one.
 func NormalizeSeries(series []Series, consolidationType int) ([]Series, error) {
 	var step int
@@ -30,11 +99,20 @@ func NormalizeSeries(series []Series, consolidationType int) ([]Series, error) {
 
 	outputSeries := make([]Series, seriesCount)
 
-	// Get least common multiple
-	step = series[0].Step
-	if seriesCount > 1 {
-		for i := 1; i < seriesCount; i++ {
-			step = lcm(step, series[i].Step)
+	// Get least common multiple, guessing steps from plots time if not provided by the connector
+	steps := make([]int, seriesCount)
+
+	for i := range series {
+		if steps[i] = series[i].Step; steps[i] == 0 {
+			steps[i] = series[i].guessStep()
+		}
+
+		if steps[i] == 0 {
+			continue
+		} else if step == 0 {
+			step = steps[i]
+		} else {
+			step = lcm(step, steps[i])
 		}
 	}
 
@@ -42,13 +120,78 @@ func NormalizeSeries(series []Series, consolidationType int) ([]Series, error) {
 		outputSeries[i] = Series{}
 		utils.Clone(&serie, &outputSeries[i])
 
-		outputSeries[i].Consolidate(step/outputSeries[i].Step, consolidationType)
+		if steps[i] > 0 {
+			outputSeries[i].Consolidate(step/steps[i], consolidationType)
+		}
+
 		outputSeries[i].Step = step
 	}
 
 	return outputSeries, nil
 }
 
+// AlignSeries normalizes series steps and aligns their plots on the same timestamps, filling missing plots with NaN
+// values. Series coming from different connectors neither share the same start time nor the same number of plots.
+func AlignSeries(series []Series) ([]Series, error) {
+	normalizedSeries, err := NormalizeSeries(series, ConsolidateAverage)
+	if err != nil {
+		return nil, err
+	}
+
+	step := int64(normalizedSeries[0].Step)
+
+	bucketTime := func(plotTime time.Time) int64 {
+		if step <= 0 {
+			return plotTime.Unix()
+		}
+
+		return plotTime.Unix() - plotTime.Unix()%step
+	}
+
+	// Collect the union of all series buckets
+	bucketSet := make(map[int64]bool)
+
+	for _, serie := range normalizedSeries {
+		for _, plot := range serie.Plots {
+			bucketSet[bucketTime(plot.Time)] = true
+		}
+	}
+
+	buckets := make([]int64, 0, len(bucketSet))
+	for bucket := range bucketSet {
+		buckets = append(buckets, bucket)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	outputSeries := make([]Series, len(normalizedSeries))
+
+	for i, serie := range normalizedSeries {
+		values := make(map[int64]Value)
+		for _, plot := range serie.Plots {
+			values[bucketTime(plot.Time)] = plot.Value
+		}
+
+		outputSeries[i] = Series{
+			Name:    serie.Name,
+			Plots:   make([]Plot, len(buckets)),
+			Step:    serie.Step,
+			Summary: serie.Summary,
+		}
+
+		for j, bucket := range buckets {
+			value, ok := values[bucket]
+			if !ok {
+				value = Value(math.NaN())
+			}
+
+			outputSeries[i].Plots[j] = Plot{Time: time.Unix(bucket, 0), Value: value}
+		}
+	}
+
+	return outputSeries, nil
+}
+
 // AvgSeries returns a new series averaging each series' datapoints.
 func AvgSeries(seriesList []Series) (Series, error) {
 	nSeries := len(seriesList)
@@ -73,6 +216,7 @@ func AvgSeries(seriesList []Series) (Series, error) {
 
 	avgSeries := Series{
 		Plots:   make([]Plot, maxPlots),
+		Step:    normalizedSeriesList[0].Step,
 		Summary: make(map[string]Value),
 	}
 
@@ -85,9 +229,10 @@ func AvgSeries(seriesList []Series) (Series, error) {
 				continue
 			}
 
+			avgSeries.Plots[plotIndex].Time = series.Plots[plotIndex].Time
+
 			if !series.Plots[plotIndex].Value.IsNaN() {
 				avgSeries.Plots[plotIndex].Value += series.Plots[plotIndex].Value
-				avgSeries.Plots[plotIndex].Time = series.Plots[plotIndex].Time
 				validPlots++
 			}
 
@@ -95,6 +240,8 @@ func AvgSeries(seriesList []Series) (Series, error) {
 
 		if validPlots > 0 {
 			avgSeries.Plots[plotIndex].Value /= validPlots
+		} else {
+			avgSeries.Plots[plotIndex].Value = Value(math.NaN())
 		}
 	}
 
@@ -125,26 +272,233 @@ func SumSeries(seriesList []Series) (Series, error) {
 
 	sumSeries := Series{
 		Plots:   make([]Plot, maxPlots),
+		Step:    normalizedSeriesList[0].Step,
 		Summary: make(map[string]Value),
 	}
 
 	for plotIndex := 0; plotIndex < maxPlots; plotIndex++ {
+		var validPlots int
+
 		for _, series := range normalizedSeriesList {
 			// Skip shorter series
 			if plotIndex >= len(series.Plots) {
 				continue
 			}
 
+			sumSeries.Plots[plotIndex].Time = series.Plots[plotIndex].Time
+
 			if !series.Plots[plotIndex].Value.IsNaN() {
 				sumSeries.Plots[plotIndex].Value += series.Plots[plotIndex].Value
-				sumSeries.Plots[plotIndex].Time = series.Plots[plotIndex].Time
+				validPlots++
 			}
 		}
+
+		if validPlots == 0 {
+			sumSeries.Plots[plotIndex].Value = Value(math.NaN())
+		}
 	}
 
 	return sumSeries, nil
 }
 
+// MovingAverage returns a new series averaging each datapoint with the previous ones within a given window.
+func MovingAverage(series Series, window int) (Series, error) {
+	if window < 1 {
+		return Series{}, fmt.Errorf("invalid window size %d", window)
+	}
+
+	avgSeries := Series{
+		Plots:   make([]Plot, len(series.Plots)),
+		Step:    series.Step,
+		Summary: make(map[string]Value),
+	}
+
+	for i := range series.Plots {
+		var (
+			total       Value
+			validPlots  int
+			windowStart = i - window + 1
+		)
+
+		if windowStart < 0 {
+			windowStart = 0
+		}
+
+		for _, entry := range series.Plots[windowStart : i+1] {
+			if !entry.Value.IsNaN() {
+				total += entry.Value
+				validPlots++
+			}
+		}
+
+		avgSeries.Plots[i].Time = series.Plots[i].Time
+
+		if validPlots > 0 {
+			avgSeries.Plots[i].Value = total / Value(validPlots)
+		} else {
+			avgSeries.Plots[i].Value = Value(math.NaN())
+		}
+	}
+
+	return avgSeries, nil
+}
+
+// ExponentialMovingAverage returns a new series exponentially smoothing datapoints given a smoothing factor.
+func ExponentialMovingAverage(series Series, alpha float64) (Series, error) {
+	if alpha <= 0 || alpha > 1 {
+		return Series{}, fmt.Errorf("invalid smoothing factor %g", alpha)
+	}
+
+	avgSeries := Series{
+		Plots:   make([]Plot, len(series.Plots)),
+		Step:    series.Step,
+		Summary: make(map[string]Value),
+	}
+
+	last := Value(math.NaN())
+
+	for i := range series.Plots {
+		avgSeries.Plots[i].Time = series.Plots[i].Time
+
+		if series.Plots[i].Value.IsNaN() {
+			avgSeries.Plots[i].Value = Value(math.NaN())
+			continue
+		}
+
+		if last.IsNaN() {
+			last = series.Plots[i].Value
+		} else {
+			last = Value(alpha)*series.Plots[i].Value + Value(1-alpha)*last
+		}
+
+		avgSeries.Plots[i].Value = last
+	}
+
+	return avgSeries, nil
+}
+
+// HoltWinters returns the forecast series of a series using the triple exponential smoothing method, along with
+// its lower and upper confidence bands. The bands are delta times the smoothed deviation away from the forecast,
+// deviations being bootstrapped from the mean absolute residual while no previous season is available.
+func HoltWinters(series Series, alpha, beta, gamma float64, seasonDuration time.Duration, delta float64) (Series,
+	Series, Series, error) {
+
+	if alpha < 0 || alpha > 1 || beta < 0 || beta > 1 || gamma < 0 || gamma > 1 {
+		return Series{}, Series{}, Series{}, fmt.Errorf("smoothing factors must be between 0 and 1")
+	} else if seasonDuration < 0 {
+		return Series{}, Series{}, Series{}, fmt.Errorf("invalid season duration %s", seasonDuration)
+	}
+
+	// Get season length in number of plots
+	var season int
+
+	step := series.Step
+	if step == 0 {
+		step = series.guessStep()
+	}
+
+	if step > 0 {
+		season = int(seasonDuration.Seconds()) / step
+	}
+
+	plotsCount := len(series.Plots)
+
+	forecastSeries := Series{Plots: make([]Plot, plotsCount), Step: series.Step, Summary: make(map[string]Value)}
+	lowerSeries := Series{Plots: make([]Plot, plotsCount), Step: series.Step, Summary: make(map[string]Value)}
+	upperSeries := Series{Plots: make([]Plot, plotsCount), Step: series.Step, Summary: make(map[string]Value)}
+
+	intercepts := make([]float64, plotsCount)
+	slopes := make([]float64, plotsCount)
+	seasonals := make([]float64, plotsCount)
+	deviations := make([]float64, plotsCount)
+
+	// Retrieve previous season value, falling back on the previous plot when no season is set
+	lastSeasonal := func(values []float64, index int) float64 {
+		if season == 0 {
+			if index > 0 {
+				return values[index-1]
+			}
+		} else if index >= season {
+			return values[index-season]
+		}
+
+		return 0
+	}
+
+	lastIntercept := math.NaN()
+	lastSlope := 0.0
+
+	residualsSum := 0.0
+	residualsCount := 0
+
+	for i := range series.Plots {
+		// Use first season mean absolute residual as initial deviations for the next season
+		if i == season && residualsCount > 0 {
+			for j := 0; j < season; j++ {
+				deviations[j] = residualsSum / float64(residualsCount)
+			}
+		}
+
+		forecastSeries.Plots[i].Time = series.Plots[i].Time
+		lowerSeries.Plots[i].Time = series.Plots[i].Time
+		upperSeries.Plots[i].Time = series.Plots[i].Time
+
+		actual := float64(series.Plots[i].Value)
+
+		if math.IsNaN(actual) {
+			forecastSeries.Plots[i].Value = Value(math.NaN())
+			lowerSeries.Plots[i].Value = Value(math.NaN())
+			upperSeries.Plots[i].Value = Value(math.NaN())
+
+			intercepts[i] = lastIntercept
+			slopes[i] = lastSlope
+			seasonals[i] = lastSeasonal(seasonals, i)
+			deviations[i] = lastSeasonal(deviations, i)
+
+			continue
+		}
+
+		initial := math.IsNaN(lastIntercept)
+		if initial {
+			lastIntercept = actual
+		}
+
+		seasonal := 0.0
+		if season > 0 {
+			seasonal = lastSeasonal(seasonals, i)
+		}
+
+		prediction := lastIntercept + lastSlope + seasonal
+		residual := math.Abs(actual - prediction)
+
+		deviation := lastSeasonal(deviations, i)
+		if season > 0 && i < season && residualsCount > 0 {
+			deviation = residualsSum / float64(residualsCount)
+		}
+
+		intercepts[i] = alpha*(actual-seasonal) + (1-alpha)*(lastIntercept+lastSlope)
+		slopes[i] = beta*(intercepts[i]-lastIntercept) + (1-beta)*lastSlope
+		seasonals[i] = gamma*(actual-intercepts[i]) + (1-gamma)*seasonal
+		deviations[i] = gamma*residual + (1-gamma)*deviation
+
+		// Skip first plot residual, the prediction being initialized from the actual value
+		if season > 0 && i < season && !initial {
+			residualsSum += residual
+			residualsCount++
+		}
+
+		// Confidence bands rely on the predicted deviation, thus not taking into account the current plot
+		forecastSeries.Plots[i].Value = Value(prediction)
+		lowerSeries.Plots[i].Value = Value(prediction - delta*deviation)
+		upperSeries.Plots[i].Value = Value(prediction + delta*deviation)
+
+		lastIntercept = intercepts[i]
+		lastSlope = slopes[i]
+	}
+
+	return forecastSeries, lowerSeries, upperSeries, nil
+}
+
 func gcd(a, b int) int {
 	if a <= 0 || b <= 0 {
 		return 0
@@ -5,6 +5,7 @@ import (
 	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_FuncNormalizeSeries(test *testing.T) {
@@ -46,6 +47,122 @@ func Test_FuncNormalizeSeries(test *testing.T) {
 	}
 }
 
+func Test_FuncNormalizeSeriesGuessStep(test *testing.T) {
+	// Series without step information (e.g. coming from different connectors)
+	testSeries := []Series{
+		{Plots: []Plot{
+			{Value: 1, Time: time.Unix(60, 0)}, {Value: 3, Time: time.Unix(120, 0)},
+			{Value: 5, Time: time.Unix(180, 0)}, {Value: 7, Time: time.Unix(240, 0)},
+		}},
+		{Plots: []Plot{
+			{Value: 4, Time: time.Unix(120, 0)}, {Value: 8, Time: time.Unix(240, 0)},
+		}, Step: 120},
+	}
+
+	expectedSeries := []Series{
+		{Plots: []Plot{{Value: 2}, {Value: 6}}, Step: 120},
+		{Plots: []Plot{{Value: 4}, {Value: 8}}, Step: 120},
+	}
+
+	normalizedSeries, err := NormalizeSeries(testSeries, ConsolidateAverage)
+	if err != nil {
+		test.Logf("NormalizeSeries(testSeries) returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	for i := range expectedSeries {
+		if normalizedSeries[i].Step != expectedSeries[i].Step {
+			test.Logf("\nExpected step %d\nbut got  %d", expectedSeries[i].Step, normalizedSeries[i].Step)
+			test.Fail()
+		}
+
+		for j := range expectedSeries[i].Plots {
+			if normalizedSeries[i].Plots[j].Value != expectedSeries[i].Plots[j].Value {
+				test.Logf("\nExpected %v\nbut got  %v", expectedSeries[i].Plots, normalizedSeries[i].Plots)
+				test.Fail()
+				break
+			}
+		}
+	}
+
+	sumSeries, err := SumSeries(testSeries)
+	if err != nil {
+		test.Logf("SumSeries(testSeries) returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	for i, value := range []Value{6, 14} {
+		if sumSeries.Plots[i].Value != value {
+			test.Logf("\nExpected %v\nbut got  %v", value, sumSeries.Plots[i].Value)
+			test.Fail()
+		}
+	}
+}
+
+func Test_FuncAlignSeries(test *testing.T) {
+	nan := Value(math.NaN())
+
+	// Series shifted in time and empty series (e.g. coming from different connectors)
+	testSeries := []Series{
+		{Plots: []Plot{
+			{Value: 1, Time: time.Unix(60, 0)}, {Value: 2, Time: time.Unix(120, 0)},
+			{Value: 3, Time: time.Unix(180, 0)},
+		}, Step: 60},
+		{Plots: []Plot{
+			{Value: 10, Time: time.Unix(120, 0)}, {Value: 20, Time: time.Unix(180, 0)},
+			{Value: 30, Time: time.Unix(240, 0)},
+		}, Step: 60},
+		{},
+	}
+
+	expectedValues := [][]Value{
+		{1, 2, 3, nan},
+		{nan, 10, 20, 30},
+		{nan, nan, nan, nan},
+	}
+
+	alignedSeries, err := AlignSeries(testSeries)
+	if err != nil {
+		test.Logf("AlignSeries(testSeries) returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	for i := range expectedValues {
+		if len(alignedSeries[i].Plots) != len(expectedValues[i]) {
+			test.Logf("\nExpected %d plots\nbut got  %d", len(expectedValues[i]), len(alignedSeries[i].Plots))
+			test.Fail()
+			continue
+		}
+
+		for j, value := range expectedValues[i] {
+			plot := alignedSeries[i].Plots[j]
+
+			if plot.Time.Unix() != int64(60*(j+1)) || value.IsNaN() != plot.Value.IsNaN() ||
+				!value.IsNaN() && plot.Value != value {
+				test.Logf("\nExpected %v at %d\nbut got  %v at %d", value, 60*(j+1), plot.Value, plot.Time.Unix())
+				test.Fail()
+			}
+		}
+	}
+
+	sumSeries, err := SumSeries(alignedSeries)
+	if err != nil {
+		test.Logf("SumSeries(alignedSeries) returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	for i, value := range []Value{1, 12, 23, 30} {
+		if sumSeries.Plots[i].Value != value {
+			test.Logf("\nExpected %v\nbut got  %v", value, sumSeries.Plots[i].Value)
+			test.Fail()
+		}
+	}
+}
+
 func Test_FuncAvgSeries(test *testing.T) {
 	var (
 		// Valid series
@@ -251,3 +368,153 @@ func compareSeries(expected, actual Series) error {
 
 	return nil
 }
+
+func Test_FuncMovingAverage(test *testing.T) {
+	testSeries := Series{Plots: []Plot{
+		{Value: 1}, {Value: 2}, {Value: Value(math.NaN())}, {Value: 4},
+		{Value: 5}, {Value: Value(math.NaN())}, {Value: Value(math.NaN())}, {Value: Value(math.NaN())},
+	}}
+
+	expectedSeries := Series{Plots: []Plot{
+		{Value: 1}, {Value: 1.5}, {Value: 1.5}, {Value: 3},
+		{Value: 4.5}, {Value: 4.5}, {Value: 5}, {Value: Value(math.NaN())},
+	}}
+
+	avgSeries, err := MovingAverage(testSeries, 3)
+	if err != nil {
+		test.Logf("MovingAverage(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	if err = compareSeries(expectedSeries, avgSeries); err != nil {
+		test.Logf("MovingAverage(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	if _, err = MovingAverage(testSeries, 0); err == nil {
+		test.Logf("MovingAverage(testSeries, 0) should have returned an error")
+		test.Fail()
+	}
+}
+
+func Test_FuncExponentialMovingAverage(test *testing.T) {
+	testSeries := Series{Plots: []Plot{
+		{Value: Value(math.NaN())}, {Value: 2}, {Value: 4}, {Value: Value(math.NaN())}, {Value: 8},
+	}}
+
+	expectedSeries := Series{Plots: []Plot{
+		{Value: Value(math.NaN())}, {Value: 2}, {Value: 3}, {Value: Value(math.NaN())}, {Value: 5.5},
+	}}
+
+	avgSeries, err := ExponentialMovingAverage(testSeries, 0.5)
+	if err != nil {
+		test.Logf("ExponentialMovingAverage(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	if err = compareSeries(expectedSeries, avgSeries); err != nil {
+		test.Logf("ExponentialMovingAverage(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	if _, err = ExponentialMovingAverage(testSeries, 1.5); err == nil {
+		test.Logf("ExponentialMovingAverage(testSeries, 1.5) should have returned an error")
+		test.Fail()
+	}
+}
+
+func Test_FuncHoltWinters(test *testing.T) {
+	// Seasonal series having a 4 plots period
+	testSeries := Series{Step: 60}
+
+	for i := 0; i < 100; i++ {
+		testSeries.Plots = append(testSeries.Plots, Plot{
+			Value: []Value{10, 20, 10, 0}[i%4],
+			Time:  time.Unix(int64(i*60), 0),
+		})
+	}
+
+	forecastSeries, lowerSeries, upperSeries, err := HoltWinters(testSeries, 0.5, 0.1, 0.5, 4*time.Minute, 3)
+	if err != nil {
+		test.Logf("HoltWinters(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	// Forecast should converge to the actual values, within the confidence bands
+	for i := 80; i < 100; i++ {
+		actual := testSeries.Plots[i].Value
+
+		if math.Abs(float64(forecastSeries.Plots[i].Value-actual)) > 0.5 {
+			test.Logf("\nExpected %v\nbut got  %v", actual, forecastSeries.Plots[i].Value)
+			test.Fail()
+			return
+		} else if lowerSeries.Plots[i].Value > actual || upperSeries.Plots[i].Value < actual {
+			test.Logf("%v is out of [%v, %v] bands", actual, lowerSeries.Plots[i].Value, upperSeries.Plots[i].Value)
+			test.Fail()
+			return
+		} else if forecastSeries.Plots[i].Time != testSeries.Plots[i].Time {
+			test.Logf("\nExpected %v\nbut got  %v", testSeries.Plots[i].Time, forecastSeries.Plots[i].Time)
+			test.Fail()
+			return
+		}
+	}
+
+	// Anomalies should get out of the confidence bands
+	testSeries.Plots[99].Value = 50
+
+	_, _, upperSeries, _ = HoltWinters(testSeries, 0.5, 0.1, 0.5, 4*time.Minute, 3)
+	if upperSeries.Plots[99].Value > 50 {
+		test.Logf("%v should be above upper band %v", Value(50), upperSeries.Plots[99].Value)
+		test.Fail()
+	}
+
+	if _, _, _, err = HoltWinters(testSeries, 2, 0.1, 0.5, 0, 3); err == nil {
+		test.Logf("HoltWinters(testSeries, 2, ...) should have returned an error")
+		test.Fail()
+	}
+}
+
+func Test_FuncHoltWintersFirstSeason(test *testing.T) {
+	// One hour series with the default one day season, thus having no previous season to rely on
+	testSeries := Series{Step: 60}
+
+	for i := 0; i < 60; i++ {
+		testSeries.Plots = append(testSeries.Plots, Plot{
+			Value: Value(10 + i%5),
+			Time:  time.Unix(int64(i*60), 0),
+		})
+	}
+
+	forecastSeries, lowerSeries, upperSeries, err := HoltWinters(testSeries, 0.1, 0.0035, 0.1, 24*time.Hour, 3)
+	if err != nil {
+		test.Logf("HoltWinters(testSeries): %s", err)
+		test.Fail()
+		return
+	}
+
+	// Confidence bands should be bootstrapped from the residuals once a first residual is available
+	for i := 2; i < 60; i++ {
+		if !(lowerSeries.Plots[i].Value < forecastSeries.Plots[i].Value) ||
+			!(upperSeries.Plots[i].Value > forecastSeries.Plots[i].Value) {
+			test.Logf("\nExpected non-degenerate bands at plot %d\nbut got  [%v, %v] around %v", i,
+				lowerSeries.Plots[i].Value, upperSeries.Plots[i].Value, forecastSeries.Plots[i].Value)
+			test.Fail()
+			return
+		}
+	}
+
+	for i := 50; i < 60; i++ {
+		actual := testSeries.Plots[i].Value
+
+		if lowerSeries.Plots[i].Value > actual || upperSeries.Plots[i].Value < actual {
+			test.Logf("%v is out of [%v, %v] bands", actual, lowerSeries.Plots[i].Value, upperSeries.Plots[i].Value)
+			test.Fail()
+			return
+		}
+	}
+}
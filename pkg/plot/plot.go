@@ -23,14 +23,22 @@ func (plot Plot) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON handles JSON marshalling of the Plot type.
 func (plot *Plot) UnmarshalJSON(data []byte) error {
-	var input [2]float64
+	var input [2]*float64
 
 	if err := json.Unmarshal(data, &input); err != nil {
 		return err
+	} else if input[0] == nil {
+		return fmt.Errorf("missing plot time")
 	}
 
-	plot.Time = time.Unix(int64(input[0]), 0)
-	plot.Value = Value(input[1])
+	plot.Time = time.Unix(int64(*input[0]), 0)
+
+	// Handle null values unmarshalling, NaN values being marshalled as null
+	if input[1] == nil {
+		plot.Value = Value(math.NaN())
+	} else {
+		plot.Value = Value(*input[1])
+	}
 
 	return nil
 }
@@ -50,6 +58,24 @@ func (value Value) MarshalJSON() ([]byte, error) {
 	return json.Marshal(float64(value))
 }
 
+// UnmarshalJSON handles JSON unmarshalling of the Value type.
+func (value *Value) UnmarshalJSON(data []byte) error {
+	var input *float64
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+
+	// Handle null values unmarshalling, NaN values being marshalled as null
+	if input == nil {
+		*value = Value(math.NaN())
+	} else {
+		*value = Value(*input)
+	}
+
+	return nil
+}
+
 // IsNaN reports whether the Value is an IEEE 754 “not-a-number” value.
 func (value Value) IsNaN() bool {
 	return math.IsNaN(float64(value))
@@ -98,13 +124,15 @@ func (queryGroup *QueryGroup) String() string {
 
 // QuerySeries represents a series entry in a QueryGroup.
 type QuerySeries struct {
+	Name    string
 	Metric  *QueryMetric
 	Options map[string]interface{}
 }
 
 func (QuerySeries *QuerySeries) String() string {
 	return fmt.Sprintf(
-		"QuerySeries{Metric:%s Options:%v}",
+		"QuerySeries{Name:\"%s\" Metric:%s Options:%v}",
+		QuerySeries.Name,
 		QuerySeries.Metric,
 		QuerySeries.Options,
 	)
@@ -136,6 +164,98 @@ type Series struct {
 
 // Consolidate consolidates a series of plots given a certain number of values per point.
 func (series *Series) Consolidate(pad, consolidationType int) {
+	series.consolidate(pad, consolidationType, 0)
+}
+
+// ConsolidatePercentile consolidates a series of plots given a certain number of values per point, using the
+// percentile value of each bucket.
+func (series *Series) ConsolidatePercentile(pad int, percentile float64) {
+	series.consolidate(pad, ConsolidatePercentile, percentile)
+}
+
+// Downsample applies a sampling function on a series of plots, reducing the number of points.
+func (series *Series) Downsample(sample, consolidationType int) {
+	series.Consolidate(len(series.Plots)/sample, consolidationType)
+}
+
+// DownsamplePercentile applies a percentile sampling function on a series of plots, reducing the number of points.
+func (series *Series) DownsamplePercentile(sample int, percentile float64) {
+	series.ConsolidatePercentile(len(series.Plots)/sample, percentile)
+}
+
+// DownsampleLTTB applies the Largest-Triangle-Three-Buckets algorithm on a series of plots, reducing the number of
+// points while preserving its visual shape.
+func (series *Series) DownsampleLTTB(sample int) {
+	plotsCount := len(series.Plots)
+
+	if sample < 3 || sample >= plotsCount {
+		return
+	}
+
+	plots := series.Plots[:]
+	series.Plots = make([]Plot, 0, sample)
+
+	// Always keep first and last plots, then pick one plot per bucket forming the largest triangle with the
+	// previously selected plot and the average of the next bucket
+	series.Plots = append(series.Plots, plots[0])
+
+	every := float64(plotsCount-2) / float64(sample-2)
+	last := 0
+
+	for i := 0; i < sample-2; i++ {
+		var (
+			avgX, avgY  float64
+			validPlots  int
+			maxArea     = -1.0
+			selected    = -1
+			rangeStart  = int(float64(i)*every) + 1
+			rangeEnd    = int(float64(i+1)*every) + 1
+			avgRangeEnd = int(float64(i+2)*every) + 1
+		)
+
+		if avgRangeEnd > plotsCount {
+			avgRangeEnd = plotsCount
+		}
+
+		for j := rangeEnd; j < avgRangeEnd; j++ {
+			if !plots[j].Value.IsNaN() {
+				avgX += float64(j)
+				avgY += float64(plots[j].Value)
+				validPlots++
+			}
+		}
+
+		if validPlots > 0 {
+			avgX /= float64(validPlots)
+			avgY /= float64(validPlots)
+		} else {
+			avgX, avgY = float64(rangeEnd), math.NaN()
+		}
+
+		lastX, lastY := float64(last), float64(plots[last].Value)
+
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((lastX-avgX)*(float64(plots[j].Value)-lastY)-(lastX-float64(j))*(avgY-lastY)) / 2
+
+			if area > maxArea {
+				maxArea = area
+				selected = j
+			}
+		}
+
+		// Fall back on the bucket's first plot if no area can be computed (e.g. missing values)
+		if selected == -1 {
+			selected = rangeStart
+		}
+
+		series.Plots = append(series.Plots, plots[selected])
+		last = selected
+	}
+
+	series.Plots = append(series.Plots, plots[plotsCount-1])
+}
+
+func (series *Series) consolidate(pad, consolidationType int, percentile float64) {
 	if pad < 2 {
 		return
 	}
@@ -164,7 +284,7 @@ func (series *Series) Consolidate(pad, consolidationType int) {
 
 			series.Plots = append(
 				series.Plots,
-				Plot{Value: consolidateBucket(bucket, consolidationType), Time: plots[i].Time},
+				Plot{Value: consolidateBucket(bucket, consolidationType, percentile), Time: plots[i].Time},
 			)
 
 			bucket = make([]float64, 0)
@@ -174,16 +294,11 @@ func (series *Series) Consolidate(pad, consolidationType int) {
 	if len(bucket) > 0 {
 		series.Plots = append(
 			series.Plots,
-			Plot{Value: consolidateBucket(bucket, consolidationType), Time: plots[plotsCount-1].Time},
+			Plot{Value: consolidateBucket(bucket, consolidationType, percentile), Time: plots[plotsCount-1].Time},
 		)
 	}
 }
 
-// Downsample applies a sampling function on a series of plots, reducing the number of points.
-func (series *Series) Downsample(sample, consolidationType int) {
-	series.Consolidate(len(series.Plots)/sample, consolidationType)
-}
-
 // Scale applies a factor on a series of plots.
 func (series *Series) Scale(factor Value) {
 	for i := range series.Plots {
@@ -193,6 +308,56 @@ func (series *Series) Scale(factor Value) {
 	}
 }
 
+// Transform applies a transform function on a series of plots, replacing each value by its variation from the
+// previous one. First plot value is set to NaN as it has no predecessor.
+func (series *Series) Transform(transformType int) {
+	series.TransformCounter(transformType, 0)
+}
+
+// TransformCounter applies a transform function on a series of plots, handling counter wraps according to the
+// counter maximal value. Negative non-negative derivative values are set to NaN if no maximal value is given, as
+// they most likely come from counter resets.
+func (series *Series) TransformCounter(transformType int, maxValue Value) {
+	plotsCount := len(series.Plots)
+	if plotsCount == 0 {
+		return
+	}
+
+	previous := series.Plots[0]
+	series.Plots[0].Value = Value(math.NaN())
+
+	for i := 1; i < plotsCount; i++ {
+		current := series.Plots[i]
+		delta := current.Value - previous.Value
+
+		switch transformType {
+		case TransformPerSecond:
+			interval := current.Time.Sub(previous.Time).Seconds()
+			if interval <= 0 {
+				interval = float64(series.Step)
+			}
+
+			if interval > 0 {
+				delta /= Value(interval)
+			} else {
+				delta = Value(math.NaN())
+			}
+
+		case TransformNonNegativeDerivative:
+			if delta >= 0 {
+				break
+			} else if maxValue > 0 && current.Value <= maxValue {
+				delta = maxValue - previous.Value + current.Value + 1
+			} else {
+				delta = Value(math.NaN())
+			}
+		}
+
+		series.Plots[i].Value = delta
+		previous = current
+	}
+}
+
 // Summarize calculates the min/max/average/last and percentile values of a series of plots, and stores the results
 // into the Summary map.
 func (series *Series) Summarize(percentiles []float64) {
@@ -245,33 +410,30 @@ func (series *Series) Percentiles(percentiles []float64) {
 		return
 	}
 
-	setSize := len(set)
-	if setSize == 0 {
+	if len(set) == 0 {
 		return
 	}
 
 	sort.Float64s(set)
 
 	for _, percentile := range percentiles {
-		percentileString := fmt.Sprintf("%gth", percentile)
-
-		rank := (percentile / 100) * float64(setSize+1)
-		rankInt := int(rank)
-		rankFrac := rank - float64(rankInt)
-
-		if rank <= 0.0 {
-			series.Summary[percentileString] = Value(set[0])
-			continue
-		} else if rank-1.0 >= float64(setSize) {
-			series.Summary[percentileString] = Value(set[setSize-1])
-			continue
-		}
+		series.Summary[fmt.Sprintf("%gth", percentile)] = percentileValue(set, percentile)
+	}
+}
 
-		series.Summary[percentileString] = Value(set[rankInt-1] + rankFrac*(set[rankInt]-set[rankInt-1]))
+func (series *Series) guessStep() int {
+	if len(series.Plots) < 2 {
+		return 0
 	}
+
+	if step := int(series.Plots[1].Time.Sub(series.Plots[0].Time).Seconds()); step > 0 {
+		return step
+	}
+
+	return 0
 }
 
-func consolidateBucket(bucket []float64, consolidationType int) Value {
+func consolidateBucket(bucket []float64, consolidationType int, percentile float64) Value {
 	switch consolidationType {
 	case ConsolidateAverage, ConsolidateSum:
 		sum := 0.0
@@ -302,7 +464,44 @@ func consolidateBucket(bucket []float64, consolidationType int) Value {
 		}
 
 		return Value(min)
+
+	case ConsolidateMedian, ConsolidatePercentile:
+		set := make([]float64, len(bucket))
+		copy(set, bucket)
+		sort.Float64s(set)
+
+		if consolidationType == ConsolidateMedian {
+			return percentileValue(set, 50)
+		}
+
+		return percentileValue(set, percentile)
+
+	case ConsolidateFirst:
+		return Value(bucket[0])
+
+	case ConsolidateLast:
+		return Value(bucket[len(bucket)-1])
+
+	case ConsolidateCount:
+		return Value(len(bucket))
 	}
 
 	return Value(math.NaN())
 }
+
+// percentileValue returns the percentile value of a sorted set, interpolating between closest ranks.
+func percentileValue(set []float64, percentile float64) Value {
+	setSize := len(set)
+
+	rank := (percentile / 100) * float64(setSize+1)
+	rankInt := int(rank)
+	rankFrac := rank - float64(rankInt)
+
+	if rank <= 1.0 {
+		return Value(set[0])
+	} else if rank >= float64(setSize) {
+		return Value(set[setSize-1])
+	}
+
+	return Value(set[rankInt-1] + rankFrac*(set[rankInt]-set[rankInt-1]))
+}
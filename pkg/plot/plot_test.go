@@ -1,9 +1,11 @@
 package plot
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/facette/facette/pkg/utils"
 )
@@ -202,3 +204,241 @@ func Test_SeriesSummarize(test *testing.T) {
 		test.Fail()
 	}
 }
+
+func Test_SeriesTransform(test *testing.T) {
+	testSeries := Series{
+		Plots: []Plot{
+			{Value: 10, Time: time.Unix(0, 0)}, {Value: 30, Time: time.Unix(10, 0)},
+			{Value: Value(math.NaN()), Time: time.Unix(20, 0)}, {Value: 90, Time: time.Unix(30, 0)},
+			{Value: 50, Time: time.Unix(50, 0)}, {Value: 70, Time: time.Unix(60, 0)},
+		},
+	}
+
+	for _, entry := range []struct {
+		Type   int
+		Values []Value
+	}{
+		{TransformDerivative, []Value{Value(math.NaN()), 20, Value(math.NaN()), Value(math.NaN()), -40, 20}},
+		{TransformPerSecond, []Value{Value(math.NaN()), 2, Value(math.NaN()), Value(math.NaN()), -2, 2}},
+		{TransformNonNegativeDerivative, []Value{Value(math.NaN()), 20, Value(math.NaN()), Value(math.NaN()),
+			Value(math.NaN()), 20}},
+	} {
+		series := Series{}
+		utils.Clone(&testSeries, &series)
+
+		expectedSeries := Series{Plots: make([]Plot, len(entry.Values))}
+		for i := range entry.Values {
+			expectedSeries.Plots[i] = Plot{Value: entry.Values[i], Time: testSeries.Plots[i].Time}
+		}
+
+		series.Transform(entry.Type)
+		if err := compareSeries(expectedSeries, series); err != nil {
+			test.Logf("Transform(%d): %s", entry.Type, err)
+			test.Fail()
+		}
+	}
+
+	// 32-bit counter wrap
+	series := Series{Plots: []Plot{{Value: math.MaxUint32 - 4095}, {Value: 4096}}}
+
+	series.TransformCounter(TransformNonNegativeDerivative, math.MaxUint32)
+	if series.Plots[1].Value != 8192 {
+		test.Logf("\nExpected %#v\nbut got  %#v", Value(8192), series.Plots[1].Value)
+		test.Fail()
+	}
+
+	// Counter value beyond maximal value
+	series = Series{Plots: []Plot{{Value: 5000}, {Value: 2000}}}
+
+	series.TransformCounter(TransformNonNegativeDerivative, 1000)
+	if !series.Plots[1].Value.IsNaN() {
+		test.Logf("\nExpected %#v\nbut got  %#v", Value(math.NaN()), series.Plots[1].Value)
+		test.Fail()
+	}
+
+	if _, err := ParseTransform("unknown"); err == nil {
+		test.Logf("ParseTransform(\"unknown\") should have returned an error")
+		test.Fail()
+	}
+}
+
+func Test_SeriesConsolidate(test *testing.T) {
+	testSeries := Series{Plots: []Plot{
+		{Value: 4}, {Value: 1}, {Value: Value(math.NaN())}, {Value: 3},
+		{Value: 8}, {Value: 2}, {Value: 6}, {Value: 5},
+		{Value: Value(math.NaN())}, {Value: 7},
+	}}
+
+	for _, entry := range []struct {
+		Name   string
+		Values []Value
+	}{
+		{"median", []Value{3, 5.5, 7}},
+		{"first", []Value{4, 8, 7}},
+		{"last", []Value{3, 5, 7}},
+		{"count", []Value{3, 4, 1}},
+		{"p50", []Value{3, 5.5, 7}},
+		{"p99", []Value{4, 8, 7}},
+		{"p25", []Value{1, 2.75, 7}},
+	} {
+		consolidationType, percentile, err := ParseConsolidation(entry.Name)
+		if err != nil {
+			test.Logf("ParseConsolidation(%q): %s", entry.Name, err)
+			test.Fail()
+			continue
+		}
+
+		series := Series{}
+		utils.Clone(&testSeries, &series)
+
+		if consolidationType == ConsolidatePercentile {
+			series.ConsolidatePercentile(4, percentile)
+		} else {
+			series.Consolidate(4, consolidationType)
+		}
+
+		expectedSeries := Series{}
+		for _, value := range entry.Values {
+			expectedSeries.Plots = append(expectedSeries.Plots, Plot{Value: value})
+		}
+
+		if len(series.Plots) != len(expectedSeries.Plots) {
+			test.Logf("\nExpected %v\nbut got  %v", expectedSeries.Plots, series.Plots)
+			test.Fail()
+		} else if err := compareSeries(expectedSeries, series); err != nil {
+			test.Logf("Consolidate(%q): %s", entry.Name, err)
+			test.Fail()
+		}
+	}
+
+	for _, name := range []string{"unknown", "p0", "p101", "pfoo"} {
+		if _, _, err := ParseConsolidation(name); err == nil {
+			test.Logf("ParseConsolidation(%q) should have returned an error", name)
+			test.Fail()
+		}
+	}
+}
+
+func Test_SeriesDownsampleLTTB(test *testing.T) {
+	testSeries := Series{}
+
+	for i := 0; i < 1000; i++ {
+		testSeries.Plots = append(testSeries.Plots, Plot{
+			Value: Value(math.Sin(float64(i) / 50)),
+			Time:  time.Unix(int64(i*10), 0),
+		})
+	}
+
+	// Add isolated peaks and a gap
+	testSeries.Plots[123].Value = 100
+	testSeries.Plots[456].Value = -100
+	testSeries.Plots[789].Value = 42
+
+	for i := 600; i < 650; i++ {
+		testSeries.Plots[i].Value = Value(math.NaN())
+	}
+
+	series := Series{}
+	utils.Clone(&testSeries, &series)
+
+	series.DownsampleLTTB(50)
+
+	if len(series.Plots) != 50 {
+		test.Logf("\nExpected %d\nbut got  %d", 50, len(series.Plots))
+		test.Fail()
+		return
+	}
+
+	if series.Plots[0] != testSeries.Plots[0] || series.Plots[49] != testSeries.Plots[999] {
+		test.Logf("first and last plots should have been preserved")
+		test.Fail()
+	}
+
+	for _, index := range []int{123, 456, 789} {
+		found := false
+
+		for _, entry := range series.Plots {
+			if entry == testSeries.Plots[index] {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			test.Logf("extreme plot %v should have been preserved", testSeries.Plots[index])
+			test.Fail()
+		}
+	}
+
+	for i := 1; i < len(series.Plots); i++ {
+		if !series.Plots[i].Time.After(series.Plots[i-1].Time) {
+			test.Logf("plots should be ordered by time")
+			test.Fail()
+			break
+		}
+	}
+
+	// Fixed-pad bucketing averages peaks away
+	utils.Clone(&testSeries, &series)
+
+	series.Downsample(50, ConsolidateAverage)
+
+	for _, entry := range series.Plots {
+		if entry.Value == 100 {
+			test.Logf("peak should not have been preserved using average consolidation")
+			test.Fail()
+		}
+	}
+}
+
+func Test_PlotJSON(test *testing.T) {
+	for _, entry := range []struct {
+		Input  string
+		Result Plot
+	}{
+		{`[60,1.5]`, Plot{Time: time.Unix(60, 0), Value: 1.5}},
+		{`[120,null]`, Plot{Time: time.Unix(120, 0), Value: Value(math.NaN())}},
+	} {
+		var result Plot
+
+		if err := json.Unmarshal([]byte(entry.Input), &result); err != nil {
+			test.Fatalf("unable to unmarshal plot: %s", err)
+		}
+
+		if !result.Time.Equal(entry.Result.Time) || result.Value != entry.Result.Value &&
+			!(result.Value.IsNaN() && entry.Result.Value.IsNaN()) {
+			test.Logf("\nExpected %v\nbut got  %v", entry.Result, result)
+			test.Fail()
+		}
+
+		// Check for marshalling round trip
+		if data, _ := json.Marshal(result); string(data) != entry.Input {
+			test.Logf("\nExpected %s\nbut got  %s", entry.Input, data)
+			test.Fail()
+		}
+	}
+
+	var result Plot
+	if err := json.Unmarshal([]byte(`[null,1]`), &result); err == nil {
+		test.Logf("\nExpected error for missing plot time")
+		test.Fail()
+	}
+}
+
+func Test_ValueJSON(test *testing.T) {
+	var result map[string]Value
+
+	if err := json.Unmarshal([]byte(`{"avg":0.5,"last":null}`), &result); err != nil {
+		test.Fatalf("unable to unmarshal values: %s", err)
+	}
+
+	if result["avg"] != 0.5 {
+		test.Logf("\nExpected %v\nbut got  %v", 0.5, result["avg"])
+		test.Fail()
+	}
+
+	if !result["last"].IsNaN() {
+		test.Logf("\nExpected %v\nbut got  %v", math.NaN(), result["last"])
+		test.Fail()
+	}
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+type pngCanvas struct {
+	image *image.RGBA
+}
+
+func newPNGCanvas(width, height int) *pngCanvas {
+	return &pngCanvas{image: image.NewRGBA(image.Rect(0, 0, width, height))}
+}
+
+func (c *pngCanvas) fillRect(x, y, width, height float64, fill color.RGBA) {
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	x1, y1 := int(math.Ceil(x+width)), int(math.Ceil(y+height))
+
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			c.blend(px, py, fill)
+		}
+	}
+}
+
+func (c *pngCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	if len(points) == 0 {
+		return
+	}
+
+	size := int(math.Max(1, math.Floor(width+0.5)))
+
+	// Keep track of painted pixels to prevent alpha blending from stacking up on overlapping brush strokes
+	painted := make(map[image.Point]struct{})
+
+	brush := func(x, y int) {
+		for dy := 0; dy < size; dy++ {
+			for dx := 0; dx < size; dx++ {
+				p := image.Point{x + dx - size/2, y + dy - size/2}
+				if _, ok := painted[p]; ok {
+					continue
+				}
+
+				painted[p] = struct{}{}
+				c.blend(p.X, p.Y, stroke)
+			}
+		}
+	}
+
+	if len(points) == 1 {
+		brush(int(math.Floor(points[0].x+0.5)), int(math.Floor(points[0].y+0.5)))
+		return
+	}
+
+	for i := 1; i < len(points); i++ {
+		x0, y0 := int(math.Floor(points[i-1].x+0.5)), int(math.Floor(points[i-1].y+0.5))
+		x1, y1 := int(math.Floor(points[i].x+0.5)), int(math.Floor(points[i].y+0.5))
+
+		dx, dy := abs(x1-x0), -abs(y1-y0)
+		sx, sy := sign(x1-x0), sign(y1-y0)
+		err := dx + dy
+
+		for {
+			brush(x0, y0)
+
+			if x0 == x1 && y0 == y1 {
+				break
+			}
+
+			if e2 := 2 * err; e2 >= dy {
+				err += dy
+				x0 += sx
+			} else if e2 <= dx {
+				err += dx
+				y0 += sy
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) polygon(points []point, fill color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+
+	minY, maxY := points[0].y, points[0].y
+	for _, p := range points[1:] {
+		minY = math.Min(minY, p.y)
+		maxY = math.Max(maxY, p.y)
+	}
+
+	// Fill polygon using scanlines sampled at pixels centers, following the even-odd rule
+	for py := int(math.Floor(minY)); py <= int(math.Ceil(maxY)); py++ {
+		y := float64(py) + 0.5
+		nodes := []float64{}
+
+		for i := range points {
+			a, b := points[i], points[(i+1)%len(points)]
+			if a.y < y && b.y >= y || b.y < y && a.y >= y {
+				nodes = append(nodes, a.x+(y-a.y)/(b.y-a.y)*(b.x-a.x))
+			}
+		}
+
+		sort.Float64s(nodes)
+
+		for i := 0; i+1 < len(nodes); i += 2 {
+			for px := int(math.Ceil(nodes[i] - 0.5)); float64(px)+0.5 <= nodes[i+1]; px++ {
+				c.blend(px, py, fill)
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) text(x, y float64, text string, fill color.RGBA, scale, anchor int, vertical bool) {
+	width := textWidth(text, scale)
+
+	offset := 0.0
+	switch anchor {
+	case anchorMiddle:
+		offset = width / 2
+	case anchorEnd:
+		offset = width
+	}
+
+	x0, y0 := int(math.Floor(x+0.5)), int(math.Floor(y+0.5))
+	if vertical {
+		y0 = int(math.Floor(y + offset + 0.5))
+	} else {
+		x0 = int(math.Floor(x - offset + 0.5))
+	}
+
+	advance := (fontGlyphWidth + fontGlyphSpacing) * scale
+
+	for i, char := range []rune(text) {
+		glyph := fontGlyph(char)
+
+		for col := 0; col < fontGlyphWidth; col++ {
+			for row := 0; row < fontGlyphHeight; row++ {
+				if glyph[col]&(1<<uint(row)) == 0 {
+					continue
+				}
+
+				// Vertical text is read from bottom to top: glyph columns go up and rows go right
+				var px, py int
+				if vertical {
+					px, py = x0+row*scale, y0-i*advance-(col+1)*scale
+				} else {
+					px, py = x0+i*advance+col*scale, y0+row*scale
+				}
+
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						c.blend(px+dx, py+dy, fill)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) write(writer io.Writer) error {
+	return png.Encode(writer, c.image)
+}
+
+func (c *pngCanvas) blend(x, y int, fill color.RGBA) {
+	if !(image.Point{x, y}).In(c.image.Rect) {
+		return
+	}
+
+	if fill.A == 0xff {
+		c.image.SetRGBA(x, y, fill)
+		return
+	}
+
+	// Blend non-premultiplied color over the existing pixel
+	dst := c.image.RGBAAt(x, y)
+	alpha := uint32(fill.A)
+
+	mix := func(src, dst uint8) uint8 {
+		return uint8((uint32(src)*alpha + uint32(dst)*(0xff-alpha)) / 0xff)
+	}
+
+	c.image.SetRGBA(x, y, color.RGBA{
+		R: mix(fill.R, dst.R),
+		G: mix(fill.G, dst.G),
+		B: mix(fill.B, dst.B),
+		A: uint8(alpha + uint32(dst.A)*(0xff-alpha)/0xff),
+	})
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+
+	return 0
+}
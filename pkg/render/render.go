@@ -0,0 +1,627 @@
+// Package render implements the server-side rendering of graphs into images.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/plot"
+)
+
+const (
+	// FormatPNG represents the PNG image rendering format.
+	FormatPNG = "png"
+	// FormatSVG represents the SVG image rendering format.
+	FormatSVG = "svg"
+)
+
+const (
+	// DefaultWidth represents the default rendered image width.
+	DefaultWidth = 800
+	// DefaultHeight represents the default rendered image height.
+	DefaultHeight = 300
+
+	minWidth  = 200
+	minHeight = 150
+	maxWidth  = 4096
+	maxHeight = 4096
+)
+
+const (
+	anchorStart = iota
+	anchorMiddle
+	anchorEnd
+)
+
+const (
+	padding         = 10
+	tickLength      = 4
+	legendBoxSize   = 8
+	legendSpacing   = 16
+	lineWidth       = 1.5
+	areaOpacity     = 0.75
+	textScaleNormal = 1
+	textScaleTitle  = 2
+)
+
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorGrid       = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+	colorAxis       = color.RGBA{0xc0, 0xd0, 0xe0, 0xff}
+	colorText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	colorSubText    = color.RGBA{0x66, 0x66, 0x66, 0xff}
+
+	// Default series colors, matching the ones of the web interface
+	seriesColors = []string{
+		"#2f7ed8", "#0d233a", "#8bbc21", "#910000", "#1aadce",
+		"#492970", "#f28f43", "#77a1e5", "#c42525", "#a6c96a",
+	}
+
+	timeSteps = []time.Duration{
+		time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+		24 * time.Hour, 2 * 24 * time.Hour, 7 * 24 * time.Hour, 14 * 24 * time.Hour, 30 * 24 * time.Hour,
+	}
+)
+
+// Graph represents a graph to be rendered.
+type Graph struct {
+	Title      string
+	Type       int
+	StackMode  int
+	UnitType   int
+	UnitLegend string
+	StartTime  time.Time
+	EndTime    time.Time
+	Series     []*Series
+}
+
+// Series represents a graph series to be rendered.
+type Series struct {
+	Name     string
+	StackID  int
+	Color    string
+	Constant bool
+	Plots    []plot.Plot
+}
+
+type point struct {
+	x, y float64
+}
+
+// canvas represents the drawing primitives an image format backend has to implement.
+type canvas interface {
+	fillRect(x, y, width, height float64, fill color.RGBA)
+	polyline(points []point, stroke color.RGBA, width float64)
+	polygon(points []point, fill color.RGBA)
+	text(x, y float64, text string, fill color.RGBA, scale, anchor int, vertical bool)
+	write(writer io.Writer) error
+}
+
+// renderSeries represents a series along with its computed lower and upper values, taking stacking into account.
+type renderSeries struct {
+	*Series
+	color        color.RGBA
+	lower, upper []float64
+}
+
+// Render draws a graph into an image of a given format and size.
+func Render(writer io.Writer, graph *Graph, format string, width, height int) error {
+	var c canvas
+
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	if height <= 0 {
+		height = DefaultHeight
+	}
+
+	if width < minWidth || width > maxWidth || height < minHeight || height > maxHeight {
+		return fmt.Errorf("image size must be between %dx%d and %dx%d", minWidth, minHeight, maxWidth, maxHeight)
+	}
+
+	switch format {
+	case FormatPNG:
+		c = newPNGCanvas(width, height)
+
+	case FormatSVG:
+		c = newSVGCanvas(width, height)
+
+	default:
+		return fmt.Errorf("unsupported format `%s'", format)
+	}
+
+	draw(c, graph, float64(width), float64(height))
+
+	return c.write(writer)
+}
+
+// FormatValue formats a value given a graph unit type, in the same fashion as the web interface does.
+func FormatValue(value float64, unitType int) string {
+	switch unitType {
+	case library.GraphUnitTypeMetric:
+		if value == 0 {
+			return "0"
+		}
+
+		units := []string{"", "k", "M", "G", "T", "P", "E", "Z", "Y"}
+
+		index := int(math.Log(math.Abs(value)) / math.Log(1000))
+		if index < 0 {
+			index = 0
+		} else if index >= len(units) {
+			index = len(units) - 1
+		}
+
+		return strings.TrimSpace(strconv.FormatFloat(math.Round(value/math.Pow(1000, float64(index))*100)/100,
+			'f', -1, 64) + " " + units[index])
+
+	case library.GraphUnitTypeFixed:
+		return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	}
+
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+func draw(c canvas, graph *Graph, width, height float64) {
+	c.fillRect(0, 0, width, height, colorBackground)
+
+	top := float64(padding)
+
+	// Draw title and time range
+	if graph.Title != "" {
+		c.text(width/2, top, graph.Title, colorText, textScaleTitle, anchorMiddle, false)
+		top += textHeight(textScaleTitle) + padding/2
+	}
+
+	if !graph.StartTime.IsZero() && !graph.EndTime.IsZero() {
+		c.text(width/2, top, fmt.Sprintf("%s - %s", graph.StartTime.Format("Jan 2, 2006 15:04"),
+			graph.EndTime.Format("Jan 2, 2006 15:04")), colorSubText, textScaleNormal, anchorMiddle, false)
+		top += textHeight(textScaleNormal) + padding
+	}
+
+	series := stackSeries(graph)
+
+	// Compute legend height
+	legendLines := layoutLegend(series, width-2*padding)
+	bottom := height - padding - float64(len(legendLines))*legendSpacing
+
+	if len(series) == 0 {
+		c.text(width/2, (top+bottom)/2, "No data", colorSubText, textScaleTitle, anchorMiddle, false)
+		return
+	}
+
+	// Compute values and time boundaries
+	minValue, maxValue := valueBounds(graph, series)
+	startTime, endTime := timeBounds(graph, series)
+
+	yTicks := valueTicks(minValue, maxValue)
+	if len(yTicks) == 0 {
+		c.text(width/2, (top+bottom)/2, "No data", colorSubText, textScaleTitle, anchorMiddle, false)
+		return
+	}
+
+	minValue, maxValue = math.Min(minValue, yTicks[0]), math.Max(maxValue, yTicks[len(yTicks)-1])
+
+	// Compute plot area
+	left := float64(padding)
+	if graph.UnitLegend != "" {
+		left += textHeight(textScaleNormal) + padding
+	}
+
+	labelWidth := 0.0
+	for _, tick := range yTicks {
+		labelWidth = math.Max(labelWidth, textWidth(formatTick(tick, graph), textScaleNormal))
+	}
+
+	left += labelWidth + tickLength + padding/2
+	right := width - padding*2
+	bottom -= textHeight(textScaleNormal) + tickLength + padding
+
+	if right-left < 10 || bottom-top < 10 {
+		return
+	}
+
+	mapX := func(t time.Time) float64 {
+		if !endTime.After(startTime) {
+			return left
+		}
+
+		return left + float64(t.Sub(startTime))/float64(endTime.Sub(startTime))*(right-left)
+	}
+
+	mapY := func(value float64) float64 {
+		if maxValue == minValue {
+			return bottom
+		}
+
+		return bottom - (value-minValue)/(maxValue-minValue)*(bottom-top)
+	}
+
+	// Draw value grid and labels
+	for _, tick := range yTicks {
+		y := math.Floor(mapY(tick)) + 0.5
+
+		c.polyline([]point{{left, y}, {right, y}}, colorGrid, 1)
+		c.text(left-tickLength-padding/2, y-textHeight(textScaleNormal)/2, formatTick(tick, graph), colorSubText,
+			textScaleNormal, anchorEnd, false)
+	}
+
+	if graph.UnitLegend != "" {
+		c.text(padding, (top+bottom)/2, graph.UnitLegend, colorSubText, textScaleNormal, anchorMiddle, true)
+	}
+
+	// Draw time axis and labels
+	c.polyline([]point{{left, bottom + 0.5}, {right, bottom + 0.5}}, colorAxis, 1)
+
+	for _, tick := range timeTicks(startTime, endTime, right-left) {
+		x := math.Floor(mapX(tick)) + 0.5
+
+		c.polyline([]point{{x, bottom}, {x, bottom + tickLength}}, colorAxis, 1)
+		c.text(x, bottom+tickLength+2, formatTime(tick, startTime, endTime), colorSubText, textScaleNormal,
+			anchorMiddle, false)
+	}
+
+	// Draw series: areas first, then lines on top of them
+	if graph.Type == library.GraphTypeArea {
+		for _, entry := range series {
+			if entry.Constant {
+				continue
+			}
+
+			for _, segment := range segments(entry) {
+				var points []point
+
+				for _, index := range segment {
+					points = append(points, point{mapX(entry.Plots[index].Time), mapY(entry.upper[index])})
+				}
+
+				for i := len(segment) - 1; i >= 0; i-- {
+					points = append(points, point{mapX(entry.Plots[segment[i]].Time), mapY(entry.lower[segment[i]])})
+				}
+
+				fill := entry.color
+				fill.A = uint8(math.Round(areaOpacity * 0xff))
+
+				c.polygon(points, fill)
+			}
+		}
+	}
+
+	for _, entry := range series {
+		for _, segment := range segments(entry) {
+			var points []point
+
+			for _, index := range segment {
+				points = append(points, point{mapX(entry.Plots[index].Time), mapY(entry.upper[index])})
+			}
+
+			c.polyline(points, entry.color, lineWidth)
+		}
+	}
+
+	// Draw legend
+	y := height - padding - float64(len(legendLines))*legendSpacing + (legendSpacing-legendBoxSize)/2
+
+	for _, line := range legendLines {
+		x := float64(padding)
+
+		for _, entry := range line {
+			c.fillRect(x, y, legendBoxSize, legendBoxSize, entry.color)
+			c.text(x+legendBoxSize+4, y+(legendBoxSize-textHeight(textScaleNormal))/2, entry.Name, colorText,
+				textScaleNormal, anchorStart, false)
+
+			x += legendWidth(entry)
+		}
+
+		y += legendSpacing
+	}
+}
+
+// stackSeries computes the lower and upper bounds of each series plot, according to the graph stack mode.
+func stackSeries(graph *Graph) []*renderSeries {
+	var result []*renderSeries
+
+	stacks := make(map[int][]*renderSeries)
+
+	for i, series := range graph.Series {
+		if series == nil || len(series.Plots) == 0 {
+			continue
+		}
+
+		entry := &renderSeries{
+			Series: series,
+			color:  parseColor(series.Color, seriesColors[i%len(seriesColors)]),
+			lower:  make([]float64, len(series.Plots)),
+			upper:  make([]float64, len(series.Plots)),
+		}
+
+		for j := range series.Plots {
+			entry.upper[j] = float64(series.Plots[j].Value)
+		}
+
+		if graph.StackMode != library.StackModeNone && graph.StackMode != 0 && !series.Constant {
+			stacks[series.StackID] = append(stacks[series.StackID], entry)
+		}
+
+		result = append(result, entry)
+	}
+
+	for _, stack := range stacks {
+		for i, entry := range stack {
+			for j := range entry.upper {
+				if i > 0 && j < len(stack[i-1].upper) {
+					entry.lower[j] = stack[i-1].upper[j]
+					if math.IsNaN(entry.lower[j]) {
+						// Skip missing values of the underlying series
+						entry.lower[j] = stack[i-1].lower[j]
+					}
+
+					if !math.IsNaN(entry.upper[j]) && !math.IsNaN(entry.lower[j]) {
+						entry.upper[j] += entry.lower[j]
+					} else if math.IsNaN(entry.upper[j]) {
+						// Keep stacking over missing values
+						entry.upper[j] = entry.lower[j]
+					}
+				}
+			}
+		}
+
+		if graph.StackMode != library.StackModePercent {
+			continue
+		}
+
+		// Convert stacked values into percentages of the stack total
+		top := stack[len(stack)-1]
+
+		for _, entry := range stack {
+			for j := range entry.upper {
+				if j >= len(top.upper) || top.upper[j] == 0 || math.IsNaN(top.upper[j]) {
+					continue
+				}
+
+				entry.lower[j] = entry.lower[j] / top.upper[j] * 100
+				entry.upper[j] = entry.upper[j] / top.upper[j] * 100
+			}
+		}
+	}
+
+	return result
+}
+
+// segments returns the list of contiguous non-missing plots indexes of a series.
+func segments(series *renderSeries) [][]int {
+	var (
+		result  [][]int
+		current []int
+	)
+
+	for i := range series.Plots {
+		if series.Plots[i].Value.IsNaN() || math.IsNaN(series.upper[i]) {
+			if len(current) > 0 {
+				result = append(result, current)
+				current = nil
+			}
+
+			continue
+		}
+
+		current = append(current, i)
+	}
+
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+
+	return result
+}
+
+func valueBounds(graph *Graph, series []*renderSeries) (float64, float64) {
+	if graph.StackMode == library.StackModePercent {
+		return 0, 100
+	}
+
+	minValue, maxValue := 0.0, math.NaN()
+
+	for _, entry := range series {
+		for i := range entry.upper {
+			for _, value := range []float64{entry.lower[i], entry.upper[i]} {
+				if math.IsNaN(value) {
+					continue
+				}
+
+				minValue = math.Min(minValue, value)
+
+				if math.IsNaN(maxValue) || value > maxValue {
+					maxValue = value
+				}
+			}
+		}
+	}
+
+	if math.IsNaN(maxValue) || maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	return minValue, maxValue
+}
+
+func timeBounds(graph *Graph, series []*renderSeries) (time.Time, time.Time) {
+	startTime, endTime := graph.StartTime, graph.EndTime
+
+	if !startTime.IsZero() && endTime.After(startTime) {
+		return startTime, endTime
+	}
+
+	for _, entry := range series {
+		for i := range entry.Plots {
+			if startTime.IsZero() || entry.Plots[i].Time.Before(startTime) {
+				startTime = entry.Plots[i].Time
+			}
+
+			if entry.Plots[i].Time.After(endTime) {
+				endTime = entry.Plots[i].Time
+			}
+		}
+	}
+
+	return startTime, endTime
+}
+
+// valueTicks returns evenly spaced round values covering a given interval.
+func valueTicks(minValue, maxValue float64) []float64 {
+	var ticks []float64
+
+	if math.IsNaN(minValue) || math.IsNaN(maxValue) || math.IsInf(minValue, 0) || math.IsInf(maxValue, 0) {
+		return nil
+	}
+
+	rawStep := (maxValue - minValue) / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(rawStep)))
+
+	step := magnitude * 10
+	for _, factor := range []float64{1, 2, 2.5, 5, 10} {
+		if rawStep <= factor*magnitude {
+			step = factor * magnitude
+			break
+		}
+	}
+
+	// Fall back to bounds for degenerate intervals, either too narrow to be split given the values magnitude or
+	// having a step not representable as a float
+	if !(step > 0) || math.IsInf(step, 0) || minValue+step == minValue || maxValue+step == maxValue {
+		if minValue == maxValue {
+			return []float64{minValue}
+		}
+
+		return []float64{minValue, maxValue}
+	}
+
+	for tick := math.Floor(minValue/step) * step; tick < maxValue+step; tick += step {
+		ticks = append(ticks, tick)
+
+		if tick >= maxValue {
+			break
+		}
+	}
+
+	return ticks
+}
+
+// timeTicks returns evenly spaced round times covering a given interval, given the available drawing width.
+func timeTicks(startTime, endTime time.Time, width float64) []time.Time {
+	var ticks []time.Time
+
+	if !endTime.After(startTime) {
+		return nil
+	}
+
+	maxTicks := int(width / 100)
+	if maxTicks < 2 {
+		maxTicks = 2
+	}
+
+	step := timeSteps[len(timeSteps)-1]
+	for _, entry := range timeSteps {
+		if endTime.Sub(startTime)/entry <= time.Duration(maxTicks) {
+			step = entry
+			break
+		}
+	}
+
+	// Align ticks on local time boundaries
+	_, offset := startTime.Zone()
+	zoneOffset := time.Duration(offset) * time.Second
+
+	tick := startTime.Add(zoneOffset).Truncate(step).Add(-zoneOffset)
+	if tick.Before(startTime) {
+		tick = tick.Add(step)
+	}
+
+	for ; !tick.After(endTime); tick = tick.Add(step) {
+		ticks = append(ticks, tick)
+	}
+
+	return ticks
+}
+
+func formatTick(value float64, graph *Graph) string {
+	if graph.StackMode == library.StackModePercent {
+		return FormatValue(value, library.GraphUnitTypeFixed) + "%"
+	}
+
+	return FormatValue(value, graph.UnitType)
+}
+
+func formatTime(t, startTime, endTime time.Time) string {
+	if endTime.Sub(startTime) > 24*time.Hour {
+		return t.Format("01/02 15:04")
+	}
+
+	return t.Format("15:04")
+}
+
+func layoutLegend(series []*renderSeries, width float64) [][]*renderSeries {
+	var (
+		lines     [][]*renderSeries
+		line      []*renderSeries
+		lineWidth float64
+	)
+
+	for _, entry := range series {
+		entryWidth := legendWidth(entry)
+
+		if len(line) > 0 && lineWidth+entryWidth > width {
+			lines = append(lines, line)
+			line, lineWidth = nil, 0
+		}
+
+		line = append(line, entry)
+		lineWidth += entryWidth
+	}
+
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func legendWidth(series *renderSeries) float64 {
+	return legendBoxSize + 4 + textWidth(series.Name, textScaleNormal) + legendSpacing
+}
+
+func textWidth(text string, scale int) float64 {
+	return float64(len(text)*(fontGlyphWidth+fontGlyphSpacing)*scale - fontGlyphSpacing*scale)
+}
+
+func textHeight(scale int) float64 {
+	return float64(fontGlyphHeight * scale)
+}
+
+// parseColor parses an hexadecimal color (e.g. `#ff0000' or `#f00'), returning the fallback one if invalid.
+func parseColor(input, fallback string) color.RGBA {
+	value := strings.TrimPrefix(input, "#")
+
+	if len(value) == 3 {
+		value = string([]byte{value[0], value[0], value[1], value[1], value[2], value[2]})
+	}
+
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if len(value) != 6 || err != nil {
+		if fallback == "" {
+			return colorText
+		}
+
+		return parseColor(fallback, "")
+	}
+
+	return color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff}
+}
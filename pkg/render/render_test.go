@@ -0,0 +1,173 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func testGraph(stackMode int) *Graph {
+	startTime := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	graph := &Graph{
+		Title:      "Test <graph>",
+		Type:       library.GraphTypeArea,
+		StackMode:  stackMode,
+		UnitType:   library.GraphUnitTypeMetric,
+		UnitLegend: "bytes",
+		StartTime:  startTime,
+		EndTime:    startTime.Add(time.Hour),
+		Series: []*Series{
+			{Name: "a", Plots: make([]plot.Plot, 7)},
+			{Name: "b", Color: "#ff0000", Plots: make([]plot.Plot, 7)},
+		},
+	}
+
+	for i := 0; i < 7; i++ {
+		graph.Series[0].Plots[i] = plot.Plot{Time: startTime.Add(time.Duration(i) * 10 * time.Minute), Value: 1}
+		graph.Series[1].Plots[i] = plot.Plot{Time: startTime.Add(time.Duration(i) * 10 * time.Minute), Value: 3}
+	}
+
+	graph.Series[0].Plots[3].Value = plot.Value(math.NaN())
+
+	return graph
+}
+
+func Test_RenderSVG(test *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+
+	if err := Render(buffer, testGraph(library.StackModeNormal), FormatSVG, 0, 0); err != nil {
+		test.Logf("Render() returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	output := buffer.String()
+
+	for _, expected := range []string{
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" version=\"1.1\" width=\"800\" height=\"300\"",
+		">Test &lt;graph&gt;</text>",
+		"<polygon ",
+		"stroke=\"#ff0000\"",
+		"transform=\"rotate(-90 ",
+		"</svg>",
+	} {
+		if !strings.Contains(output, expected) {
+			test.Logf("\nExpected output to contain %q", expected)
+			test.Fail()
+		}
+	}
+}
+
+func Test_RenderPNG(test *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+
+	if err := Render(buffer, testGraph(library.StackModeNone), FormatPNG, 400, 200); err != nil {
+		test.Logf("Render() returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	img, err := png.Decode(buffer)
+	if err != nil {
+		test.Logf("png.Decode() returned an error: %s", err)
+		test.Fail()
+		return
+	}
+
+	if size := img.Bounds().Size(); size.X != 400 || size.Y != 200 {
+		test.Logf("\nExpected %dx%d\nbut got  %dx%d", 400, 200, size.X, size.Y)
+		test.Fail()
+	}
+}
+
+func Test_RenderInvalid(test *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+
+	if err := Render(buffer, testGraph(library.StackModeNone), "gif", 0, 0); err == nil {
+		test.Logf("Render() should have returned an error on unsupported format")
+		test.Fail()
+	}
+
+	if err := Render(buffer, testGraph(library.StackModeNone), FormatPNG, 10, 10); err == nil {
+		test.Logf("Render() should have returned an error on invalid size")
+		test.Fail()
+	}
+}
+
+func Test_StackSeries(test *testing.T) {
+	series := stackSeries(testGraph(library.StackModeNormal))
+
+	expected := []float64{1, 1, 1, 0, 1, 1, 1}
+	if !reflect.DeepEqual(expected, series[1].lower) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, series[1].lower)
+		test.Fail()
+	}
+
+	expected = []float64{4, 4, 4, 3, 4, 4, 4}
+	if !reflect.DeepEqual(expected, series[1].upper) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, series[1].upper)
+		test.Fail()
+	}
+
+	series = stackSeries(testGraph(library.StackModePercent))
+
+	expected = []float64{25, 25, 25, 0, 25, 25, 25}
+	if !reflect.DeepEqual(expected, series[1].lower) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, series[1].lower)
+		test.Fail()
+	}
+
+	expected = []float64{100, 100, 100, 100, 100, 100, 100}
+	if !reflect.DeepEqual(expected, series[1].upper) {
+		test.Logf("\nExpected %#v\nbut got  %#v", expected, series[1].upper)
+		test.Fail()
+	}
+}
+
+func Test_ValueTicks(test *testing.T) {
+	for _, entry := range []struct {
+		Min, Max float64
+		Ticks    []float64
+	}{
+		{0, 1, []float64{0, 0.2, 0.4, 0.6000000000000001, 0.8, 1}},
+		{0, 100, []float64{0, 20, 40, 60, 80, 100}},
+		{-10, 45, []float64{-20, 0, 20, 40, 60}},
+		{-1e17, -1e17 + 1, []float64{-1e17}},
+		{0, 1e-323, []float64{0, 1e-323}},
+		{math.NaN(), 1, nil},
+	} {
+		ticks := valueTicks(entry.Min, entry.Max)
+
+		if !reflect.DeepEqual(entry.Ticks, ticks) {
+			test.Logf("\nExpected %#v\nbut got  %#v", entry.Ticks, ticks)
+			test.Fail()
+		}
+	}
+}
+
+func Test_FormatValue(test *testing.T) {
+	for _, entry := range []struct {
+		Value    float64
+		UnitType int
+		Result   string
+	}{
+		{0, library.GraphUnitTypeMetric, "0"},
+		{999, library.GraphUnitTypeMetric, "999"},
+		{1500, library.GraphUnitTypeMetric, "1.5 k"},
+		{2345678, library.GraphUnitTypeMetric, "2.35 M"},
+		{3.14159, library.GraphUnitTypeFixed, "3.14"},
+	} {
+		if result := FormatValue(entry.Value, entry.UnitType); result != entry.Result {
+			test.Logf("\nExpected %#v\nbut got  %#v", entry.Result, result)
+			test.Fail()
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type svgCanvas struct {
+	width, height int
+	buffer        bytes.Buffer
+}
+
+func newSVGCanvas(width, height int) *svgCanvas {
+	return &svgCanvas{width: width, height: height}
+}
+
+func (c *svgCanvas) fillRect(x, y, width, height float64, fill color.RGBA) {
+	fmt.Fprintf(&c.buffer, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" %s/>\n", svgFloat(x), svgFloat(y),
+		svgFloat(width), svgFloat(height), svgPaint("fill", fill))
+}
+
+func (c *svgCanvas) polyline(points []point, stroke color.RGBA, width float64) {
+	if len(points) == 0 {
+		return
+	}
+
+	fmt.Fprintf(&c.buffer, "<polyline points=\"%s\" fill=\"none\" stroke-width=\"%s\" stroke-linejoin=\"round\" %s/>\n",
+		svgPoints(points), svgFloat(width), svgPaint("stroke", stroke))
+}
+
+func (c *svgCanvas) polygon(points []point, fill color.RGBA) {
+	if len(points) == 0 {
+		return
+	}
+
+	fmt.Fprintf(&c.buffer, "<polygon points=\"%s\" %s/>\n", svgPoints(points), svgPaint("fill", fill))
+}
+
+func (c *svgCanvas) text(x, y float64, text string, fill color.RGBA, scale, anchor int, vertical bool) {
+	var (
+		buffer     bytes.Buffer
+		textAnchor string
+	)
+
+	switch anchor {
+	case anchorMiddle:
+		textAnchor = "middle"
+	case anchorEnd:
+		textAnchor = "end"
+	default:
+		textAnchor = "start"
+	}
+
+	xml.EscapeText(&buffer, []byte(text))
+
+	// Monospace font size is set so that characters width matches the bitmap font one
+	baseline := textHeight(scale)
+
+	if vertical {
+		fmt.Fprintf(&c.buffer, "<text x=\"%s\" y=\"%s\" transform=\"rotate(-90 %s %s)\" font-family=\"monospace\" "+
+			"font-size=\"%d\" text-anchor=\"%s\" %s>%s</text>\n", svgFloat(x+baseline), svgFloat(y),
+			svgFloat(x+baseline), svgFloat(y), 10*scale, textAnchor, svgPaint("fill", fill), buffer.String())
+		return
+	}
+
+	fmt.Fprintf(&c.buffer, "<text x=\"%s\" y=\"%s\" font-family=\"monospace\" font-size=\"%d\" text-anchor=\"%s\" "+
+		"%s>%s</text>\n", svgFloat(x), svgFloat(y+baseline), 10*scale, textAnchor, svgPaint("fill", fill),
+		buffer.String())
+}
+
+func (c *svgCanvas) write(writer io.Writer) error {
+	if _, err := fmt.Fprintf(writer, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" version=\"1.1\" width=\"%d\" height=\"%d\" "+
+		"viewBox=\"0 0 %d %d\">\n", c.width, c.height, c.width, c.height); err != nil {
+		return err
+	}
+
+	if _, err := c.buffer.WriteTo(writer); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(writer, "</svg>\n")
+
+	return err
+}
+
+func svgFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func svgPaint(attr string, value color.RGBA) string {
+	paint := fmt.Sprintf("%s=\"#%02x%02x%02x\"", attr, value.R, value.G, value.B)
+
+	if value.A != 0xff {
+		paint += fmt.Sprintf(" %s-opacity=\"%s\"", attr, strconv.FormatFloat(float64(value.A)/0xff, 'f', 2, 64))
+	}
+
+	return paint
+}
+
+func svgPoints(points []point) string {
+	chunks := make([]string, len(points))
+	for i, p := range points {
+		chunks[i] = strconv.FormatFloat(p.x, 'f', 2, 64) + "," + strconv.FormatFloat(p.y, 'f', 2, 64)
+	}
+
+	return strings.Join(chunks, " ")
+}
@@ -8,7 +8,9 @@ import (
 func (server *Server) serveLibrary(writer http.ResponseWriter, request *http.Request) {
 	setHTTPCacheHeaders(writer)
 
-	if strings.HasPrefix(request.URL.Path, urlLibraryPath+"sourcegroups/") {
+	if strings.Contains(strings.TrimPrefix(request.URL.Path, urlLibraryPath), "/revisions/") {
+		server.serveRevision(writer, request)
+	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"sourcegroups/") {
 		server.serveGroup(writer, request)
 	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"metricgroups/") {
 		server.serveGroup(writer, request)
@@ -20,14 +22,22 @@ func (server *Server) serveLibrary(writer http.ResponseWriter, request *http.Req
 		server.serveUnitLabels(writer, request)
 	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"units/") {
 		server.serveUnit(writer, request)
+	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"export/") {
+		server.serveBundleExport(writer, request)
+	} else if request.URL.Path == urlLibraryPath+"import" {
+		server.serveBundleImport(writer, request)
 	} else if request.URL.Path == urlLibraryPath+"expand" {
 		server.serveGroupExpand(writer, request)
 	} else if request.URL.Path == urlLibraryPath+"graphs/plots" {
 		server.serveGraphPlots(writer, request)
+	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"graphs/render/") {
+		server.serveGraphRender(writer, request)
 	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"graphs/") {
 		server.serveGraph(writer, request)
 	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"collections/") {
 		server.serveCollection(writer, request)
+	} else if strings.HasPrefix(request.URL.Path, urlLibraryPath+"annotations/") {
+		server.serveAnnotation(writer, request)
 	} else {
 		server.serveResponse(writer, nil, http.StatusNotFound)
 	}
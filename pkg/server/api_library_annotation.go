@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+)
+
+func (server *Server) serveAnnotation(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" && request.Method != "HEAD" {
+		if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+	}
+
+	annotationID := strings.TrimPrefix(request.URL.Path, urlLibraryPath+"annotations/")
+
+	switch request.Method {
+	case "DELETE":
+		if annotationID == "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := server.Library.DeleteItem(annotationID, library.LibraryItemAnnotation, library.DeleteModeStrict)
+		if os.IsNotExist(err) {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		server.serveResponse(writer, nil, http.StatusOK)
+
+	case "GET", "HEAD":
+		if annotationID == "" {
+			server.serveAnnotationList(writer, request)
+			return
+		}
+
+		item, err := server.Library.GetItem(annotationID, library.LibraryItemAnnotation)
+		if os.IsNotExist(err) {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		server.serveResponse(writer, item, http.StatusOK)
+
+	case "POST", "PUT":
+		if response, status := server.parseStoreRequest(writer, request, annotationID); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		// Create a new annotation instance
+		annotation := &library.Annotation{Item: library.Item{ID: annotationID}}
+		annotation.Modified = time.Now()
+
+		// Parse input JSON for annotation data
+		body, _ := ioutil.ReadAll(request.Body)
+
+		if err := json.Unmarshal(body, annotation); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+
+		// Default to current time for events being reported as they happen
+		if annotation.Start.IsZero() {
+			annotation.Start = annotation.Modified
+		}
+
+		// Store annotation data
+		err := server.Library.StoreItem(annotation, library.LibraryItemAnnotation, requestUser(request))
+		if response, status := server.parseError(writer, request, err); status != http.StatusOK {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		if request.Method == "POST" {
+			writer.Header().Add("Location", strings.TrimRight(request.URL.Path, "/")+"/"+annotation.ID)
+			server.serveResponse(writer, nil, http.StatusCreated)
+		} else {
+			server.serveResponse(writer, nil, http.StatusOK)
+		}
+
+	default:
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+	}
+}
+
+func (server *Server) serveAnnotationList(writer http.ResponseWriter, request *http.Request) {
+	var (
+		startTime, endTime time.Time
+		offset, limit      int
+		err                error
+	)
+
+	if response, status := server.parseListRequest(writer, request, &offset, &limit); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	if value := request.FormValue("start"); value != "" {
+		if startTime, err = time.Parse(time.RFC3339, value); err != nil {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := request.FormValue("end"); value != "" {
+		if endTime, err = time.Parse(time.RFC3339, value); err != nil {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := &listResponse{
+		list:   AnnotationListResponse(server.Library.FilterAnnotations(startTime, endTime, requestTags(request))),
+		offset: offset,
+		limit:  limit,
+	}
+
+	server.applyResponseLimit(writer, request, response)
+
+	server.serveResponse(writer, response.list, http.StatusOK)
+}
+
+// requestTags returns the list of tags given in a request, either as repeated or comma-separated `tag' parameters.
+func requestTags(request *http.Request) []string {
+	var tags []string
+
+	request.ParseForm()
+
+	for _, value := range request.Form["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_RequestTags(test *testing.T) {
+	for _, entry := range []struct {
+		Query  string
+		Result []string
+	}{
+		{"", nil},
+		{"tag=deploy", []string{"deploy"}},
+		{"tag=deploy,%20web,&tag=incident", []string{"deploy", "web", "incident"}},
+		{"tag=,", nil},
+	} {
+		request, err := http.NewRequest("GET", "/api/v1/library/annotations/?"+entry.Query, nil)
+		if err != nil {
+			test.Fatalf("unable to create request: %s", err)
+		}
+
+		if result := requestTags(request); !reflect.DeepEqual(result, entry.Result) {
+			test.Logf("\nExpected %v\nbut got  %v for query %q", entry.Result, result, entry.Query)
+			test.Fail()
+		}
+	}
+}
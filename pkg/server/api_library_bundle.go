@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+)
+
+func (server *Server) serveBundleExport(writer http.ResponseWriter, request *http.Request) {
+	if response, status := server.parseShowRequest(writer, request); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	collectionID := strings.TrimPrefix(request.URL.Path, urlLibraryPath+"export/")
+
+	bundle, err := server.Library.ExportCollection(collectionID)
+	if response, status := server.parseError(writer, request, err); status != http.StatusOK {
+		if status == http.StatusInternalServerError {
+			logger.Log(logger.LevelError, "server", "%s", err)
+		}
+
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	server.serveResponse(writer, bundle, http.StatusOK)
+}
+
+func (server *Server) serveBundleImport(writer http.ResponseWriter, request *http.Request) {
+	var bundle *library.Bundle
+
+	if request.Method != "POST" {
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+		return
+	} else if response, status := server.parseStoreRequest(writer, request, ""); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	} else if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	policy := request.FormValue("policy")
+	if policy == "" {
+		policy = library.ImportPolicySkip
+	} else if policy != library.ImportPolicySkip && policy != library.ImportPolicyRename &&
+		policy != library.ImportPolicyOverwrite {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(request.Body)
+
+	if err := json.Unmarshal(body, &bundle); err != nil || bundle == nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	// Check for ownership of the collections either overwritten or used as parent
+	result, err := server.Library.ImportBundle(bundle, policy, requestUser(request), func(owner string) error {
+		if _, status := server.parseWriteRequest(request, owner); status != http.StatusOK {
+			return os.ErrPermission
+		}
+
+		return nil
+	})
+	if os.IsPermission(err) {
+		server.serveResponse(writer, serverResponse{mesgPermissionDenied}, http.StatusForbidden)
+		return
+	} else if err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	server.serveResponse(writer, result, http.StatusOK)
+}
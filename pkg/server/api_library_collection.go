@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/facette/facette/pkg/auth"
 	"github.com/facette/facette/pkg/library"
 	"github.com/facette/facette/pkg/logger"
 	"github.com/facette/facette/pkg/utils"
@@ -18,12 +20,15 @@ import (
 func (server *Server) serveCollection(writer http.ResponseWriter, request *http.Request) {
 	type tmpCollection struct {
 		*library.Collection
-		Parent string `json:"parent"`
+		Parent string  `json:"parent"`
+		Owner  *string `json:"owner"`
 	}
 
-	if request.Method != "GET" && request.Method != "HEAD" && server.Config.ReadOnly {
-		server.serveResponse(writer, serverResponse{mesgReadOnlyMode}, http.StatusForbidden)
-		return
+	if request.Method != "GET" && request.Method != "HEAD" {
+		if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
 	}
 
 	collectionID := strings.TrimPrefix(request.URL.Path, urlLibraryPath+"collections/")
@@ -35,7 +40,15 @@ func (server *Server) serveCollection(writer http.ResponseWriter, request *http.
 			return
 		}
 
-		err := server.Library.DeleteItem(collectionID, library.LibraryItemCollection)
+		if item, _ := server.Library.GetItem(collectionID, library.LibraryItemCollection); item != nil {
+			if response, status := server.parseWriteRequest(request,
+				item.(*library.Collection).Owner); status != http.StatusOK {
+				server.serveResponse(writer, response, status)
+				return
+			}
+		}
+
+		err := server.Library.DeleteItem(collectionID, library.LibraryItemCollection, library.DeleteModeStrict)
 		if os.IsNotExist(err) {
 			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
 			return
@@ -63,6 +76,11 @@ func (server *Server) serveCollection(writer http.ResponseWriter, request *http.
 			return
 		}
 
+		// Expand generated entries if requested
+		if expand, _ := strconv.ParseBool(request.FormValue("expand")); expand {
+			item = server.Library.ExpandCollection(item.(*library.Collection))
+		}
+
 		server.serveResponse(writer, item, http.StatusOK)
 
 	case "POST", "PUT":
@@ -93,6 +111,7 @@ func (server *Server) serveCollection(writer http.ResponseWriter, request *http.
 
 			collectionTemp.Collection.ID = ""
 			collectionTemp.Collection.Children = nil
+			collectionTemp.Collection.Owner = ""
 		}
 
 		collectionTemp.Collection.Modified = time.Now()
@@ -106,10 +125,47 @@ func (server *Server) serveCollection(writer http.ResponseWriter, request *http.
 			return
 		}
 
+		// Check for collection ownership, keeping the current owner unless explicitly changed
+		parentID := ""
+
+		if item, _ := server.Library.GetItem(collectionTemp.Collection.ID,
+			library.LibraryItemCollection); item != nil {
+			owner := item.(*library.Collection).Owner
+
+			if response, status := server.parseWriteRequest(request, owner); status != http.StatusOK {
+				server.serveResponse(writer, response, status)
+				return
+			}
+
+			collectionTemp.Collection.Owner = owner
+			parentID = item.(*library.Collection).ParentID
+		} else {
+			// New collections are owned by their creator
+			collectionTemp.Collection.Owner = requestUser(request)
+		}
+
+		if collectionTemp.Owner != nil {
+			if *collectionTemp.Owner != "" && *collectionTemp.Owner != requestUser(request) &&
+				server.requestRole(request) < auth.RoleAdmin {
+				server.serveResponse(writer, serverResponse{mesgPermissionDenied}, http.StatusForbidden)
+				return
+			}
+
+			collectionTemp.Collection.Owner = *collectionTemp.Owner
+		}
+
 		// Update parent relation
 		if item, _ := server.Library.GetItem(collectionTemp.Parent, library.LibraryItemCollection); item != nil {
 			collection := item.(*library.Collection)
 
+			// Check for parent collection ownership when attaching to a new parent
+			if collection.ID != parentID {
+				if response, status := server.parseWriteRequest(request, collection.Owner); status != http.StatusOK {
+					server.serveResponse(writer, response, status)
+					return
+				}
+			}
+
 			// Register parent relation
 			collectionTemp.Collection.Parent = collection
 			collectionTemp.Collection.ParentID = collectionTemp.Collection.Parent.ID
@@ -138,7 +194,8 @@ func (server *Server) serveCollection(writer http.ResponseWriter, request *http.
 		}
 
 		// Store collection data
-		err := server.Library.StoreItem(collectionTemp.Collection, library.LibraryItemCollection)
+		err := server.Library.StoreItem(collectionTemp.Collection, library.LibraryItemCollection,
+			requestUser(request))
 		if response, status := server.parseError(writer, request, err); status != http.StatusOK {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, response, status)
@@ -1,27 +1,39 @@
 package server
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/facette/facette/pkg/catalog"
 	"github.com/facette/facette/pkg/config"
 	"github.com/facette/facette/pkg/connector"
 	"github.com/facette/facette/pkg/library"
 	"github.com/facette/facette/pkg/logger"
 	"github.com/facette/facette/pkg/plot"
+	"github.com/facette/facette/pkg/render"
 	"github.com/facette/facette/pkg/utils"
 	"github.com/facette/facette/thirdparty/github.com/fatih/set"
 )
 
+var errEmptyData = errors.New("no data")
+
 func (server *Server) serveGraph(writer http.ResponseWriter, request *http.Request) {
-	if request.Method != "GET" && request.Method != "HEAD" && server.Config.ReadOnly {
-		server.serveResponse(writer, serverResponse{mesgReadOnlyMode}, http.StatusForbidden)
-		return
+	if request.Method != "GET" && request.Method != "HEAD" {
+		if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
 	}
 
 	graphID := strings.TrimPrefix(request.URL.Path, urlLibraryPath+"graphs/")
@@ -33,10 +45,20 @@ func (server *Server) serveGraph(writer http.ResponseWriter, request *http.Reque
 			return
 		}
 
-		err := server.Library.DeleteItem(graphID, library.LibraryItemGraph)
+		mode, response, status := server.parseDeleteMode(request, graphID, library.LibraryItemGraph)
+		if status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		err := server.Library.DeleteItem(graphID, library.LibraryItemGraph, mode)
 		if os.IsNotExist(err) {
 			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
 			return
+		} else if referenceErr, ok := err.(*library.ReferenceError); ok {
+			server.serveResponse(writer, referenceResponse{mesgResourceReferenced, referenceErr.References},
+				http.StatusConflict)
+			return
 		} else if err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
@@ -103,7 +125,7 @@ func (server *Server) serveGraph(writer http.ResponseWriter, request *http.Reque
 			return
 		}
 
-		err := server.Library.StoreItem(graph, library.LibraryItemGraph)
+		err := server.Library.StoreItem(graph, library.LibraryItemGraph, requestUser(request))
 		if response, status := server.parseError(writer, request, err); status != http.StatusOK {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, response, status)
@@ -147,7 +169,7 @@ func (server *Server) serveGraphList(writer http.ResponseWriter, request *http.R
 			return
 		}
 
-		collection := item.(*library.Collection)
+		collection := server.Library.ExpandCollection(item.(*library.Collection))
 
 		for _, graph := range collection.Entries {
 			graphSet.Add(graph.ID)
@@ -186,14 +208,6 @@ func (server *Server) serveGraphList(writer http.ResponseWriter, request *http.R
 }
 
 func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.Request) {
-	var (
-		graphPlotSeries    [][]plot.Series
-		err                error
-		graph              *library.Graph
-		item               interface{}
-		startTime, endTime time.Time
-	)
-
 	if request.Method != "POST" && request.Method != "HEAD" {
 		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
 		return
@@ -202,6 +216,12 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 		return
 	}
 
+	format := request.FormValue("format")
+	if format != "" && format != plotsFormatJSON && format != plotsFormatFlat && format != plotsFormatCSV {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
 	// Parse input JSON for graph series
 	body, _ := ioutil.ReadAll(request.Body)
 
@@ -213,38 +233,170 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 		return
 	}
 
+	response, err := server.getPlots(&plotReq)
+	if err == errEmptyData {
+		server.serveResponse(writer, serverResponse{mesgEmptyData}, http.StatusOK)
+		return
+	} else if err != nil {
+		errResponse, status := server.parseError(writer, request, err)
+		if status == http.StatusInternalServerError {
+			logger.Log(logger.LevelError, "server", "%s", err)
+		}
+
+		server.serveResponse(writer, errResponse, status)
+		return
+	}
+
+	switch format {
+	case plotsFormatCSV:
+		server.servePlotsCSV(writer, response)
+
+	case plotsFormatFlat:
+		server.serveResponse(writer, flattenPlots(response), http.StatusOK)
+
+	default:
+		server.serveResponse(writer, response, http.StatusOK)
+	}
+}
+
+func (server *Server) serveGraphRender(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	if request.Method != "GET" && request.Method != "HEAD" {
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := request.FormValue("format")
+	if format == "" {
+		format = render.FormatPNG
+	} else if format != render.FormatPNG && format != render.FormatSVG {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	plotReq := PlotRequest{
+		ID:    strings.TrimPrefix(request.URL.Path, urlLibraryPath+"graphs/render/"),
+		Time:  request.FormValue("time"),
+		Range: request.FormValue("range"),
+	}
+
+	if plotReq.Range == "" {
+		plotReq.Range = "-1h"
+	}
+
+	size := make(map[string]int)
+
+	for _, key := range []string{"sample", "width", "height"} {
+		if request.FormValue(key) == "" {
+			continue
+		} else if size[key], err = strconv.Atoi(request.FormValue(key)); err != nil || size[key] <= 0 {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	plotReq.Sample = size["sample"]
+	plotReq.Variables = requestVariables(request)
+
+	response, err := server.getPlots(&plotReq)
+	if err != nil && err != errEmptyData {
+		errResponse, status := server.parseError(writer, request, err)
+		if status == http.StatusInternalServerError {
+			logger.Log(logger.LevelError, "server", "%s", err)
+		}
+
+		server.serveResponse(writer, errResponse, status)
+		return
+	}
+
+	graph := renderGraph(response)
+
+	if title := request.FormValue("title"); title != "" {
+		graph.Title = title
+	}
+
+	buffer := bytes.NewBuffer(nil)
+
+	if err := render.Render(buffer, graph, format, size["width"], size["height"]); err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	if format == render.FormatSVG {
+		writer.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		writer.Header().Set("Content-Type", "image/png")
+	}
+
+	writer.WriteHeader(http.StatusOK)
+
+	if request.Method != "HEAD" {
+		buffer.WriteTo(writer)
+	}
+}
+
+func (server *Server) getPlots(plotReq *PlotRequest) (*PlotResponse, error) {
+	var (
+		graphPlotSeries    [][]plot.Series
+		err                error
+		graph              *library.Graph
+		item               interface{}
+		startTime, endTime time.Time
+	)
+
 	if plotReq.Time == "" {
 		endTime = time.Now()
 	} else if strings.HasPrefix(strings.Trim(plotReq.Range, " "), "-") {
 		if endTime, err = time.Parse(time.RFC3339, plotReq.Time); err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
-			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
-			return
+			return nil, os.ErrInvalid
 		}
 	} else {
 		if startTime, err = time.Parse(time.RFC3339, plotReq.Time); err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
-			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
-			return
+			return nil, os.ErrInvalid
 		}
 	}
 
 	if startTime.IsZero() {
 		if startTime, err = utils.TimeApplyRange(endTime, plotReq.Range); err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
-			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
-			return
+			return nil, os.ErrInvalid
 		}
 	} else if endTime, err = utils.TimeApplyRange(startTime, plotReq.Range); err != nil {
 		logger.Log(logger.LevelError, "server", "%s", err)
-		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
-		return
+		return nil, os.ErrInvalid
 	}
 
 	if plotReq.Sample == 0 {
 		plotReq.Sample = config.DefaultPlotSample
 	}
 
+	if plotReq.Downsampling == "" {
+		plotReq.Downsampling = downsamplingConsolidate
+	} else if plotReq.Downsampling != downsamplingConsolidate && plotReq.Downsampling != downsamplingLTTB {
+		logger.Log(logger.LevelError, "server", "unknown downsampling mode `%s'", plotReq.Downsampling)
+		return nil, os.ErrInvalid
+	}
+
+	for _, shift := range plotReq.Shifts {
+		if _, err := utils.TimeApplyRange(startTime, shift); err != nil {
+			logger.Log(logger.LevelError, "server", "invalid time shift `%s': %s", shift, err)
+			return nil, os.ErrInvalid
+		}
+	}
+
+	for groupName, smoothReq := range plotReq.Smoothing {
+		if smoothReq == nil {
+			continue
+		} else if err := smoothReq.check(); err != nil {
+			logger.Log(logger.LevelError, "server", "group `%s': %s", groupName, err)
+			return nil, os.ErrInvalid
+		}
+	}
+
 	// Get graph from library
 	graph = plotReq.Graph
 
@@ -259,14 +411,15 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 	}
 
 	if err != nil {
-		if os.IsNotExist(err) {
-			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
-		} else {
+		return nil, err
+	}
+
+	// Expand graph template variables
+	if len(graph.Variables) > 0 {
+		if graph, err = graph.Expand(plotReq.Variables); err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
-			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return nil, os.ErrInvalid
 		}
-
-		return
 	}
 
 	// Get graph plots series
@@ -275,7 +428,7 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 	for _, groupItem := range graph.Groups {
 		groupOptions[groupItem.Name] = groupItem.Options
 
-		query, providerConnector, err := server.prepareQuery(&plotReq, groupItem)
+		queries, err := server.prepareQuery(plotReq, groupItem)
 		if err != nil {
 			if err != os.ErrInvalid {
 				logger.Log(logger.LevelError, "server", "%s", err)
@@ -285,21 +438,79 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 			continue
 		}
 
-		plotSeries, err := providerConnector.GetPlots(&plot.Query{query, startTime, endTime, plotReq.Sample})
+		plotSeries, err := server.executeQueries(queries, groupItem, startTime, endTime, plotReq.Sample)
 		if err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
 		}
 
-		if len(plotSeries) > 1 {
-			for index := range plotSeries {
-				plotSeries[index].Name = fmt.Sprintf("%s (%s)", query.Series[index].Metric.Source, query.Series[index].Metric.Name)
-				plotSeries[index].Summarize(plotReq.Percentiles)
-				plotSeries[index].Downsample(plotReq.Sample, plot.ConsolidateAverage)
-			}
-		} else if len(plotSeries) == 1 {
+		if len(plotSeries) == 1 {
 			plotSeries[0].Name = groupItem.Name
-			plotSeries[0].Summarize(plotReq.Percentiles)
-			plotSeries[0].Downsample(plotReq.Sample, plot.ConsolidateAverage)
+		}
+
+		if smoothReq, ok := plotReq.Smoothing[groupItem.Name]; ok && smoothReq != nil {
+			smoothedSeries, err := server.applySmoothing(plotSeries, smoothReq)
+			if err != nil {
+				logger.Log(logger.LevelError, "server", "%s", err)
+			} else {
+				plotSeries = smoothedSeries
+			}
+		}
+
+		// Append time-shifted copies of the group series, re-aligned onto the requested time range
+		for _, shift := range plotReq.Shifts {
+			shiftStartTime, _ := utils.TimeApplyRange(startTime, shift)
+			shiftEndTime, _ := utils.TimeApplyRange(endTime, shift)
+
+			shiftedSeries, err := server.executeQueries(queries, groupItem, shiftStartTime, shiftEndTime,
+				plotReq.Sample)
+			if err != nil {
+				logger.Log(logger.LevelError, "server", "%s", err)
+				continue
+			}
+
+			offset := startTime.Sub(shiftStartTime)
+
+			for index := range shiftedSeries {
+				if len(shiftedSeries) == 1 {
+					shiftedSeries[index].Name = groupItem.Name
+				}
+
+				shiftedSeries[index].Name = fmt.Sprintf("%s (%s)", shiftedSeries[index].Name, shift)
+
+				plots := shiftedSeries[index].Plots
+				for plotIndex := range plots {
+					plots[plotIndex].Time = plots[plotIndex].Time.Add(offset)
+				}
+			}
+
+			plotSeries = append(plotSeries, shiftedSeries...)
+		}
+
+		// Get group consolidation, falling back on graph one
+		consolidation := groupItem.Consolidation
+		if consolidation == "" {
+			consolidation = graph.Consolidation
+		}
+
+		consolidationType, percentile := plot.ConsolidateAverage, 0.0
+
+		if consolidation != "" {
+			if consolidationType, percentile, err = plot.ParseConsolidation(consolidation); err != nil {
+				logger.Log(logger.LevelError, "server", "%s", err)
+				consolidationType = plot.ConsolidateAverage
+			}
+		}
+
+		for index := range plotSeries {
+			plotSeries[index].Summarize(plotReq.Percentiles)
+
+			if plotReq.Downsampling == downsamplingLTTB {
+				plotSeries[index].DownsampleLTTB(plotReq.Sample)
+			} else if consolidationType == plot.ConsolidatePercentile {
+				plotSeries[index].DownsamplePercentile(plotReq.Sample, percentile)
+			} else {
+				plotSeries[index].Downsample(plotReq.Sample, consolidationType)
+			}
 		}
 
 		graphPlotSeries = append(graphPlotSeries, plotSeries)
@@ -320,10 +531,11 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 	}
 
 	if len(graphPlotSeries) == 0 {
-		server.serveResponse(writer, serverResponse{mesgEmptyData}, http.StatusOK)
-		return
+		return nil, errEmptyData
 	}
 
+	var refPlots []plot.Plot
+
 	plotMax := 0
 
 	for _, groupItem := range graph.Groups {
@@ -334,6 +546,7 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 		for _, seriesResult := range series {
 			if len(seriesResult.Plots) > plotMax {
 				plotMax = len(seriesResult.Plots)
+				refPlots = seriesResult.Plots
 			}
 
 			response.Series = append(response.Series, &SeriesResponse{
@@ -350,26 +563,64 @@ func (server *Server) serveGraphPlots(writer http.ResponseWriter, request *http.
 		response.Step = (endTime.Sub(startTime) / time.Duration(plotMax)).Seconds()
 	}
 
-	server.serveResponse(writer, response, http.StatusOK)
-}
+	// Append constants as flat series, graph-defined ones first
+	response.Series = append(response.Series, constantsSeries(graph.Constants, plotReq, refPlots, startTime,
+		endTime)...)
+
+	// Append annotations matching requested tags, an empty list matching any tag
+	if plotReq.Annotations != nil {
+		response.Annotations = server.Library.FilterAnnotations(startTime, endTime, plotReq.Annotations)
+	}
 
-func (server *Server) prepareQuery(plotReq *PlotRequest, groupItem *library.OperGroup) (*plot.QueryGroup,
-	connector.Connector, error) {
+	return response, nil
+}
 
+func (server *Server) prepareQuery(plotReq *PlotRequest, groupItem *library.OperGroup) ([]*providerQuery, error) {
 	var (
-		providerConnector connector.Connector
-		seriesSources     []string
+		queries       []*providerQuery
+		seriesSources []string
 	)
 
-	query := &plot.QueryGroup{
-		Type:    groupItem.Type,
-		Options: groupItem.Options,
+	// Group series by connector, keeping their order of appearance
+	appendSeries := func(metric *catalog.Metric, seriesItem *library.Series) {
+		var query *providerQuery
+
+		providerConnector := metric.Connector.(connector.Connector)
+
+		for _, entry := range queries {
+			if entry.connector == providerConnector {
+				query = entry
+				break
+			}
+		}
+
+		if query == nil {
+			query = &providerQuery{
+				query: &plot.QueryGroup{
+					Type:    groupItem.Type,
+					Options: groupItem.Options,
+				},
+				connector: providerConnector,
+			}
+
+			queries = append(queries, query)
+		}
+
+		query.query.Series = append(query.query.Series, &plot.QuerySeries{
+			Name: seriesItem.Name,
+			Metric: &plot.QueryMetric{
+				Name:   metric.OriginalName,
+				Origin: metric.Source.Origin.OriginalName,
+				Source: metric.Source.OriginalName,
+			},
+			Options: seriesItem.Options,
+		})
 	}
 
 	for _, seriesItem := range groupItem.Series {
-		// Check for connectors errors or conflicts
+		// Check for connectors errors
 		if _, ok := server.Catalog.Origins[seriesItem.Origin]; !ok {
-			return nil, nil, fmt.Errorf("unknown series origin `%s'", seriesItem.Origin)
+			return nil, fmt.Errorf("unknown series origin `%s'", seriesItem.Origin)
 		}
 
 		if strings.HasPrefix(seriesItem.Source, library.LibraryGroupPrefix) {
@@ -381,8 +632,6 @@ func (server *Server) prepareQuery(plotReq *PlotRequest, groupItem *library.Oper
 			seriesSources = []string{seriesItem.Source}
 		}
 
-		index := 0
-
 		for _, seriesEntry := range seriesSources {
 			if strings.HasPrefix(seriesItem.Metric, library.LibraryGroupPrefix) {
 				for _, seriesChunk := range server.Library.ExpandGroup(
@@ -404,22 +653,7 @@ func (server *Server) prepareQuery(plotReq *PlotRequest, groupItem *library.Oper
 						continue
 					}
 
-					if providerConnector == nil {
-						providerConnector = metric.Connector.(connector.Connector)
-					} else if providerConnector != metric.Connector.(connector.Connector) {
-						return nil, nil, fmt.Errorf("connectors differ between series")
-					}
-
-					query.Series = append(query.Series, &plot.QuerySeries{
-						Metric: &plot.QueryMetric{
-							Name:   metric.OriginalName,
-							Origin: metric.Source.Origin.OriginalName,
-							Source: metric.Source.OriginalName,
-						},
-						Options: seriesItem.Options,
-					})
-
-					index++
+					appendSeries(metric, seriesItem)
 				}
 			} else {
 				metric := server.Catalog.GetMetric(seriesItem.Origin, seriesEntry, seriesItem.Metric)
@@ -437,29 +671,481 @@ func (server *Server) prepareQuery(plotReq *PlotRequest, groupItem *library.Oper
 					continue
 				}
 
-				if providerConnector == nil {
-					providerConnector = metric.Connector.(connector.Connector)
-				} else if providerConnector != metric.Connector.(connector.Connector) {
-					return nil, nil, fmt.Errorf("connectors differ between series")
+				appendSeries(metric, seriesItem)
+			}
+		}
+	}
+
+	if len(queries) == 0 {
+		return nil, os.ErrInvalid
+	}
+
+	return queries, nil
+}
+
+func (server *Server) executeQueries(queries []*providerQuery, groupItem *library.OperGroup, startTime,
+	endTime time.Time, sample int) ([]plot.Series, error) {
+
+	var resultSeries []plot.Series
+
+	// Let the connector handle the whole operation group if all series share the same connector and no expression
+	// nor transform has to be applied
+	if len(queries) == 1 && groupItem.Expr == "" && !hasTransform(queries[0].query) {
+		plotSeries, err := queries[0].connector.GetPlots(&plot.Query{
+			Group:     queries[0].query,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Sample:    sample,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(plotSeries) > 1 {
+			for index := range plotSeries {
+				plotSeries[index].Name = fmt.Sprintf(
+					"%s (%s)",
+					queries[0].query.Series[index].Metric.Source,
+					queries[0].query.Series[index].Metric.Name,
+				)
+			}
+		}
+
+		return plotSeries, nil
+	}
+
+	// Fan out per-connector sub-queries, then perform the operation on the resulting series
+	for _, entry := range queries {
+		entry.query.Type = connector.OperGroupTypeNone
+
+		plotSeries, err := entry.connector.GetPlots(&plot.Query{
+			Group:     entry.query,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Sample:    sample,
+		})
+		if err != nil {
+			return nil, err
+		} else if len(plotSeries) != len(entry.query.Series) {
+			// Connectors omit series having no data (e.g. Graphite), thus query them one by one to map the results
+			if plotSeries, err = queryEachSeries(entry, startTime, endTime, sample); err != nil {
+				return nil, err
+			}
+		}
+
+		for index := range plotSeries {
+			plotSeries[index].Name = fmt.Sprintf(
+				"%s (%s)",
+				entry.query.Series[index].Metric.Source,
+				entry.query.Series[index].Metric.Name,
+			)
+
+			transform, _ := config.GetString(entry.query.Series[index].Options, "transform", false)
+			if transform != "" {
+				transformType, err := plot.ParseTransform(transform)
+				if err != nil {
+					return nil, err
 				}
 
-				query.Series = append(query.Series, &plot.QuerySeries{
-					Metric: &plot.QueryMetric{
-						Name:   metric.OriginalName,
-						Origin: metric.Source.Origin.OriginalName,
-						Source: metric.Source.OriginalName,
-					},
-					Options: seriesItem.Options,
-				})
+				// Counter wraps are only handled if the counter maximal value is known
+				counterMax, err := config.GetFloat(entry.query.Series[index].Options, "counter_max", false)
+				if err != nil {
+					return nil, err
+				}
 
-				index++
+				plotSeries[index].TransformCounter(transformType, plot.Value(counterMax))
 			}
 		}
+
+		resultSeries = append(resultSeries, plotSeries...)
+	}
+
+	if len(resultSeries) == 0 || groupItem.Expr == "" && groupItem.Type == connector.OperGroupTypeNone {
+		return resultSeries, nil
+	}
+
+	// Align series plots coming from different connectors on the same timestamps before combining them
+	resultSeries, err := plot.AlignSeries(resultSeries)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupItem.Expr != "" {
+		return server.evalExpr(queries, resultSeries, groupItem.Expr)
+	}
+
+	switch groupItem.Type {
+	case connector.OperGroupTypeAvg:
+		avgSeries, err := plot.AvgSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("unable to average series: %s", err)
+		}
+
+		return []plot.Series{avgSeries}, nil
+
+	case connector.OperGroupTypeSum:
+		sumSeries, err := plot.SumSeries(resultSeries)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sum series: %s", err)
+		}
+
+		return []plot.Series{sumSeries}, nil
 	}
 
-	if len(query.Series) == 0 {
-		return nil, nil, os.ErrInvalid
+	return resultSeries, nil
+}
+
+// queryEachSeries queries the series of a provider query separately, series returned without data being considered as
+// having only NaN values.
+func queryEachSeries(entry *providerQuery, startTime, endTime time.Time, sample int) ([]plot.Series, error) {
+	result := make([]plot.Series, len(entry.query.Series))
+
+	for index, series := range entry.query.Series {
+		plotSeries, err := entry.connector.GetPlots(&plot.Query{
+			Group: &plot.QueryGroup{
+				Type:    connector.OperGroupTypeNone,
+				Series:  []*plot.QuerySeries{series},
+				Options: entry.query.Options,
+			},
+			StartTime: startTime,
+			EndTime:   endTime,
+			Sample:    sample,
+		})
+		if err != nil {
+			return nil, err
+		} else if len(plotSeries) > 1 {
+			return nil, fmt.Errorf("got %d series from connector, expected 1", len(plotSeries))
+		} else if len(plotSeries) == 1 {
+			result[index] = plotSeries[0]
+		} else {
+			result[index] = plot.Series{Summary: make(map[string]plot.Value)}
+		}
 	}
 
-	return query, providerConnector, nil
+	return result, nil
+}
+
+func (server *Server) evalExpr(queries []*providerQuery, resultSeries []plot.Series, input string) ([]plot.Series,
+	error) {
+
+	expr, err := plot.ParseExpr(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse expression: %s", err)
+	}
+
+	// Map resulting series to their library names, summing series expanded from groups
+	namedSeries := make(map[string][]plot.Series)
+
+	index := 0
+
+	for _, entry := range queries {
+		for _, querySeries := range entry.query.Series {
+			namedSeries[querySeries.Name] = append(namedSeries[querySeries.Name], resultSeries[index])
+			index++
+		}
+	}
+
+	exprSeries := make(map[string]plot.Series)
+
+	for name, seriesList := range namedSeries {
+		if len(seriesList) == 1 {
+			exprSeries[name] = seriesList[0]
+			continue
+		}
+
+		if exprSeries[name], err = plot.SumSeries(seriesList); err != nil {
+			return nil, fmt.Errorf("unable to sum `%s' series: %s", name, err)
+		}
+	}
+
+	series, err := expr.Eval(exprSeries)
+	if err != nil {
+		return nil, fmt.Errorf("unable to evaluate expression: %s", err)
+	}
+
+	return []plot.Series{series}, nil
+}
+
+func (server *Server) applySmoothing(seriesList []plot.Series, smoothReq *SmoothingRequest) ([]plot.Series,
+	error) {
+
+	var resultSeries []plot.Series
+
+	for _, series := range seriesList {
+		resultSeries = append(resultSeries, series)
+
+		switch smoothReq.Type {
+		case smoothingTypeSMA:
+			avgSeries, err := plot.MovingAverage(series, smoothReq.Window)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compute moving average: %s", err)
+			}
+
+			avgSeries.Name = fmt.Sprintf("%s (sma)", series.Name)
+			resultSeries = append(resultSeries, avgSeries)
+
+		case smoothingTypeEMA:
+			avgSeries, err := plot.ExponentialMovingAverage(series, smoothReq.Alpha)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compute exponential moving average: %s", err)
+			}
+
+			avgSeries.Name = fmt.Sprintf("%s (ema)", series.Name)
+			resultSeries = append(resultSeries, avgSeries)
+
+		case smoothingTypeHoltWinters:
+			refTime := time.Now()
+
+			seasonTime, err := utils.TimeApplyRange(refTime, smoothReq.Season)
+			if err != nil {
+				return nil, err
+			}
+
+			seasonDuration := seasonTime.Sub(refTime)
+
+			// Confidence bands are only estimated from the residuals until a whole season is available
+			if count := len(series.Plots); count > 0 &&
+				series.Plots[count-1].Time.Sub(series.Plots[0].Time) < seasonDuration {
+				logger.Log(logger.LevelWarning, "server", "series `%s' is shorter than Holt-Winters season `%s'",
+					series.Name, smoothReq.Season)
+			}
+
+			forecastSeries, lowerSeries, upperSeries, err := plot.HoltWinters(series, smoothReq.Alpha,
+				smoothReq.Beta, smoothReq.Gamma, seasonDuration, smoothReq.Delta)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compute Holt-Winters forecast: %s", err)
+			}
+
+			forecastSeries.Name = fmt.Sprintf("%s (forecast)", series.Name)
+			lowerSeries.Name = fmt.Sprintf("%s (lower)", series.Name)
+			upperSeries.Name = fmt.Sprintf("%s (upper)", series.Name)
+
+			resultSeries = append(resultSeries, forecastSeries, lowerSeries, upperSeries)
+		}
+	}
+
+	return resultSeries, nil
+}
+
+// constantsSeries returns the flat series of graph and plot request constants, marked using the `constant' option.
+func constantsSeries(graphConstants []*library.Constant, plotReq *PlotRequest, refPlots []plot.Plot, startTime,
+	endTime time.Time) []*SeriesResponse {
+
+	var result []*SeriesResponse
+
+	constants := append([]*library.Constant{}, graphConstants...)
+
+	for _, value := range plotReq.Constants {
+		constants = append(constants, &library.Constant{Value: value})
+	}
+
+	for _, constant := range constants {
+		series := constantSeries(constant.Value, refPlots, startTime, endTime, plotReq.Sample)
+
+		if constant.Label != "" {
+			series.Name = constant.Label
+		}
+
+		series.Summarize(plotReq.Percentiles)
+
+		seriesResponse := &SeriesResponse{
+			Name:    series.Name,
+			Plots:   series.Plots,
+			Summary: series.Summary,
+			Options: map[string]interface{}{"constant": true},
+		}
+
+		if constant.Color != "" {
+			seriesResponse.Options["color"] = constant.Color
+		}
+
+		result = append(result, seriesResponse)
+	}
+
+	return result
+}
+
+func constantSeries(value float64, refPlots []plot.Plot, startTime, endTime time.Time, sample int) plot.Series {
+	series := plot.Series{
+		Name:    fmt.Sprintf("%g", value),
+		Summary: make(map[string]plot.Value),
+	}
+
+	// Align plots on reference series if any, or spread them over the time range otherwise
+	if len(refPlots) > 0 {
+		series.Plots = make([]plot.Plot, len(refPlots))
+
+		for i := range refPlots {
+			series.Plots[i] = plot.Plot{Time: refPlots[i].Time, Value: plot.Value(value)}
+		}
+
+		return series
+	}
+
+	if sample < 2 {
+		sample = 2
+	}
+
+	series.Plots = make([]plot.Plot, sample)
+
+	step := endTime.Sub(startTime) / time.Duration(sample-1)
+
+	for i := range series.Plots {
+		series.Plots[i] = plot.Plot{Time: startTime.Add(time.Duration(i) * step), Value: plot.Value(value)}
+	}
+
+	return series
+}
+
+func hasTransform(query *plot.QueryGroup) bool {
+	for _, series := range query.Series {
+		if transform, _ := config.GetString(series.Options, "transform", false); transform != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (server *Server) servePlotsCSV(writer http.ResponseWriter, response *PlotResponse) {
+	writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", response.Name+".csv"))
+	writer.WriteHeader(http.StatusOK)
+
+	if err := writePlotsCSV(writer, response); err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+	}
+}
+
+// writePlotsCSV writes the plots of a response as CSV records, one column per series.
+func writePlotsCSV(writer io.Writer, response *PlotResponse) error {
+	names, times, rows := tabulatePlots(response)
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write(append([]string{"time"}, names...))
+
+	for _, timestamp := range times {
+		record := make([]string, len(names)+1)
+		record[0] = time.Unix(timestamp, 0).Format(time.RFC3339)
+
+		for i, value := range rows[timestamp] {
+			if value != nil && !value.IsNaN() {
+				record[i+1] = strconv.FormatFloat(float64(*value), 'f', -1, 64)
+			}
+		}
+
+		csvWriter.Write(record)
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// requestVariables returns the graph template variables values given in a request as `var-<name>' parameters.
+func requestVariables(request *http.Request) map[string]string {
+	var variables map[string]string
+
+	request.ParseForm()
+
+	for key := range request.Form {
+		if !strings.HasPrefix(key, "var-") {
+			continue
+		}
+
+		if variables == nil {
+			variables = make(map[string]string)
+		}
+
+		variables[strings.TrimPrefix(key, "var-")] = request.Form.Get(key)
+	}
+
+	return variables
+}
+
+func renderGraph(response *PlotResponse) *render.Graph {
+	graph := &render.Graph{}
+
+	if response == nil {
+		return graph
+	}
+
+	graph.Title = response.Name
+	graph.Type = response.Type
+	graph.StackMode = response.StackMode
+	graph.UnitType = response.UnitType
+	graph.UnitLegend = response.UnitLegend
+	graph.StartTime, _ = time.Parse(time.RFC3339, response.Start)
+	graph.EndTime, _ = time.Parse(time.RFC3339, response.End)
+
+	for _, series := range response.Series {
+		color, _ := config.GetString(series.Options, "color", false)
+		constant, _ := config.GetBool(series.Options, "constant", false)
+
+		graph.Series = append(graph.Series, &render.Series{
+			Name:     series.Name,
+			StackID:  series.StackID,
+			Color:    color,
+			Constant: constant,
+			Plots:    series.Plots,
+		})
+	}
+
+	return graph
+}
+
+func flattenPlots(response *PlotResponse) []map[string]interface{} {
+	names, times, rows := tabulatePlots(response)
+
+	result := make([]map[string]interface{}, len(times))
+
+	for index, timestamp := range times {
+		result[index] = map[string]interface{}{"time": time.Unix(timestamp, 0).Format(time.RFC3339)}
+
+		for i, value := range rows[timestamp] {
+			if value != nil {
+				result[index][names[i]] = *value
+			} else {
+				result[index][names[i]] = nil
+			}
+		}
+	}
+
+	return result
+}
+
+// tabulatePlots arranges plot response series into rows indexed by timestamps, one column per series. Columns names
+// are made unique by suffixing duplicate series names with their occurrence number, `time' being reserved.
+func tabulatePlots(response *PlotResponse) ([]string, []int64, map[int64][]*plot.Value) {
+	var times []int64
+
+	names := make([]string, len(response.Series))
+	rows := make(map[int64][]*plot.Value)
+
+	used := map[string]bool{"time": true}
+
+	for i, series := range response.Series {
+		names[i] = series.Name
+
+		for count := 2; used[names[i]]; count++ {
+			names[i] = fmt.Sprintf("%s (%d)", series.Name, count)
+		}
+
+		used[names[i]] = true
+
+		for j := range series.Plots {
+			timestamp := series.Plots[j].Time.Unix()
+
+			if _, ok := rows[timestamp]; !ok {
+				rows[timestamp] = make([]*plot.Value, len(response.Series))
+				times = append(times, timestamp)
+			}
+
+			rows[timestamp][i] = &series.Plots[j].Value
+		}
+	}
+
+	sort.Sort(int64Slice(times))
+
+	return names, times, rows
 }
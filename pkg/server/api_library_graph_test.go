@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func Test_ConstantsSeries(test *testing.T) {
+	startTime := time.Unix(0, 0)
+	endTime := startTime.Add(4 * time.Minute)
+
+	refPlots := []plot.Plot{
+		{Time: startTime.Add(time.Minute), Value: 1},
+		{Time: startTime.Add(2 * time.Minute), Value: 2},
+		{Time: startTime.Add(3 * time.Minute), Value: 3},
+	}
+
+	graphConstants := []*library.Constant{{Label: "max", Value: 100, Color: "#ff0000"}}
+
+	plotReq := &PlotRequest{Constants: []float64{0.5}, Sample: 5}
+
+	for _, entry := range []struct {
+		RefPlots []plot.Plot
+		Times    []time.Time
+	}{
+		{refPlots, []time.Time{refPlots[0].Time, refPlots[1].Time, refPlots[2].Time}},
+		{nil, []time.Time{startTime, startTime.Add(time.Minute), startTime.Add(2 * time.Minute),
+			startTime.Add(3 * time.Minute), endTime}},
+	} {
+		result := constantsSeries(graphConstants, plotReq, entry.RefPlots, startTime, endTime)
+
+		if len(result) != 2 {
+			test.Fatalf("\nExpected %d series\nbut got  %d", 2, len(result))
+		}
+
+		for i, expected := range []struct {
+			Name    string
+			Value   plot.Value
+			Options map[string]interface{}
+		}{
+			{"max", 100, map[string]interface{}{"constant": true, "color": "#ff0000"}},
+			{"0.5", 0.5, map[string]interface{}{"constant": true}},
+		} {
+			series := result[i]
+
+			if series.Name != expected.Name {
+				test.Logf("\nExpected %q\nbut got  %q", expected.Name, series.Name)
+				test.Fail()
+			}
+
+			if !reflect.DeepEqual(series.Options, expected.Options) {
+				test.Logf("\nExpected %v\nbut got  %v", expected.Options, series.Options)
+				test.Fail()
+			}
+
+			if series.Summary["avg"] != expected.Value {
+				test.Logf("\nExpected %v\nbut got  %v", expected.Value, series.Summary["avg"])
+				test.Fail()
+			}
+
+			if len(series.Plots) != len(entry.Times) {
+				test.Logf("\nExpected %d plots\nbut got  %d", len(entry.Times), len(series.Plots))
+				test.Fail()
+				continue
+			}
+
+			for j, plotItem := range series.Plots {
+				if plotItem.Value != expected.Value || !plotItem.Time.Equal(entry.Times[j]) {
+					test.Logf("\nExpected %v at %s\nbut got  %v at %s", expected.Value, entry.Times[j],
+						plotItem.Value, plotItem.Time)
+					test.Fail()
+				}
+			}
+		}
+	}
+}
+
+func Test_FlattenPlots(test *testing.T) {
+	result := flattenPlots(testPlotResponse())
+
+	expected := []map[string]interface{}{
+		{"time": time.Unix(60, 0).Format(time.RFC3339), "a": plot.Value(1), "a (2)": plot.Value(3), "time (2)": nil},
+		{"time": time.Unix(120, 0).Format(time.RFC3339), "a": plot.Value(2), "a (2)": nil, "time (2)": plot.Value(5)},
+	}
+
+	if len(result) != len(expected) {
+		test.Fatalf("\nExpected %v\nbut got  %v", expected, result)
+	}
+
+	for i := range expected {
+		// NaN values cannot be compared, thus replace them before comparison
+		if value, ok := result[i]["a (2)"].(plot.Value); ok && value.IsNaN() {
+			result[i]["a (2)"] = nil
+		}
+
+		if !reflect.DeepEqual(result[i], expected[i]) {
+			test.Logf("\nExpected %v\nbut got  %v", expected[i], result[i])
+			test.Fail()
+		}
+	}
+}
+
+func Test_WritePlotsCSV(test *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+
+	if err := writePlotsCSV(buffer, testPlotResponse()); err != nil {
+		test.Fatalf("unable to write CSV: %s", err)
+	}
+
+	expected := "time,a,a (2),time (2)\n" +
+		time.Unix(60, 0).Format(time.RFC3339) + ",1,3,\n" +
+		time.Unix(120, 0).Format(time.RFC3339) + ",2,,5\n"
+
+	if buffer.String() != expected {
+		test.Logf("\nExpected %q\nbut got  %q", expected, buffer.String())
+		test.Fail()
+	}
+}
+
+func testPlotResponse() *PlotResponse {
+	startTime := time.Unix(0, 0).UTC()
+
+	return &PlotResponse{
+		Series: []*SeriesResponse{
+			{Name: "a", Plots: []plot.Plot{
+				{Time: startTime.Add(time.Minute), Value: 1},
+				{Time: startTime.Add(2 * time.Minute), Value: 2},
+			}},
+			{Name: "a", Plots: []plot.Plot{
+				{Time: startTime.Add(time.Minute), Value: 3},
+				{Time: startTime.Add(2 * time.Minute), Value: plot.Value(math.NaN())},
+			}},
+			{Name: "time", Plots: []plot.Plot{
+				{Time: startTime.Add(2 * time.Minute), Value: 5},
+			}},
+		},
+	}
+}
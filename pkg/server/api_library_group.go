@@ -20,9 +20,11 @@ func (server *Server) serveGroup(writer http.ResponseWriter, request *http.Reque
 		groupType int
 	)
 
-	if request.Method != "GET" && request.Method != "HEAD" && server.Config.ReadOnly {
-		server.serveResponse(writer, serverResponse{mesgReadOnlyMode}, http.StatusForbidden)
-		return
+	if request.Method != "GET" && request.Method != "HEAD" {
+		if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
 	}
 
 	if strings.HasPrefix(request.URL.Path, urlLibraryPath+"sourcegroups") {
@@ -40,10 +42,20 @@ func (server *Server) serveGroup(writer http.ResponseWriter, request *http.Reque
 			return
 		}
 
-		err := server.Library.DeleteItem(groupID, groupType)
+		mode, response, status := server.parseDeleteMode(request, groupID, groupType)
+		if status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		err := server.Library.DeleteItem(groupID, groupType, mode)
 		if os.IsNotExist(err) {
 			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
 			return
+		} else if referenceErr, ok := err.(*library.ReferenceError); ok {
+			server.serveResponse(writer, referenceResponse{mesgResourceReferenced, referenceErr.References},
+				http.StatusConflict)
+			return
 		} else if err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
@@ -111,7 +123,7 @@ func (server *Server) serveGroup(writer http.ResponseWriter, request *http.Reque
 		}
 
 		// Store group data
-		err := server.Library.StoreItem(group, groupType)
+		err := server.Library.StoreItem(group, groupType, requestUser(request))
 		if response, status := server.parseError(writer, request, err); status != http.StatusOK {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, response, status)
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+)
+
+var revisionItemTypes = map[string]int{
+	"sourcegroups": library.LibraryItemSourceGroup,
+	"metricgroups": library.LibraryItemMetricGroup,
+	"scales":       library.LibraryItemScale,
+	"units":        library.LibraryItemUnit,
+	"graphs":       library.LibraryItemGraph,
+	"collections":  library.LibraryItemCollection,
+	"annotations":  library.LibraryItemAnnotation,
+}
+
+func (server *Server) serveRevision(writer http.ResponseWriter, request *http.Request) {
+	// Split `<type>/<id>/revisions/<revision>[/restore]' path
+	chunks := strings.SplitN(strings.TrimPrefix(request.URL.Path, urlLibraryPath), "/", 4)
+	if len(chunks) != 4 || chunks[2] != "revisions" {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	itemType, ok := revisionItemTypes[chunks[0]]
+	if !ok {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	itemID, revisionPath := chunks[1], chunks[3]
+
+	switch {
+	case revisionPath == "":
+		server.serveRevisionList(writer, request, itemID, itemType)
+
+	case revisionPath == "diff":
+		server.serveRevisionDiff(writer, request, itemID, itemType)
+
+	case strings.HasSuffix(revisionPath, "/restore"):
+		server.serveRevisionRestore(writer, request, itemID, itemType, strings.TrimSuffix(revisionPath, "/restore"))
+
+	default:
+		if response, status := server.parseShowRequest(writer, request); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		revisionID, err := strconv.Atoi(revisionPath)
+		if err != nil {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		}
+
+		revision, err := server.Library.GetRevision(itemID, itemType, revisionID)
+		if err != nil {
+			server.serveRevisionError(writer, request, err)
+			return
+		}
+
+		server.serveResponse(writer, &RevisionResponse{
+			ID:       revision.ID,
+			Modified: revision.Modified.Format(time.RFC3339),
+			Author:   revision.Author,
+			Data:     revision.Data,
+		}, http.StatusOK)
+	}
+}
+
+func (server *Server) serveRevisionList(writer http.ResponseWriter, request *http.Request, itemID string,
+	itemType int) {
+
+	if response, status := server.parseShowRequest(writer, request); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	revisions, err := server.Library.GetRevisions(itemID, itemType)
+	if err != nil {
+		server.serveRevisionError(writer, request, err)
+		return
+	}
+
+	response := make([]*RevisionResponse, len(revisions))
+
+	for index, revision := range revisions {
+		response[index] = &RevisionResponse{
+			ID:       revision.ID,
+			Modified: revision.Modified.Format(time.RFC3339),
+			Author:   revision.Author,
+		}
+	}
+
+	server.serveResponse(writer, response, http.StatusOK)
+}
+
+func (server *Server) serveRevisionDiff(writer http.ResponseWriter, request *http.Request, itemID string,
+	itemType int) {
+
+	var ids [2]int
+
+	if response, status := server.parseShowRequest(writer, request); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	revisions, err := server.Library.GetRevisions(itemID, itemType)
+	if err != nil {
+		server.serveRevisionError(writer, request, err)
+		return
+	} else if len(revisions) == 0 {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	// Compare the latest revision with its predecessor by default
+	ids[1] = revisions[len(revisions)-1].ID
+	ids[0] = ids[1] - 1
+
+	for index, key := range []string{"from", "to"} {
+		if request.FormValue(key) == "" {
+			continue
+		} else if ids[index], err = strconv.Atoi(request.FormValue(key)); err != nil {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	diff, err := server.Library.DiffRevisions(itemID, itemType, ids[0], ids[1])
+	if err != nil {
+		server.serveRevisionError(writer, request, err)
+		return
+	}
+
+	server.serveResponse(writer, &RevisionDiffResponse{From: ids[0], To: ids[1], Diff: diff}, http.StatusOK)
+}
+
+func (server *Server) serveRevisionRestore(writer http.ResponseWriter, request *http.Request, itemID string,
+	itemType int, revisionPath string) {
+
+	if request.Method != "POST" {
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	revisionID, err := strconv.Atoi(revisionPath)
+	if err != nil {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	revision, err := server.Library.GetRevision(itemID, itemType, revisionID)
+	if err != nil {
+		server.serveRevisionError(writer, request, err)
+		return
+	}
+
+	// Check for collection ownership, falling back on the revision one if the collection has been deleted
+	owner := ""
+	if itemType == library.LibraryItemCollection {
+		if item, err := server.Library.GetItem(itemID, itemType); err == nil {
+			owner = item.(*library.Collection).Owner
+		} else {
+			collection := &library.Collection{}
+			json.Unmarshal(revision.Data, collection)
+			owner = collection.Owner
+		}
+	}
+
+	if response, status := server.parseWriteRequest(request, owner); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	if err := server.Library.RestoreRevision(itemID, itemType, revisionID, requestUser(request)); err != nil {
+		server.serveRevisionError(writer, request, err)
+		return
+	}
+
+	server.serveResponse(writer, nil, http.StatusOK)
+}
+
+func (server *Server) serveRevisionError(writer http.ResponseWriter, request *http.Request, err error) {
+	if os.IsNotExist(err) {
+		err = os.ErrNotExist
+	}
+
+	response, status := server.parseError(writer, request, err)
+	if status == http.StatusInternalServerError {
+		logger.Log(logger.LevelError, "server", "%s", err)
+	}
+
+	server.serveResponse(writer, response, status)
+}
@@ -14,9 +14,11 @@ import (
 )
 
 func (server *Server) serveUnit(writer http.ResponseWriter, request *http.Request) {
-	if request.Method != "GET" && request.Method != "HEAD" && server.Config.ReadOnly {
-		server.serveResponse(writer, serverResponse{mesgReadOnlyMode}, http.StatusForbidden)
-		return
+	if request.Method != "GET" && request.Method != "HEAD" {
+		if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
 	}
 
 	unitID := strings.TrimPrefix(request.URL.Path, urlLibraryPath+"units/")
@@ -28,10 +30,20 @@ func (server *Server) serveUnit(writer http.ResponseWriter, request *http.Reques
 			return
 		}
 
-		err := server.Library.DeleteItem(unitID, library.LibraryItemUnit)
+		mode, response, status := server.parseDeleteMode(request, unitID, library.LibraryItemUnit)
+		if status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		err := server.Library.DeleteItem(unitID, library.LibraryItemUnit, mode)
 		if os.IsNotExist(err) {
 			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
 			return
+		} else if referenceErr, ok := err.(*library.ReferenceError); ok {
+			server.serveResponse(writer, referenceResponse{mesgResourceReferenced, referenceErr.References},
+				http.StatusConflict)
+			return
 		} else if err != nil {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
@@ -99,7 +111,7 @@ func (server *Server) serveUnit(writer http.ResponseWriter, request *http.Reques
 		}
 
 		// Store unit data
-		err := server.Library.StoreItem(unit, library.LibraryItemUnit)
+		err := server.Library.StoreItem(unit, library.LibraryItemUnit, requestUser(request))
 		if response, status := server.parseError(writer, request, err); status != http.StatusOK {
 			logger.Log(logger.LevelError, "server", "%s", err)
 			server.serveResponse(writer, response, status)
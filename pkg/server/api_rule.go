@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (server *Server) serveRules(writer http.ResponseWriter, request *http.Request) {
+	var offset, limit int
+
+	setHTTPCacheHeaders(writer)
+
+	ruleName := strings.TrimPrefix(request.URL.Path, urlRulesPath)
+
+	if ruleName != "" {
+		if request.Method != "GET" && request.Method != "HEAD" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		server.rulesLock.RLock()
+		rule, ok := server.rules[ruleName]
+		server.rulesLock.RUnlock()
+
+		if !ok {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		}
+
+		server.serveResponse(writer, ruleResponse(rule), http.StatusOK)
+		return
+	}
+
+	if response, status := server.parseListRequest(writer, request, &offset, &limit); status != http.StatusOK {
+		server.serveResponse(writer, response, status)
+		return
+	}
+
+	items := make(RuleListResponse, 0)
+
+	for _, rule := range server.getRules() {
+		items = append(items, ruleResponse(rule))
+	}
+
+	response := &listResponse{
+		list:   items,
+		offset: offset,
+		limit:  limit,
+	}
+
+	server.applyResponseLimit(writer, request, response)
+
+	server.serveResponse(writer, response.list, http.StatusOK)
+}
+
+// ruleResponse returns the response of an alerting rule, its state being the most severe one of its series.
+func ruleResponse(rule *rule) *RuleResponse {
+	rule.Lock()
+	defer rule.Unlock()
+
+	// Unknown state is less severe than warning and critical ones
+	severity := map[int]int{
+		ruleStateOK:       0,
+		ruleStateUnknown:  1,
+		ruleStateWarning:  2,
+		ruleStateCritical: 3,
+	}
+
+	state := ruleStateOK
+
+	response := &RuleResponse{
+		Name:   rule.name,
+		Series: make(map[string]*RuleSeriesResponse),
+	}
+
+	if !rule.lastEval.IsZero() {
+		response.LastEval = rule.lastEval.Format(time.RFC3339)
+	}
+
+	for seriesName, seriesState := range rule.states {
+		if severity[seriesState.State] > severity[state] {
+			state = seriesState.State
+		}
+
+		response.Series[seriesName] = &RuleSeriesResponse{
+			State: ruleStateNames[seriesState.State],
+			Value: seriesState.Value,
+			Since: seriesState.Since.Format(time.RFC3339),
+		}
+	}
+
+	response.State = ruleStateNames[state]
+
+	return response
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/utils"
+)
+
+func (server *Server) serveSnapshot(writer http.ResponseWriter, request *http.Request) {
+	snapshotID := strings.TrimPrefix(request.URL.Path, urlSnapshotsPath)
+
+	switch request.Method {
+	case "DELETE":
+		if snapshotID == "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		snapshot, err := server.loadSnapshot(snapshotID)
+		if os.IsNotExist(err) {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		// Only admins are allowed to delete snapshots taken by other users
+		if response, status := server.parseWriteRequest(request, snapshot.Author); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		if err := server.deleteSnapshot(snapshotID); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		server.serveResponse(writer, nil, http.StatusOK)
+
+	case "GET", "HEAD":
+		if snapshotID == "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		snapshot, err := server.loadSnapshot(snapshotID)
+		if os.IsNotExist(err) {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		server.serveResponse(writer, snapshot, http.StatusOK)
+
+	case "POST":
+		if snapshotID != "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		} else if utils.HTTPGetContentType(request) != "application/json" {
+			server.serveResponse(writer, serverResponse{mesgUnsupportedMediaType}, http.StatusUnsupportedMediaType)
+			return
+		} else if response, status := server.parseWriteRequest(request, ""); status != http.StatusOK {
+			server.serveResponse(writer, response, status)
+			return
+		}
+
+		// Parse input JSON for the plot request to freeze
+		body, _ := ioutil.ReadAll(request.Body)
+
+		plotReq := &PlotRequest{}
+
+		if err := json.Unmarshal(body, plotReq); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+
+		response, err := server.getPlots(plotReq)
+		if err == errEmptyData {
+			server.serveResponse(writer, serverResponse{mesgEmptyData}, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			errResponse, status := server.parseError(writer, request, err)
+			if status == http.StatusInternalServerError {
+				logger.Log(logger.LevelError, "server", "%s", err)
+			}
+
+			server.serveResponse(writer, errResponse, status)
+			return
+		}
+
+		snapshot := &SnapshotResponse{
+			Author:  requestUser(request),
+			Request: plotReq,
+			Plots:   response,
+		}
+
+		if err := server.storeSnapshot(snapshot); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+			return
+		}
+
+		writer.Header().Add("Location", strings.TrimRight(request.URL.Path, "/")+"/"+snapshot.ID)
+		server.serveResponse(writer, nil, http.StatusCreated)
+
+	default:
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+	}
+}
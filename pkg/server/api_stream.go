@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/utils"
+)
+
+func (server *Server) serveStreams(writer http.ResponseWriter, request *http.Request) {
+	setHTTPCacheHeaders(writer)
+
+	path := strings.Trim(strings.TrimPrefix(request.URL.Path, urlStreamsPath), "/")
+
+	switch request.Method {
+	case "GET":
+		if path != "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		server.serveStreamSession(writer, request)
+
+	case "POST":
+		if path == "" || strings.Contains(path, "/") {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		server.serveStreamSubscribe(writer, request, path)
+
+	case "DELETE":
+		chunks := strings.SplitN(path, "/", 2)
+		if len(chunks) != 2 || chunks[1] == "" {
+			server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+			return
+		}
+
+		session := server.getStreamSession(chunks[0])
+		if session == nil || session.user != requestUser(request) {
+			server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+			return
+		}
+
+		if err := server.unsubscribeStream(session, chunks[1]); err != nil {
+			errResponse, status := server.parseError(writer, request, err)
+			server.serveResponse(writer, errResponse, status)
+			return
+		}
+
+		server.serveResponse(writer, nil, http.StatusOK)
+
+	default:
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+	}
+}
+
+func (server *Server) serveStreamSession(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+		return
+	}
+
+	session, err := server.newStreamSession(requestUser(request))
+	if err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgUnhandledError}, http.StatusInternalServerError)
+		return
+	}
+
+	defer server.closeStreamSession(session)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.WriteHeader(http.StatusOK)
+
+	// Send session identifier to be used by the client when subscribing to streams
+	fmt.Fprintf(writer, "event: %s\ndata: {\"id\":%q}\n\n", streamEventSession, session.id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+
+		case <-session.done:
+			return
+
+		case event := <-session.events:
+			fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.name, event.data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (server *Server) serveStreamSubscribe(writer http.ResponseWriter, request *http.Request, sessionID string) {
+	if utils.HTTPGetContentType(request) != "application/json" {
+		server.serveResponse(writer, serverResponse{mesgUnsupportedMediaType}, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	session := server.getStreamSession(sessionID)
+	if session == nil || session.user != requestUser(request) {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	// Parse input JSON for stream subscription
+	body, _ := ioutil.ReadAll(request.Body)
+
+	streamReq := StreamRequest{}
+
+	if err := json.Unmarshal(body, &streamReq); err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	} else if streamReq.ID == "" || streamReq.Request == nil ||
+		streamReq.Request.ID == "" && streamReq.Request.Graph == nil {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	if streamReq.Request.ID != "" && !server.Library.ItemExists(streamReq.Request.ID, library.LibraryItemGraph) {
+		server.serveResponse(writer, serverResponse{mesgResourceNotFound}, http.StatusNotFound)
+		return
+	}
+
+	if streamReq.Interval == 0 {
+		streamReq.Interval = defaultStreamInterval
+	}
+
+	// Subscribe to stream, identical plot requests sharing the same connectors polling
+	err := server.subscribeStream(session, streamReq.ID, streamReq.Request, streamReq.Interval)
+	if err == errTooManyStreams {
+		server.serveResponse(writer, serverResponse{mesgTooManyStreams}, http.StatusTooManyRequests)
+		return
+	} else if err != nil {
+		errResponse, status := server.parseError(writer, request, err)
+		if status == http.StatusInternalServerError {
+			logger.Log(logger.LevelError, "server", "%s", err)
+		}
+
+		server.serveResponse(writer, errResponse, status)
+		return
+	}
+
+	server.serveResponse(writer, nil, http.StatusOK)
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/facette/facette/pkg/auth"
+	"github.com/facette/facette/pkg/library"
 	"github.com/facette/facette/pkg/utils"
 )
 
@@ -40,6 +42,35 @@ func (server *Server) parseError(writer http.ResponseWriter, request *http.Reque
 	return nil, http.StatusOK
 }
 
+// parseDeleteMode returns the library deletion mode of a request, checking in case of cascading deletion that the
+// user is allowed to modify the affected collections.
+func (server *Server) parseDeleteMode(request *http.Request, id string, itemType int) (int, *serverResponse, int) {
+	if force, _ := strconv.ParseBool(request.FormValue("force")); force {
+		return library.DeleteModeForce, nil, http.StatusOK
+	} else if cascade, _ := strconv.ParseBool(request.FormValue("cascade")); !cascade {
+		return library.DeleteModeStrict, nil, http.StatusOK
+	}
+
+	references := server.Library.GetReferences(id, itemType)
+
+	// Graphs referencing a group are deleted along with it
+	if itemType == library.LibraryItemSourceGroup || itemType == library.LibraryItemMetricGroup {
+		for _, reference := range references {
+			references = append(references, server.Library.GetReferences(reference.ID, library.LibraryItemGraph)...)
+		}
+	}
+
+	for _, reference := range references {
+		if collection, ok := server.Library.Collections[reference.ID]; ok {
+			if response, status := server.parseWriteRequest(request, collection.Owner); status != http.StatusOK {
+				return library.DeleteModeStrict, response, status
+			}
+		}
+	}
+
+	return library.DeleteModeCascade, nil, http.StatusOK
+}
+
 func (server *Server) parseListRequest(writer http.ResponseWriter, request *http.Request,
 	offset, limit *int) (*serverResponse, int) {
 
@@ -64,6 +95,20 @@ func (server *Server) parseListRequest(writer http.ResponseWriter, request *http
 	return nil, http.StatusOK
 }
 
+func (server *Server) parseWriteRequest(request *http.Request, owner string) (*serverResponse, int) {
+	if server.Config.ReadOnly {
+		return &serverResponse{mesgReadOnlyMode}, http.StatusForbidden
+	}
+
+	// Only admins are allowed to modify items owned by other users
+	role := server.requestRole(request)
+	if role < auth.RoleEditor || role < auth.RoleAdmin && owner != "" && owner != requestUser(request) {
+		return &serverResponse{mesgPermissionDenied}, http.StatusForbidden
+	}
+
+	return nil, http.StatusOK
+}
+
 func (server *Server) parseShowRequest(writer http.ResponseWriter, request *http.Request) (*serverResponse, int) {
 	return server.parseListRequest(writer, request, nil, nil)
 }
@@ -87,3 +132,24 @@ func setHTTPCacheHeaders(writer http.ResponseWriter) {
 	writer.Header().Set("Date", date)
 	writer.Header().Set("Expires", date)
 }
+
+// requestRole returns the role of the user performing a request, every request being granted the admin role if
+// authentication is disabled.
+func (server *Server) requestRole(request *http.Request) int {
+	if server.authenticator == nil {
+		return auth.RoleAdmin
+	}
+
+	user := requestUser(request)
+	if user == "" {
+		return auth.RoleViewer
+	} else if role, ok := server.roles[user]; ok {
+		return role
+	}
+
+	return server.defaultRole
+}
+
+func (server *Server) isReadOnly(request *http.Request) bool {
+	return server.Config.ReadOnly || server.requestRole(request) < auth.RoleEditor
+}
@@ -1,17 +1,22 @@
 package server
 
 const (
-	mesgDisabledCommand      string = "Command administratively disabled"
-	mesgEmptyData            string = "No data"
-	mesgFormLimitInvalid     string = "Request limit must be an integer"
-	mesgFormOffsetInvalid    string = "Request offset must be an integer"
-	mesgFormOffsetOutOfRange string = "Request offset is out of range"
-	mesgMethodNotAllowed     string = "Request method is not allowed"
-	mesgReadOnlyMode         string = "Instance is read-only"
-	mesgResourceConflict     string = "A resource conflict has occured"
-	mesgResourceInvalid      string = "Resource is invalid"
-	mesgResourceNotFound     string = "Unable to find requested resource"
-	mesgServiceLoading       string = "Service is loading"
-	mesgUnhandledError       string = "An unhandled error has occured"
-	mesgUnsupportedMediaType string = "Provided media type is not supported"
+	mesgAuthenticationRequired string = "Authentication is required"
+	mesgDisabledCommand        string = "Command administratively disabled"
+	mesgEmptyData              string = "No data"
+	mesgFormLimitInvalid       string = "Request limit must be an integer"
+	mesgFormOffsetInvalid      string = "Request offset must be an integer"
+	mesgFormOffsetOutOfRange   string = "Request offset is out of range"
+	mesgMethodNotAllowed       string = "Request method is not allowed"
+	mesgPermissionDenied       string = "Permission denied"
+	mesgReadOnlyMode           string = "Instance is read-only"
+	mesgResourceConflict       string = "A resource conflict has occured"
+	mesgResourceInvalid        string = "Resource is invalid"
+	mesgResourceNotFound       string = "Unable to find requested resource"
+	mesgResourceReferenced     string = "Resource is still referenced by other resources"
+	mesgServiceLoading         string = "Service is loading"
+	mesgTooManySeries          string = "Too many series match the requested targets"
+	mesgTooManyStreams         string = "Too many plots streams are running"
+	mesgUnhandledError         string = "An unhandled error has occured"
+	mesgUnsupportedMediaType   string = "Provided media type is not supported"
 )
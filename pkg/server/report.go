@@ -0,0 +1,295 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/plot"
+	"github.com/facette/facette/pkg/render"
+	"github.com/facette/facette/pkg/utils"
+)
+
+const (
+	reportFormatCSV string = "csv"
+
+	defaultReportRange   string = "-1w"
+	defaultReportTimeout        = 30 * time.Second
+
+	reportSummaryFile string = "summary.json"
+)
+
+var reportFileRegexp = regexp.MustCompile("[^A-Za-z0-9_.-]+")
+
+// report represents a scheduled report along with its next run time.
+type report struct {
+	name     string
+	config   *config.ReportConfig
+	schedule *utils.CronSchedule
+	next     time.Time
+}
+
+// reportFile represents a file generated by a report run.
+type reportFile struct {
+	name string
+	data []byte
+}
+
+// reportSummary represents the summary of a report graph, stored along with the generated files.
+type reportSummary struct {
+	Title  string                           `json:"title"`
+	Graph  string                           `json:"graph"`
+	File   string                           `json:"file"`
+	Start  string                           `json:"start"`
+	End    string                           `json:"end"`
+	Series map[string]map[string]plot.Value `json:"series"`
+}
+
+// newReport creates a new scheduled report instance, checking its definition validity.
+func newReport(name string, reportConfig *config.ReportConfig) (*report, error) {
+	if reportConfig.Collection == "" {
+		return nil, fmt.Errorf("missing collection")
+	} else if reportConfig.OutputDir == "" && reportConfig.Webhook == "" {
+		return nil, fmt.Errorf("either an output directory or a webhook must be set")
+	}
+
+	if reportConfig.Range == "" {
+		reportConfig.Range = defaultReportRange
+	}
+
+	if reportConfig.Format == "" {
+		reportConfig.Format = render.FormatPNG
+	} else if reportConfig.Format != render.FormatPNG && reportConfig.Format != render.FormatSVG &&
+		reportConfig.Format != reportFormatCSV {
+		return nil, fmt.Errorf("unknown format `%s'", reportConfig.Format)
+	}
+
+	schedule, err := utils.ParseCronSchedule(reportConfig.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return nil, fmt.Errorf("schedule `%s' never matches", reportConfig.Schedule)
+	}
+
+	return &report{
+		name:     name,
+		config:   reportConfig,
+		schedule: schedule,
+		next:     next,
+	}, nil
+}
+
+func (server *Server) initReports() {
+	reports := make(map[string]*report)
+
+	server.reportsLock.Lock()
+	defer server.reportsLock.Unlock()
+
+	for reportName, reportConfig := range server.Config.Reports {
+		item, err := newReport(reportName, reportConfig)
+		if err != nil {
+			logger.Log(logger.LevelWarning, "server", "in report `%s', %s", reportName, err)
+			logger.Log(logger.LevelWarning, "server", "discarding report `%s'", reportName)
+			continue
+		}
+
+		logger.Log(logger.LevelDebug, "server", "declared report `%s', next run at %s", reportName,
+			item.next.Format(time.RFC3339))
+
+		// Keep unchanged reports as is, not to reset their next run time
+		if current, ok := server.reports[reportName]; ok && reflect.DeepEqual(current.config, item.config) {
+			item = current
+		}
+
+		reports[reportName] = item
+	}
+
+	server.reports = reports
+}
+
+// getReports returns the list of declared scheduled reports.
+func (server *Server) getReports() []*report {
+	server.reportsLock.RLock()
+	defer server.reportsLock.RUnlock()
+
+	reports := make([]*report, 0, len(server.reports))
+	for _, item := range server.reports {
+		reports = append(reports, item)
+	}
+
+	return reports
+}
+
+// runReport renders the graphs of a report collection for the report time range ending at a given time, and
+// dispatches the generated files along with their summaries.
+func (server *Server) runReport(report *report, refTime time.Time) error {
+	item, err := server.Library.GetItem(report.config.Collection, library.LibraryItemCollection)
+	if err != nil {
+		return fmt.Errorf("unable to get collection `%s': %s", report.config.Collection, err)
+	}
+
+	collection := server.Library.FilterCollection(server.Library.ExpandCollection(item.(*library.Collection)), "")
+
+	files := []*reportFile{}
+	summaries := []*reportSummary{}
+
+	for index, entry := range collection.Entries {
+		plotReq := &PlotRequest{
+			ID:        entry.ID,
+			Time:      refTime.Format(time.RFC3339),
+			Range:     report.config.Range,
+			Variables: entryVariables(entry.Options),
+		}
+
+		response, err := server.getPlots(plotReq)
+		if err == errEmptyData {
+			continue
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "report `%s': unable to get graph `%s' plots: %s", report.name,
+				entry.ID, err)
+			continue
+		}
+
+		title, _ := config.GetString(entry.Options, "title", false)
+		if title == "" {
+			title = response.Name
+		}
+
+		buffer := bytes.NewBuffer(nil)
+
+		if report.config.Format == reportFormatCSV {
+			err = writePlotsCSV(buffer, response)
+		} else {
+			graph := renderGraph(response)
+			graph.Title = title
+
+			err = render.Render(buffer, graph, report.config.Format, report.config.Width, report.config.Height)
+		}
+
+		if err != nil {
+			logger.Log(logger.LevelError, "server", "report `%s': unable to render graph `%s': %s", report.name,
+				entry.ID, err)
+			continue
+		}
+
+		fileName := fmt.Sprintf("%02d-%s.%s", index+1, reportFileRegexp.ReplaceAllString(title, "_"),
+			report.config.Format)
+
+		summary := &reportSummary{
+			Title:  title,
+			Graph:  entry.ID,
+			File:   fileName,
+			Start:  response.Start,
+			End:    response.End,
+			Series: make(map[string]map[string]plot.Value),
+		}
+
+		for _, series := range response.Series {
+			summary.Series[series.Name] = series.Summary
+		}
+
+		files = append(files, &reportFile{name: summary.File, data: buffer.Bytes()})
+		summaries = append(summaries, summary)
+	}
+
+	data, err := json.MarshalIndent(summaries, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	files = append(files, &reportFile{name: reportSummaryFile, data: data})
+
+	if report.config.OutputDir != "" {
+		if err := writeReportFiles(path.Join(report.config.OutputDir, report.name, refTime.Format("20060102-150405")),
+			files); err != nil {
+			return err
+		}
+	}
+
+	if report.config.Webhook != "" {
+		if err := postReportFiles(report.config.Webhook, report.name, files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryVariables returns the graph template variables values set in collection entry options.
+func entryVariables(options map[string]interface{}) map[string]string {
+	variables, ok := options["variables"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]string)
+
+	for key, value := range variables {
+		result[key] = fmt.Sprintf("%v", value)
+	}
+
+	return result
+}
+
+func writeReportFiles(dirPath string, files []*reportFile) error {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := ioutil.WriteFile(path.Join(dirPath, file.name), file.data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func postReportFiles(url, name string, files []*reportFile) error {
+	body := bytes.NewBuffer(nil)
+
+	multipartWriter := multipart.NewWriter(body)
+	multipartWriter.WriteField("report", name)
+
+	for _, file := range files {
+		part, err := multipartWriter.CreateFormFile("files", file.name)
+		if err != nil {
+			return err
+		}
+
+		part.Write(file.data)
+	}
+
+	if err := multipartWriter.Close(); err != nil {
+		return err
+	}
+
+	httpClient := http.Client{Timeout: defaultReportTimeout}
+
+	response, err := httpClient.Post(url, multipartWriter.FormDataContentType(), body)
+	if err != nil {
+		return fmt.Errorf("unable to call webhook `%s': %s", url, err)
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook `%s' returned %s", url, response.Status)
+	}
+
+	return nil
+}
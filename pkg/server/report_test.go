@@ -0,0 +1,80 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/facette/facette/pkg/config"
+)
+
+func Test_NewReport(test *testing.T) {
+	for _, entry := range []struct {
+		Config config.ReportConfig
+		Error  bool
+	}{
+		{config.ReportConfig{Collection: "collection1", Schedule: "0 8 * * *", OutputDir: "/tmp"}, false},
+		{config.ReportConfig{Collection: "collection1", Schedule: "@weekly", Webhook: "http://localhost/"}, false},
+		{config.ReportConfig{Schedule: "0 8 * * *", OutputDir: "/tmp"}, true},
+		{config.ReportConfig{Collection: "collection1", Schedule: "0 8 * * *"}, true},
+		{config.ReportConfig{Collection: "collection1", Schedule: "0 8 * *", OutputDir: "/tmp"}, true},
+		{config.ReportConfig{Collection: "collection1", Schedule: "0 0 31 2 *", OutputDir: "/tmp"}, true},
+		{config.ReportConfig{Collection: "collection1", Schedule: "0 8 * * *", OutputDir: "/tmp", Format: "gif"},
+			true},
+	} {
+		reportConfig := entry.Config
+
+		result, err := newReport("report1", &reportConfig)
+		if entry.Error {
+			if err == nil {
+				test.Logf("\nExpected error for report %+v", entry.Config)
+				test.Fail()
+			}
+
+			continue
+		} else if err != nil {
+			test.Logf("\nUnexpected error for report %+v: %s", entry.Config, err)
+			test.Fail()
+			continue
+		}
+
+		if result.next.IsZero() {
+			test.Logf("\nExpected next report time to be set for schedule %q", entry.Config.Schedule)
+			test.Fail()
+		}
+	}
+}
+
+func Test_InitReports(test *testing.T) {
+	server := NewServer("", "", 0)
+	server.Config = &config.Config{Reports: map[string]*config.ReportConfig{
+		"report1": {Collection: "collection1", Schedule: "0 8 * * *", OutputDir: "/tmp"},
+		"report2": {Collection: "collection2", Schedule: "0 8 * * *", OutputDir: "/tmp"},
+	}}
+
+	server.initReports()
+
+	report1, report2 := server.reports["report1"], server.reports["report2"]
+
+	// Reload definitions, unchanged reports being kept along with their next run time
+	server.Config.Reports = map[string]*config.ReportConfig{
+		"report1": {Collection: "collection1", Schedule: "0 8 * * *", OutputDir: "/tmp"},
+		"report2": {Collection: "collection2", Schedule: "0 9 * * *", OutputDir: "/tmp"},
+		"report3": {Collection: "collection3", Schedule: "0 8 * * *"},
+	}
+
+	server.initReports()
+
+	if len(server.getReports()) != 2 {
+		test.Logf("\nExpected %d reports\nbut got  %d", 2, len(server.getReports()))
+		test.Fail()
+	}
+
+	if server.reports["report1"] != report1 {
+		test.Logf("\nExpected unchanged report `report1' to be kept")
+		test.Fail()
+	}
+
+	if server.reports["report2"] == report2 || server.reports["report2"].config.Schedule != "0 9 * * *" {
+		test.Logf("\nExpected changed report `report2' to be replaced")
+		test.Fail()
+	}
+}
@@ -1,12 +1,19 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/facette/facette/pkg/logger"
 )
 
+type contextKey int
+
+const (
+	contextKeyUser contextKey = iota
+)
+
 // ResponseWriter represents the structure of an HTTP response writer handling logged output.
 type ResponseWriter struct {
 	http.ResponseWriter
@@ -17,10 +24,22 @@ type ResponseWriter struct {
 func (writer ResponseWriter) WriteHeader(status int) {
 	writer.ResponseWriter.WriteHeader(status)
 
-	logger.Log(logger.LevelDebug, "serveWorker", "\"%s %s %s\" %d", writer.request.Method, writer.request.URL,
+	user := requestUser(writer.request)
+	if user == "" {
+		user = "-"
+	}
+
+	logger.Log(logger.LevelDebug, "serveWorker", "%s \"%s %s %s\" %d", user, writer.request.Method, writer.request.URL,
 		writer.request.Proto, status)
 }
 
+// Flush sends any buffered data to the client, if supported by the underlying response writer.
+func (writer ResponseWriter) Flush() {
+	if flusher, ok := writer.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Router represents the structure of an HTTP requests router.
 type Router struct {
 	*http.ServeMux
@@ -41,5 +60,45 @@ func (router *Router) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 		request.URL.Path = strings.TrimPrefix(request.URL.Path, router.server.Config.URLPrefix)
 	}
 
+	// Authenticate request if enabled, static files being always served
+	if router.server.authenticator != nil && !strings.HasPrefix(request.URL.Path, urlStaticPath) {
+		user, ok := router.server.authenticator.Authenticate(request)
+		if !ok && (!router.server.Config.Auth.AnonymousRead || !isReadRequest(request)) {
+			router.server.authenticator.Challenge(writer)
+			router.server.serveResponse(ResponseWriter{writer, request}, serverResponse{mesgAuthenticationRequired},
+				http.StatusUnauthorized)
+			return
+		}
+
+		request = request.WithContext(context.WithValue(request.Context(), contextKeyUser, user))
+	}
+
 	router.ServeMux.ServeHTTP(ResponseWriter{writer, request}, request)
 }
+
+// isReadRequest returns whether or not a request only reads data, plots requests and plots streams subscriptions
+// being considered as such.
+func isReadRequest(request *http.Request) bool {
+	streamPath := strings.Trim(strings.TrimPrefix(request.URL.Path, urlStreamsPath), "/")
+	isStreamRequest := strings.HasPrefix(request.URL.Path, urlStreamsPath) && streamPath != ""
+
+	switch request.Method {
+	case "GET", "HEAD":
+		return true
+
+	case "POST":
+		return request.URL.Path == urlLibraryPath+"graphs/plots" || request.URL.Path == urlRenderPath ||
+			isStreamRequest && !strings.Contains(streamPath, "/")
+
+	case "DELETE":
+		return isStreamRequest && strings.Count(streamPath, "/") == 1
+	}
+
+	return false
+}
+
+// requestUser returns the name of the user authenticated for a request, if any.
+func requestUser(request *http.Request) string {
+	user, _ := request.Context().Value(contextKeyUser).(string)
+	return user
+}
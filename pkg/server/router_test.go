@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_IsReadRequest(test *testing.T) {
+	for _, entry := range []struct {
+		Method string
+		Path   string
+		Result bool
+	}{
+		{"GET", urlLibraryPath + "graphs/", true},
+		{"HEAD", urlLibraryPath + "graphs/", true},
+		{"POST", urlLibraryPath + "graphs/plots", true},
+		{"POST", urlRenderPath, true},
+		{"POST", urlLibraryPath + "graphs/", false},
+		{"PUT", urlLibraryPath + "graphs/graph1", false},
+		{"DELETE", urlLibraryPath + "graphs/graph1", false},
+		{"POST", urlStreamsPath + "session1", true},
+		{"POST", urlStreamsPath, false},
+		{"POST", urlStreamsPath + "session1/stream1", false},
+		{"DELETE", urlStreamsPath + "session1/stream1", true},
+		{"DELETE", urlStreamsPath + "session1", false},
+		{"DELETE", urlStreamsPath, false},
+	} {
+		request, _ := http.NewRequest(entry.Method, entry.Path, nil)
+
+		if result := isReadRequest(request); result != entry.Result {
+			test.Logf("\nExpected %v\nbut got  %v for %s %s", entry.Result, result, entry.Method, entry.Path)
+			test.Fail()
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/connector"
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/plot"
+)
+
+const (
+	ruleStateOK = iota
+	ruleStateWarning
+	ruleStateCritical
+	ruleStateUnknown
+)
+
+const (
+	ruleOperatorAbove string = ">"
+	ruleOperatorBelow string = "<"
+
+	defaultRuleInterval int    = 60
+	defaultRuleRange    string = "-5m"
+	defaultRuleSummary  string = "avg"
+	defaultRuleTimeout         = 10 * time.Second
+
+	ruleStateExpireIntervals int = 10
+)
+
+var ruleStateNames = map[int]string{
+	ruleStateOK:       "ok",
+	ruleStateWarning:  "warning",
+	ruleStateCritical: "critical",
+	ruleStateUnknown:  "unknown",
+}
+
+// rule represents an alerting rule along with the last known states of its evaluated series.
+type rule struct {
+	name       string
+	config     *config.RuleConfig
+	summary    string
+	percentile float64
+	states     map[string]*ruleSeriesState
+	lastEval   time.Time
+	sync.Mutex
+}
+
+// ruleSeriesState represents the state of a series evaluated by an alerting rule.
+type ruleSeriesState struct {
+	State int
+	Value plot.Value
+	Since time.Time
+}
+
+// ruleNotification represents the payload sent to webhooks and exec hooks on rule series state changes.
+type ruleNotification struct {
+	Rule      string     `json:"rule"`
+	Series    string     `json:"series"`
+	State     string     `json:"state"`
+	Previous  string     `json:"previous"`
+	Summary   string     `json:"summary"`
+	Value     plot.Value `json:"value"`
+	Threshold *float64   `json:"threshold,omitempty"`
+	Time      time.Time  `json:"time"`
+}
+
+// newRule creates a new alerting rule instance, checking its definition validity.
+func newRule(name string, ruleConfig *config.RuleConfig) (*rule, error) {
+	var err error
+
+	if ruleConfig.Graph == "" && (ruleConfig.Origin == "" || ruleConfig.Source == "" || ruleConfig.Metric == "") {
+		return nil, fmt.Errorf("either a graph or an origin, source and metric must be set")
+	} else if ruleConfig.Warning == nil && ruleConfig.Critical == nil {
+		return nil, fmt.Errorf("no threshold defined")
+	}
+
+	if ruleConfig.Interval == 0 {
+		ruleConfig.Interval = defaultRuleInterval
+	} else if ruleConfig.Interval < 0 {
+		return nil, fmt.Errorf("invalid interval `%d'", ruleConfig.Interval)
+	}
+
+	if ruleConfig.Range == "" {
+		ruleConfig.Range = defaultRuleRange
+	}
+
+	if ruleConfig.Operator == "" {
+		ruleConfig.Operator = ruleOperatorAbove
+	} else if ruleConfig.Operator != ruleOperatorAbove && ruleConfig.Operator != ruleOperatorBelow {
+		return nil, fmt.Errorf("unknown operator `%s'", ruleConfig.Operator)
+	}
+
+	result := &rule{
+		name:    name,
+		config:  ruleConfig,
+		summary: ruleConfig.Summary,
+		states:  make(map[string]*ruleSeriesState),
+	}
+
+	// Handle percentile summaries (e.g. `p95' being mapped to the `95th' summary value)
+	if result.summary == "" {
+		result.summary = defaultRuleSummary
+	} else if strings.HasPrefix(result.summary, "p") {
+		if result.percentile, err = strconv.ParseFloat(strings.TrimPrefix(result.summary, "p"), 64); err != nil ||
+			result.percentile <= 0 || result.percentile > 100 {
+			return nil, fmt.Errorf("invalid summary `%s'", result.summary)
+		}
+
+		result.summary = fmt.Sprintf("%gth", result.percentile)
+	} else if result.summary != "avg" && result.summary != "min" && result.summary != "max" &&
+		result.summary != "last" {
+		return nil, fmt.Errorf("unknown summary `%s'", result.summary)
+	}
+
+	return result, nil
+}
+
+// check returns the state matching a summary value according to the rule thresholds.
+func (rule *rule) check(value plot.Value) int {
+	if value.IsNaN() {
+		return ruleStateUnknown
+	}
+
+	exceeds := func(threshold *float64) bool {
+		if threshold == nil {
+			return false
+		} else if rule.config.Operator == ruleOperatorBelow {
+			return float64(value) < *threshold
+		}
+
+		return float64(value) > *threshold
+	}
+
+	if exceeds(rule.config.Critical) {
+		return ruleStateCritical
+	} else if exceeds(rule.config.Warning) {
+		return ruleStateWarning
+	}
+
+	return ruleStateOK
+}
+
+// threshold returns the threshold value associated with a state, if any.
+func (rule *rule) threshold(state int) *float64 {
+	switch state {
+	case ruleStateCritical:
+		return rule.config.Critical
+
+	case ruleStateWarning:
+		return rule.config.Warning
+	}
+
+	return nil
+}
+
+// initRules declares the alerting rules defined in the configuration, keeping the series states of the rules whose
+// definition did not change since the last call.
+func (server *Server) initRules() {
+	rules := make(map[string]*rule)
+
+	server.rulesLock.Lock()
+	defer server.rulesLock.Unlock()
+
+	for ruleName, ruleConfig := range server.Config.Rules {
+		item, err := newRule(ruleName, ruleConfig)
+		if err != nil {
+			logger.Log(logger.LevelWarning, "server", "in rule `%s', %s", ruleName, err)
+			logger.Log(logger.LevelWarning, "server", "discarding rule `%s'", ruleName)
+			continue
+		}
+
+		if current, ok := server.rules[ruleName]; ok && reflect.DeepEqual(current.config, item.config) {
+			item = current
+		}
+
+		rules[ruleName] = item
+
+		logger.Log(logger.LevelDebug, "server", "declared rule `%s'", ruleName)
+	}
+
+	server.rules = rules
+}
+
+// getRules returns the list of declared alerting rules.
+func (server *Server) getRules() []*rule {
+	server.rulesLock.RLock()
+	defer server.rulesLock.RUnlock()
+
+	rules := make([]*rule, 0, len(server.rules))
+	for _, item := range server.rules {
+		rules = append(rules, item)
+	}
+
+	return rules
+}
+
+// evalRule evaluates an alerting rule, retrieving its series plots the same way graphs plots are, and notifies the
+// series state changes.
+func (server *Server) evalRule(rule *rule) {
+	now := time.Now()
+
+	rule.Lock()
+	rule.lastEval = now
+	rule.Unlock()
+
+	// Rule definition being immutable, plots are retrieved without holding the lock as backends may be slow to reply
+	plotReq := &PlotRequest{
+		Range:     rule.config.Range,
+		ID:        rule.config.Graph,
+		Variables: rule.config.Variables,
+	}
+
+	if rule.percentile > 0 {
+		plotReq.Percentiles = []float64{rule.percentile}
+	}
+
+	if rule.config.Graph == "" {
+		plotReq.Graph = &library.Graph{
+			Item: library.Item{Name: rule.name},
+			Groups: []*library.OperGroup{{
+				Name: rule.name,
+				Type: connector.OperGroupTypeNone,
+				Series: []*library.Series{{
+					Name:   rule.name,
+					Origin: rule.config.Origin,
+					Source: rule.config.Source,
+					Metric: rule.config.Metric,
+				}},
+			}},
+		}
+	}
+
+	response, err := server.getPlots(plotReq)
+	if err != nil && err != errEmptyData {
+		logger.Log(logger.LevelError, "server", "rule `%s': unable to get plots: %s", rule.name, err)
+		return
+	}
+
+	values := make(map[string]plot.Value)
+
+	if response != nil {
+		for _, series := range response.Series {
+			if constant, ok := series.Options["constant"].(bool); ok && constant {
+				continue
+			} else if rule.config.Series != "" && series.Name != rule.config.Series {
+				continue
+			}
+
+			value, ok := series.Summary[rule.summary]
+			if !ok {
+				value = plot.Value(math.NaN())
+			}
+
+			values[series.Name] = value
+		}
+	}
+
+	rule.Lock()
+	notifications := rule.update(values, now)
+	rule.Unlock()
+
+	for _, notification := range notifications {
+		notifyRule(rule, notification)
+	}
+}
+
+// update updates the rule series states with their last summary values, returning the notifications of the series
+// whose state changed.
+func (rule *rule) update(values map[string]plot.Value, now time.Time) []*ruleNotification {
+	var notifications []*ruleNotification
+
+	// Series no longer returned are considered as unknown, their state being discarded once unknown for a while
+	expire := time.Duration(rule.config.Interval*ruleStateExpireIntervals) * time.Second
+
+	for seriesName, seriesState := range rule.states {
+		if _, ok := values[seriesName]; ok {
+			continue
+		} else if seriesState.State == ruleStateUnknown && now.Sub(seriesState.Since) >= expire {
+			logger.Log(logger.LevelDebug, "server", "rule `%s': discarding series `%s' state", rule.name, seriesName)
+			delete(rule.states, seriesName)
+			continue
+		}
+
+		values[seriesName] = plot.Value(math.NaN())
+	}
+
+	for seriesName, value := range values {
+		state := rule.check(value)
+
+		previous, ok := rule.states[seriesName]
+		if !ok {
+			previous = &ruleSeriesState{State: ruleStateOK, Since: now}
+		}
+
+		rule.states[seriesName] = &ruleSeriesState{State: state, Value: value, Since: previous.Since}
+
+		if state == previous.State {
+			continue
+		}
+
+		rule.states[seriesName].Since = now
+
+		logger.Log(logger.LevelInfo, "server", "rule `%s': series `%s' state changed from %s to %s", rule.name,
+			seriesName, ruleStateNames[previous.State], ruleStateNames[state])
+
+		notifications = append(notifications, &ruleNotification{
+			Rule:      rule.name,
+			Series:    seriesName,
+			State:     ruleStateNames[state],
+			Previous:  ruleStateNames[previous.State],
+			Summary:   rule.summary,
+			Value:     value,
+			Threshold: rule.threshold(state),
+			Time:      now,
+		})
+	}
+
+	return notifications
+}
+
+// notifyRule dispatches a rule state change notification to the rule webhooks and exec hook. Each of them is run
+// asynchronously, being cancelled if not completed in time.
+func notifyRule(rule *rule, notification *ruleNotification) {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		logger.Log(logger.LevelError, "server", "rule `%s': unable to marshal notification: %s", rule.name, err)
+		return
+	}
+
+	for _, url := range rule.config.Webhooks {
+		go callRuleWebhook(rule.name, url, data)
+	}
+
+	if rule.config.Exec != "" {
+		go runRuleExec(rule.name, rule.config.Exec, notification, data)
+	}
+}
+
+func callRuleWebhook(ruleName, url string, data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRuleTimeout)
+	defer cancel()
+
+	request, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		logger.Log(logger.LevelError, "server", "rule `%s': unable to call webhook `%s': %s", ruleName, url, err)
+		return
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		logger.Log(logger.LevelError, "server", "rule `%s': unable to call webhook `%s': %s", ruleName, url, err)
+		return
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		logger.Log(logger.LevelError, "server", "rule `%s': webhook `%s' returned %s", ruleName, url,
+			response.Status)
+	}
+}
+
+func runRuleExec(ruleName, command string, notification *ruleNotification, data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRuleTimeout)
+	defer cancel()
+
+	// Pass notification both as environment variables and JSON data on standard input
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"FACETTE_RULE="+notification.Rule,
+		"FACETTE_SERIES="+notification.Series,
+		"FACETTE_STATE="+notification.State,
+		"FACETTE_PREVIOUS_STATE="+notification.Previous,
+		"FACETTE_VALUE="+strconv.FormatFloat(float64(notification.Value), 'g', -1, 64),
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Log(logger.LevelError, "server", "rule `%s': exec hook `%s' failed: %s: %s", ruleName, command, err,
+			strings.TrimSpace(string(output)))
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func Test_NewRule(test *testing.T) {
+	threshold := 10.0
+
+	for _, entry := range []struct {
+		Config  config.RuleConfig
+		Summary string
+		Error   bool
+	}{
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold}, "avg", false},
+		{config.RuleConfig{Origin: "origin1", Source: "source1", Metric: "metric1", Critical: &threshold,
+			Summary: "p95"}, "95th", false},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Summary: "last", Operator: "<"}, "last", false},
+		{config.RuleConfig{Origin: "origin1", Source: "source1", Warning: &threshold}, "", true},
+		{config.RuleConfig{Graph: "graph1"}, "", true},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Interval: -1}, "", true},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Operator: ">="}, "", true},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Summary: "median"}, "", true},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Summary: "p101"}, "", true},
+		{config.RuleConfig{Graph: "graph1", Warning: &threshold, Summary: "pabc"}, "", true},
+	} {
+		ruleConfig := entry.Config
+
+		result, err := newRule("rule1", &ruleConfig)
+		if entry.Error {
+			if err == nil {
+				test.Logf("\nExpected error for rule %+v", entry.Config)
+				test.Fail()
+			}
+
+			continue
+		} else if err != nil {
+			test.Logf("\nExpected %q summary\nbut got  error %s", entry.Summary, err)
+			test.Fail()
+			continue
+		}
+
+		if result.summary != entry.Summary {
+			test.Logf("\nExpected %q\nbut got  %q", entry.Summary, result.summary)
+			test.Fail()
+		}
+
+		if ruleConfig.Interval != defaultRuleInterval || ruleConfig.Range != defaultRuleRange ||
+			ruleConfig.Operator == "" {
+			test.Logf("\nExpected rule defaults to be set\nbut got  %+v", ruleConfig)
+			test.Fail()
+		}
+	}
+}
+
+func Test_RuleCheck(test *testing.T) {
+	warning, critical := 10.0, 20.0
+
+	for _, entry := range []struct {
+		Operator string
+		Warning  *float64
+		Critical *float64
+		Value    plot.Value
+		State    int
+	}{
+		{">", &warning, &critical, 5, ruleStateOK},
+		{">", &warning, &critical, 10, ruleStateOK},
+		{">", &warning, &critical, 15, ruleStateWarning},
+		{">", &warning, &critical, 25, ruleStateCritical},
+		{">", nil, &critical, 15, ruleStateOK},
+		{">", &warning, nil, 25, ruleStateWarning},
+		{">", &warning, &critical, plot.Value(math.NaN()), ruleStateUnknown},
+		{"<", &critical, &warning, 25, ruleStateOK},
+		{"<", &critical, &warning, 15, ruleStateWarning},
+		{"<", &critical, &warning, 5, ruleStateCritical},
+	} {
+		item, err := newRule("rule1", &config.RuleConfig{Graph: "graph1", Operator: entry.Operator,
+			Warning: entry.Warning, Critical: entry.Critical})
+		if err != nil {
+			test.Fatalf("unable to create rule: %s", err)
+		}
+
+		if state := item.check(entry.Value); state != entry.State {
+			test.Logf("\nExpected %s\nbut got  %s for value %v", ruleStateNames[entry.State], ruleStateNames[state],
+				entry.Value)
+			test.Fail()
+		}
+	}
+}
+
+func Test_RuleUpdate(test *testing.T) {
+	warning, critical := 10.0, 20.0
+
+	item, err := newRule("rule1", &config.RuleConfig{Graph: "graph1", Warning: &warning, Critical: &critical})
+	if err != nil {
+		test.Fatalf("unable to create rule: %s", err)
+	}
+
+	startTime := time.Now()
+
+	for index, entry := range []struct {
+		Values        map[string]plot.Value
+		Notifications map[string][2]string
+	}{
+		// New series start in the OK state, thus only notifying on other states
+		{map[string]plot.Value{"a": 5, "b": 15}, map[string][2]string{"b": {"ok", "warning"}}},
+		{map[string]plot.Value{"a": 5, "b": 16}, map[string][2]string{}},
+		{map[string]plot.Value{"a": 25, "b": 5}, map[string][2]string{"a": {"ok", "critical"},
+			"b": {"warning", "ok"}}},
+		// Series no longer returned are unknown
+		{map[string]plot.Value{"b": 5}, map[string][2]string{"a": {"critical", "unknown"}}},
+		{map[string]plot.Value{"a": 15, "b": plot.Value(math.NaN())}, map[string][2]string{
+			"a": {"unknown", "warning"}, "b": {"ok", "unknown"}}},
+		{map[string]plot.Value{"a": 12, "b": plot.Value(math.NaN())}, map[string][2]string{}},
+	} {
+		now := startTime.Add(time.Duration(index) * time.Minute)
+
+		notifications := item.update(entry.Values, now)
+
+		if len(notifications) != len(entry.Notifications) {
+			test.Logf("\nExpected %d notifications\nbut got  %d at step %d", len(entry.Notifications),
+				len(notifications), index)
+			test.Fail()
+		}
+
+		for _, notification := range notifications {
+			expected, ok := entry.Notifications[notification.Series]
+			if !ok || notification.Previous != expected[0] || notification.State != expected[1] {
+				test.Logf("\nExpected %v\nbut got  %v for series `%s' at step %d", expected,
+					[2]string{notification.Previous, notification.State}, notification.Series, index)
+				test.Fail()
+			} else if !notification.Time.Equal(now) || !item.states[notification.Series].Since.Equal(now) {
+				test.Logf("\nExpected state change time %s for series `%s' at step %d", now, notification.Series,
+					index)
+				test.Fail()
+			}
+		}
+	}
+
+	// Unchanged states keep their initial change time
+	if since := item.states["b"].Since; !since.Equal(startTime.Add(4 * time.Minute)) {
+		test.Logf("\nExpected %s\nbut got  %s", startTime.Add(4*time.Minute), since)
+		test.Fail()
+	}
+
+	if since := item.states["a"].Since; !since.Equal(startTime.Add(4 * time.Minute)) {
+		test.Logf("\nExpected %s\nbut got  %s", startTime.Add(4*time.Minute), since)
+		test.Fail()
+	}
+}
+
+func Test_RuleUpdateExpire(test *testing.T) {
+	warning := 10.0
+
+	item, err := newRule("rule1", &config.RuleConfig{Graph: "graph1", Warning: &warning})
+	if err != nil {
+		test.Fatalf("unable to create rule: %s", err)
+	}
+
+	startTime := time.Now()
+	expire := time.Duration(defaultRuleInterval*ruleStateExpireIntervals) * time.Second
+
+	item.update(map[string]plot.Value{"a": 5, "b": 5}, startTime)
+	item.update(map[string]plot.Value{"b": 5}, startTime.Add(time.Minute))
+
+	for _, entry := range []struct {
+		Time   time.Time
+		States []string
+	}{
+		{startTime.Add(time.Minute + expire - time.Second), []string{"a", "b"}},
+		// Series no longer returned are discarded once unknown for long enough
+		{startTime.Add(time.Minute + expire), []string{"b"}},
+	} {
+		if notifications := item.update(map[string]plot.Value{"b": 5}, entry.Time); len(notifications) != 0 {
+			test.Logf("\nExpected no notification\nbut got  %d", len(notifications))
+			test.Fail()
+		}
+
+		states := []string{}
+		for seriesName := range item.states {
+			states = append(states, seriesName)
+		}
+
+		sort.Strings(states)
+
+		if !reflect.DeepEqual(states, entry.States) {
+			test.Logf("\nExpected %v\nbut got  %v", entry.States, states)
+			test.Fail()
+		}
+	}
+}
@@ -58,7 +58,7 @@ func (server *Server) serveAdminCatalog(writer http.ResponseWriter, request *htt
 			Section   string
 		}{
 			URLPrefix: server.Config.URLPrefix,
-			ReadOnly:  server.Config.ReadOnly,
+			ReadOnly:  server.isReadOnly(request),
 			Section:   strings.TrimRight(strings.TrimPrefix(request.URL.Path, urlAdminPath), "/"),
 		},
 		path.Join(server.Config.BaseDir, "template", "layout.html"),
@@ -78,7 +78,7 @@ func (server *Server) serveAdminCollection(writer http.ResponseWriter, request *
 		Path      string
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 	}
 
 	data.Section, data.Path = splitAdminURLPath(request.URL.Path)
@@ -121,7 +121,7 @@ func (server *Server) serveAdminGraph(writer http.ResponseWriter, request *http.
 		GraphUnitTypeMetric int
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 	}
 
 	data.Section, data.Path = splitAdminURLPath(request.URL.Path)
@@ -171,7 +171,7 @@ func (server *Server) serveAdminGroup(writer http.ResponseWriter, request *http.
 		Origins   []string
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 	}
 
 	data.Section, data.Path = splitAdminURLPath(request.URL.Path)
@@ -219,7 +219,7 @@ func (server *Server) serveAdminUnit(writer http.ResponseWriter, request *http.R
 		UnitTypeDuration int
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 	}
 
 	data.Section, data.Path = splitAdminURLPath(request.URL.Path)
@@ -258,7 +258,7 @@ func (server *Server) serveAdminScale(writer http.ResponseWriter, request *http.
 		Path      string
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 	}
 
 	data.Section, data.Path = splitAdminURLPath(request.URL.Path)
@@ -295,7 +295,7 @@ func (server *Server) serveAdminIndex(writer http.ResponseWriter, request *http.
 			Stats     *statsResponse
 		}{
 			URLPrefix: server.Config.URLPrefix,
-			ReadOnly:  server.Config.ReadOnly,
+			ReadOnly:  server.isReadOnly(request),
 			Section:   "",
 			Stats:     server.getStats(writer, request),
 		},
@@ -58,7 +58,7 @@ func (server *Server) serveBrowseIndex(writer http.ResponseWriter, request *http
 			Request   *http.Request
 		}{
 			URLPrefix: server.Config.URLPrefix,
-			ReadOnly:  server.Config.ReadOnly,
+			ReadOnly:  server.isReadOnly(request),
 			Request:   request,
 		},
 		path.Join(server.Config.BaseDir, "template", "layout.html"),
@@ -81,7 +81,7 @@ func (server *Server) serveBrowseCollection(writer http.ResponseWriter, request
 		Request    *http.Request
 	}{
 		URLPrefix:  server.Config.URLPrefix,
-		ReadOnly:   server.Config.ReadOnly,
+		ReadOnly:   server.isReadOnly(request),
 		Collection: &collectionData{Collection: &library.Collection{}},
 		Request:    request,
 	}
@@ -93,7 +93,10 @@ func (server *Server) serveBrowseCollection(writer http.ResponseWriter, request
 		return err
 	}
 
-	data.Collection.Collection = server.Library.FilterCollection(item.(*library.Collection), request.FormValue("q"))
+	data.Collection.Collection = server.Library.FilterCollection(
+		server.Library.ExpandCollection(item.(*library.Collection)),
+		request.FormValue("q"),
+	)
 
 	if data.Collection.Collection.Parent != nil {
 		data.Collection.Parent = data.Collection.Collection.Parent.ID
@@ -121,7 +124,7 @@ func (server *Server) serveBrowseGraph(writer http.ResponseWriter, request *http
 		Request   *http.Request
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 		Request:   request,
 	}
 
@@ -160,7 +163,7 @@ func (server *Server) serveBrowseSearch(writer http.ResponseWriter, request *htt
 		Graphs      []*library.Graph
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 		Request:   request,
 	}
 
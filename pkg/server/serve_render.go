@@ -0,0 +1,383 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/pkg/connector"
+	"github.com/facette/facette/pkg/library"
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/render"
+)
+
+const renderMaxSeries int = 100
+
+var (
+	errTooManySeries = errors.New("too many matching series")
+
+	graphiteRelativeRegexp = regexp.MustCompile("^([-+])(\\d+)\\s*([a-z]+)$")
+
+	graphiteTimeUnits = map[string]time.Duration{
+		"s":       time.Second,
+		"sec":     time.Second,
+		"secs":    time.Second,
+		"second":  time.Second,
+		"seconds": time.Second,
+		"min":     time.Minute,
+		"mins":    time.Minute,
+		"minute":  time.Minute,
+		"minutes": time.Minute,
+		"h":       time.Hour,
+		"hour":    time.Hour,
+		"hours":   time.Hour,
+		"d":       24 * time.Hour,
+		"day":     24 * time.Hour,
+		"days":    24 * time.Hour,
+		"w":       7 * 24 * time.Hour,
+		"week":    7 * 24 * time.Hour,
+		"weeks":   7 * 24 * time.Hour,
+		"mon":     30 * 24 * time.Hour,
+		"month":   30 * 24 * time.Hour,
+		"months":  30 * 24 * time.Hour,
+		"y":       365 * 24 * time.Hour,
+		"year":    365 * 24 * time.Hour,
+		"years":   365 * 24 * time.Hour,
+	}
+)
+
+// graphiteSeries represents a series in the Graphite render API JSON format.
+type graphiteSeries struct {
+	Target     string           `json:"target"`
+	Datapoints [][2]interface{} `json:"datapoints"`
+}
+
+func (server *Server) serveRender(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	if request.Method != "GET" && request.Method != "POST" && request.Method != "HEAD" {
+		server.serveResponse(writer, serverResponse{mesgMethodNotAllowed}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	setHTTPCacheHeaders(writer)
+
+	request.ParseForm()
+
+	format := request.FormValue("format")
+	if format == "" {
+		format = render.FormatPNG
+	} else if format != plotsFormatJSON && format != render.FormatPNG && format != render.FormatSVG {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	// Parse requested time range, defaulting to the last 24 hours as Graphite does
+	now := time.Now()
+
+	fromTime, untilTime := now.Add(-24*time.Hour), now
+
+	if value := request.FormValue("from"); value != "" {
+		if fromTime, err = parseGraphiteTime(value, now); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := request.FormValue("until"); value != "" {
+		if untilTime, err = parseGraphiteTime(value, now); err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !untilTime.After(fromTime) {
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	plotReq := &PlotRequest{
+		Time:  untilTime.Format(time.RFC3339),
+		Range: fmt.Sprintf("-%ds", int64(untilTime.Sub(fromTime).Seconds())),
+		Graph: &library.Graph{},
+	}
+
+	if value := request.FormValue("maxDataPoints"); value != "" {
+		if plotReq.Sample, err = strconv.Atoi(value); err != nil || plotReq.Sample <= 0 {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Resolve targets against the catalog, each matching metric being queried in its own group
+	for _, target := range request.Form["target"] {
+		groups, err := server.resolveGraphiteTarget(target, renderMaxSeries-len(plotReq.Graph.Groups))
+		if err == errTooManySeries {
+			server.serveResponse(writer, serverResponse{mesgTooManySeries}, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			logger.Log(logger.LevelError, "server", "%s", err)
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+
+		plotReq.Graph.Groups = append(plotReq.Graph.Groups, groups...)
+	}
+
+	response, err := server.getPlots(plotReq)
+	if err != nil && err != errEmptyData {
+		errResponse, status := server.parseError(writer, request, err)
+		if status == http.StatusInternalServerError {
+			logger.Log(logger.LevelError, "server", "%s", err)
+		}
+
+		server.serveResponse(writer, errResponse, status)
+		return
+	}
+
+	if format == plotsFormatJSON {
+		result := []*graphiteSeries{}
+
+		if response != nil {
+			for _, series := range response.Series {
+				entry := &graphiteSeries{Target: series.Name, Datapoints: make([][2]interface{}, len(series.Plots))}
+
+				for index, plot := range series.Plots {
+					entry.Datapoints[index][1] = plot.Time.Unix()
+
+					if !plot.Value.IsNaN() {
+						entry.Datapoints[index][0] = float64(plot.Value)
+					}
+				}
+
+				result = append(result, entry)
+			}
+		}
+
+		server.serveResponse(writer, result, http.StatusOK)
+		return
+	}
+
+	graph := renderGraph(response)
+	graph.Title = request.FormValue("title")
+	graph.StartTime, graph.EndTime = fromTime, untilTime
+
+	size := make(map[string]int)
+
+	for _, key := range []string{"width", "height"} {
+		if request.FormValue(key) == "" {
+			continue
+		} else if size[key], err = strconv.Atoi(request.FormValue(key)); err != nil || size[key] <= 0 {
+			server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	buffer := bytes.NewBuffer(nil)
+
+	if err := render.Render(buffer, graph, format, size["width"], size["height"]); err != nil {
+		logger.Log(logger.LevelError, "server", "%s", err)
+		server.serveResponse(writer, serverResponse{mesgResourceInvalid}, http.StatusBadRequest)
+		return
+	}
+
+	if format == render.FormatSVG {
+		writer.Header().Set("Content-Type", "image/svg+xml")
+	} else {
+		writer.Header().Set("Content-Type", "image/png")
+	}
+
+	writer.WriteHeader(http.StatusOK)
+
+	if request.Method != "HEAD" {
+		buffer.WriteTo(writer)
+	}
+}
+
+// resolveGraphiteTarget returns the operation groups of the catalog metrics matching a Graphite-style target, failing
+// if more than max metrics match.
+func (server *Server) resolveGraphiteTarget(target string, max int) ([]*library.OperGroup, error) {
+	re, err := graphiteTargetRegexp(target)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*library.OperGroup)
+	paths := []string{}
+
+	for _, origin := range server.Catalog.Origins {
+		for _, source := range origin.Sources {
+			for _, metric := range source.Metrics {
+				path := graphitePath(origin.Name, source.Name, metric.Name)
+				if !re.MatchString(path) {
+					continue
+				}
+
+				// Keep the first colliding metric in lexical order, as catalog iteration order is random
+				if group, ok := groups[path]; ok {
+					series := group.Series[0]
+
+					current := []string{series.Origin, series.Source, series.Metric}
+					ignored := []string{origin.Name, source.Name, metric.Name}
+
+					if strings.Join(ignored, "\x00") < strings.Join(current, "\x00") {
+						current, ignored = ignored, current
+						series.Origin, series.Source, series.Metric = current[0], current[1], current[2]
+					}
+
+					logger.Log(logger.LevelWarning, "server", "Graphite path `%s' collision, ignoring `%s' for `%s'",
+						path, strings.Join(ignored, "/"), strings.Join(current, "/"))
+
+					continue
+				} else if len(paths) >= max {
+					return nil, errTooManySeries
+				}
+
+				groups[path] = &library.OperGroup{
+					Name: path,
+					Type: connector.OperGroupTypeNone,
+					Series: []*library.Series{{
+						Name:   path,
+						Origin: origin.Name,
+						Source: source.Name,
+						Metric: metric.Name,
+					}},
+				}
+
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	sort.Strings(paths)
+
+	result := make([]*library.OperGroup, len(paths))
+	for index, path := range paths {
+		result[index] = groups[path]
+	}
+
+	return result, nil
+}
+
+// graphitePath returns the Graphite dotted path of a catalog metric. As dots separate path nodes, the ones found in
+// origin and source names are replaced by underscores, whereas slashes and spaces found in metric names are turned
+// into dots.
+func graphitePath(origin, source, metric string) string {
+	return strings.Join([]string{
+		strings.Replace(origin, ".", "_", -1),
+		strings.Replace(source, ".", "_", -1),
+		strings.NewReplacer("/", ".", " ", ".").Replace(metric),
+	}, ".")
+}
+
+// graphiteTargetRegexp converts a Graphite path pattern (supporting `*', `?', `[...]' and `{a,b}' wildcards) into a
+// regular expression.
+func graphiteTargetRegexp(target string) (*regexp.Regexp, error) {
+	var (
+		buffer bytes.Buffer
+		brace  bool
+	)
+
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("empty target")
+	}
+
+	for index := 0; index < len(target); index++ {
+		switch char := target[index]; char {
+		case '*':
+			buffer.WriteString("[^.]*")
+
+		case '?':
+			buffer.WriteString("[^.]")
+
+		case '[':
+			end := strings.IndexByte(target[index:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated character class in target `%s'", target)
+			}
+
+			buffer.WriteString(target[index : index+end+1])
+			index += end
+
+		case '{':
+			if brace {
+				return nil, fmt.Errorf("nested braces in target `%s'", target)
+			}
+
+			brace = true
+			buffer.WriteString("(?:")
+
+		case '}':
+			if !brace {
+				return nil, fmt.Errorf("unexpected closing brace in target `%s'", target)
+			}
+
+			brace = false
+			buffer.WriteString(")")
+
+		case ',':
+			if brace {
+				buffer.WriteString("|")
+			} else {
+				buffer.WriteString(regexp.QuoteMeta(string(char)))
+			}
+
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	if brace {
+		return nil, fmt.Errorf("unterminated brace in target `%s'", target)
+	}
+
+	return regexp.Compile("^" + buffer.String() + "$")
+}
+
+// parseGraphiteTime parses a Graphite render API time specification (e.g. `-1h', `now', `1388534400',
+// `14:30_20140101' or `20140101').
+func parseGraphiteTime(input string, now time.Time) (time.Time, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	if input == "now" {
+		return now, nil
+	}
+
+	if match := graphiteRelativeRegexp.FindStringSubmatch(input); match != nil {
+		unit, ok := graphiteTimeUnits[match[3]]
+		if !ok {
+			return time.Time{}, fmt.Errorf("unknown time unit `%s'", match[3])
+		}
+
+		count, _ := strconv.Atoi(match[2])
+
+		if match[1] == "-" {
+			count = -count
+		}
+
+		return now.Add(time.Duration(count) * unit), nil
+	}
+
+	if len(input) != 8 {
+		if timestamp, err := strconv.ParseInt(input, 10, 64); err == nil {
+			return time.Unix(timestamp, 0), nil
+		}
+	}
+
+	for _, layout := range []string{"15:04_20060102", "20060102"} {
+		if result, err := time.ParseInLocation(layout, input, time.Local); err == nil {
+			return result, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time `%s'", input)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/catalog"
+)
+
+func Test_GraphitePath(test *testing.T) {
+	for _, entry := range []struct {
+		Origin string
+		Source string
+		Metric string
+		Result string
+	}{
+		{"collectd", "host1.example.net", "cpu.0.user", "collectd.host1_example_net.cpu.0.user"},
+		{"collectd", "host1", "df/root used", "collectd.host1.df.root.used"},
+		{"origin.1", "host1", "load", "origin_1.host1.load"},
+	} {
+		if result := graphitePath(entry.Origin, entry.Source, entry.Metric); result != entry.Result {
+			test.Logf("\nExpected %q\nbut got  %q", entry.Result, result)
+			test.Fail()
+		}
+	}
+}
+
+func Test_GraphiteTargetRegexp(test *testing.T) {
+	for _, entry := range []struct {
+		Target string
+		Path   string
+		Match  bool
+		Error  bool
+	}{
+		{"collectd.host1.load", "collectd.host1.load", true, false},
+		{"collectd.host1.load", "collectd.host1.loadavg", false, false},
+		{"collectd.*.load", "collectd.host1.load", true, false},
+		{"collectd.*.load", "collectd.host1.sub.load", false, false},
+		{"collectd.host?.load", "collectd.host1.load", true, false},
+		{"collectd.host?.load", "collectd.host10.load", false, false},
+		{"collectd.host[0-9].load", "collectd.host2.load", true, false},
+		{"collectd.host[0-9].load", "collectd.hostx.load", false, false},
+		{"collectd.{host1,host2}.load", "collectd.host2.load", true, false},
+		{"collectd.{host1,host2}.load", "collectd.host3.load", false, false},
+		{"collectd.host1.cpu+user", "collectd.host1.cpu+user", true, false},
+		{"collectd.host1.cpu+user", "collectd.host1.cpuuuser", false, false},
+		{"", "", false, true},
+		{"collectd.host[0-9.load", "", false, true},
+		{"collectd.{host1,{host2}}.load", "", false, true},
+		{"collectd.host1}.load", "", false, true},
+		{"collectd.{host1,host2.load", "", false, true},
+	} {
+		re, err := graphiteTargetRegexp(entry.Target)
+		if entry.Error {
+			if err == nil {
+				test.Logf("\nExpected error for target %q", entry.Target)
+				test.Fail()
+			}
+
+			continue
+		} else if err != nil {
+			test.Logf("\nUnexpected error for target %q: %s", entry.Target, err)
+			test.Fail()
+			continue
+		}
+
+		if match := re.MatchString(entry.Path); match != entry.Match {
+			test.Logf("\nExpected %v\nbut got  %v for target %q and path %q", entry.Match, match, entry.Target,
+				entry.Path)
+			test.Fail()
+		}
+	}
+}
+
+func Test_ResolveGraphiteTarget(test *testing.T) {
+	server := NewServer("", "", 0)
+	server.Catalog = catalog.NewCatalog()
+
+	for _, record := range []catalog.Record{
+		{Origin: "collectd", Source: "host1", Metric: "load"},
+		{Origin: "collectd", Source: "host2", Metric: "load"},
+		{Origin: "collectd", Source: "host2", Metric: "cpu/user"},
+		{Origin: "collectd", Source: "host2", Metric: "cpu user"},
+		{Origin: "collectd", Source: "a.b", Metric: "load"},
+		{Origin: "collectd", Source: "a_b", Metric: "load"},
+	} {
+		record := record
+		server.Catalog.Insert(&record)
+	}
+
+	for _, entry := range []struct {
+		Target string
+		Max    int
+		Result [][3]string
+		Error  error
+	}{
+		{"collectd.*.load", 10, [][3]string{
+			{"collectd", "a.b", "load"},
+			{"collectd", "host1", "load"},
+			{"collectd", "host2", "load"},
+		}, nil},
+		{"collectd.{host1,host3}.load", 10, [][3]string{{"collectd", "host1", "load"}}, nil},
+		{"collectd.host2.cpu.user", 10, [][3]string{{"collectd", "host2", "cpu user"}}, nil},
+		{"collectd.*.load", 2, nil, errTooManySeries},
+		{"collectd.host3.load", 10, [][3]string{}, nil},
+	} {
+		groups, err := server.resolveGraphiteTarget(entry.Target, entry.Max)
+		if err != entry.Error {
+			test.Logf("\nExpected %v\nbut got  %v for target %q", entry.Error, err, entry.Target)
+			test.Fail()
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		result := [][3]string{}
+		for _, group := range groups {
+			result = append(result, [3]string{group.Series[0].Origin, group.Series[0].Source,
+				group.Series[0].Metric})
+		}
+
+		if !reflect.DeepEqual(result, entry.Result) {
+			test.Logf("\nExpected %v\nbut got  %v for target %q", entry.Result, result, entry.Target)
+			test.Fail()
+		}
+	}
+}
+
+func Test_ParseGraphiteTime(test *testing.T) {
+	now := time.Date(2014, 1, 1, 12, 0, 0, 0, time.Local)
+
+	for _, entry := range []struct {
+		Input  string
+		Result time.Time
+		Error  bool
+	}{
+		{"now", now, false},
+		{"-1h", now.Add(-time.Hour), false},
+		{"-30min", now.Add(-30 * time.Minute), false},
+		{"+2d", now.Add(48 * time.Hour), false},
+		{"-1 weeks", now.Add(-7 * 24 * time.Hour), false},
+		{"1388534400", time.Unix(1388534400, 0), false},
+		{"14:30_20140101", time.Date(2014, 1, 1, 14, 30, 0, 0, time.Local), false},
+		{"20140102", time.Date(2014, 1, 2, 0, 0, 0, 0, time.Local), false},
+		{"-1fortnight", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	} {
+		result, err := parseGraphiteTime(entry.Input, now)
+		if entry.Error {
+			if err == nil {
+				test.Logf("\nExpected error for input %q", entry.Input)
+				test.Fail()
+			}
+
+			continue
+		} else if err != nil {
+			test.Logf("\nUnexpected error for input %q: %s", entry.Input, err)
+			test.Fail()
+			continue
+		}
+
+		if !result.Equal(entry.Result) {
+			test.Logf("\nExpected %s\nbut got  %s for input %q", entry.Result, result, entry.Input)
+			test.Fail()
+		}
+	}
+}
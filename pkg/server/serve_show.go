@@ -22,6 +22,8 @@ func (server *Server) serveShow(writer http.ResponseWriter, request *http.Reques
 
 	if strings.HasPrefix(request.URL.Path, urlShowPath+"graphs/") {
 		err = server.serveShowGraph(writer, request)
+	} else if strings.HasPrefix(request.URL.Path, urlShowPath+"snapshot/") {
+		err = server.serveShowSnapshot(writer, request)
 	} else {
 		err = os.ErrNotExist
 	}
@@ -43,7 +45,7 @@ func (server *Server) serveShowGraph(writer http.ResponseWriter, request *http.R
 		Range     string
 	}{
 		URLPrefix: server.Config.URLPrefix,
-		ReadOnly:  server.Config.ReadOnly,
+		ReadOnly:  server.isReadOnly(request),
 		Range:     request.FormValue("range"),
 		Request:   request,
 	}
@@ -69,3 +71,34 @@ func (server *Server) serveShowGraph(writer http.ResponseWriter, request *http.R
 		path.Join(server.Config.BaseDir, "template", "show", "graph.html"),
 	)
 }
+
+func (server *Server) serveShowSnapshot(writer http.ResponseWriter, request *http.Request) error {
+	var err error
+
+	data := struct {
+		URLPrefix string
+		ReadOnly  bool
+		Snapshot  *SnapshotResponse
+		Request   *http.Request
+	}{
+		URLPrefix: server.Config.URLPrefix,
+		ReadOnly:  server.isReadOnly(request),
+		Request:   request,
+	}
+
+	data.Snapshot, err = server.loadSnapshot(strings.TrimPrefix(request.URL.Path, urlShowPath+"snapshot/"))
+	if err != nil {
+		return err
+	}
+
+	return server.execTemplate(
+		writer,
+		http.StatusOK,
+		data,
+		path.Join(server.Config.BaseDir, "template", "layout.html"),
+		path.Join(server.Config.BaseDir, "template", "common", "element.html"),
+		path.Join(server.Config.BaseDir, "template", "common", "graph.html"),
+		path.Join(server.Config.BaseDir, "template", "show", "layout.html"),
+		path.Join(server.Config.BaseDir, "template", "show", "snapshot.html"),
+	)
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/facette/facette/pkg/auth"
 	"github.com/facette/facette/pkg/catalog"
 	"github.com/facette/facette/pkg/config"
 	"github.com/facette/facette/pkg/library"
@@ -22,10 +23,22 @@ type Server struct {
 	Config          *config.Config
 	Catalog         *catalog.Catalog
 	Library         *library.Library
+	authenticator   *auth.Authenticator
+	roles           map[string]int
+	defaultRole     int
 	providers       map[string]*provider.Provider
 	providerWorkers worker.Pool
 	catalogWorker   *worker.Worker
+	ruleWorker      *worker.Worker
+	rules           map[string]*rule
+	rulesLock       sync.RWMutex
+	reportWorker    *worker.Worker
+	reports         map[string]*report
+	reportsLock     sync.RWMutex
 	serveWorker     *worker.Worker
+	streams         map[string]*stream
+	streamSessions  map[string]*streamSession
+	streamsLock     sync.Mutex
 	configPath      string
 	logPath         string
 	logLevel        int
@@ -38,26 +51,57 @@ type Server struct {
 func NewServer(configPath, logPath string, logLevel int) *Server {
 	return &Server{
 		Config: &config.Config{
-			BindAddr:     config.DefaultBindAddr,
-			BaseDir:      config.DefaultBaseDir,
-			DataDir:      config.DefaultDataDir,
-			ProvidersDir: config.DefaultProvidersDir,
-			PidFile:      config.DefaultPidFile,
-			SocketUser:   config.DefaultSocketUser,
-			SocketGroup:  config.DefaultSocketGroup,
+			BindAddr:       config.DefaultBindAddr,
+			BaseDir:        config.DefaultBaseDir,
+			DataDir:        config.DefaultDataDir,
+			ProvidersDir:   config.DefaultProvidersDir,
+			RulesDir:       config.DefaultRulesDir,
+			ReportsDir:     config.DefaultReportsDir,
+			PidFile:        config.DefaultPidFile,
+			SocketUser:     config.DefaultSocketUser,
+			SocketGroup:    config.DefaultSocketGroup,
+			RevisionsLimit: config.DefaultRevisionsLimit,
 		},
-		configPath: configPath,
-		logPath:    logPath,
-		logLevel:   logLevel,
-		providers:  make(map[string]*provider.Provider),
-		wg:         &sync.WaitGroup{},
+		configPath:     configPath,
+		logPath:        logPath,
+		logLevel:       logLevel,
+		providers:      make(map[string]*provider.Provider),
+		streams:        make(map[string]*stream),
+		streamSessions: make(map[string]*streamSession),
+		wg:             &sync.WaitGroup{},
 	}
 }
 
-// Refresh refreshes both catalog and library.
+// Refresh refreshes catalog, library, alerting rules and scheduled reports.
 func (server *Server) Refresh() {
 	server.providerWorkers.Broadcast(eventCatalogRefresh, nil)
 	server.Library.Refresh()
+
+	if err := server.Config.LoadRules(); err != nil {
+		logger.Log(logger.LevelError, "server", "unable to reload rules: %s", err)
+	} else {
+		server.initRules()
+
+		if err := server.startRuleWorker(); err != nil {
+			logger.Log(logger.LevelError, "server", "unable to start rule worker: %s", err)
+		}
+	}
+
+	if err := server.Config.LoadReports(); err != nil {
+		logger.Log(logger.LevelError, "server", "unable to reload reports: %s", err)
+	} else {
+		server.initReports()
+
+		if err := server.startReportWorker(); err != nil {
+			logger.Log(logger.LevelError, "server", "unable to start report worker: %s", err)
+		}
+	}
+
+	if server.authenticator != nil {
+		if err := server.authenticator.Reload(); err != nil {
+			logger.Log(logger.LevelError, "server", "unable to reload authentication: %s", err)
+		}
+	}
 }
 
 // Run starts the server serving the HTTP responses.
@@ -88,6 +132,14 @@ func (server *Server) Run() error {
 		return err
 	}
 
+	// Set up authentication if enabled
+	if server.Config.Auth != nil {
+		if err := server.initAuth(); err != nil {
+			logger.Log(logger.LevelError, "server", "unable to initialize authentication: %s", err)
+			return err
+		}
+	}
+
 	// Handle pid file creation if set
 	if server.Config.PidFile != "" {
 		fd, err := os.OpenFile(server.Config.PidFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
@@ -133,6 +185,20 @@ func (server *Server) Run() error {
 	server.Library = library.NewLibrary(server.Config, server.Catalog)
 	go server.Library.Refresh()
 
+	// Instanciate rule worker if alerting rules are defined
+	server.initRules()
+
+	if err := server.startRuleWorker(); err != nil {
+		return err
+	}
+
+	// Instanciate report worker if scheduled reports are defined
+	server.initReports()
+
+	if err := server.startReportWorker(); err != nil {
+		return err
+	}
+
 	// Instanciate serve worker
 	server.serveWorker = worker.NewWorker()
 	server.serveWorker.RegisterEvent(eventInit, workerServeInit)
@@ -150,6 +216,100 @@ func (server *Server) Run() error {
 	return nil
 }
 
+// startRuleWorker starts the rule worker if alerting rules are defined and it is not running yet.
+func (server *Server) startRuleWorker() error {
+	if server.ruleWorker != nil || len(server.getRules()) == 0 {
+		return nil
+	}
+
+	ruleWorker := worker.NewWorker()
+	ruleWorker.RegisterEvent(eventInit, workerRuleInit)
+	ruleWorker.RegisterEvent(eventShutdown, workerRuleShutdown)
+	ruleWorker.RegisterEvent(eventRun, workerRuleRun)
+
+	if err := ruleWorker.SendEvent(eventInit, false, server); err != nil {
+		return err
+	}
+
+	ruleWorker.SendEvent(eventRun, true, nil)
+
+	server.ruleWorker = ruleWorker
+
+	return nil
+}
+
+// startReportWorker starts the report worker if scheduled reports are defined and it is not running yet.
+func (server *Server) startReportWorker() error {
+	if server.reportWorker != nil || len(server.getReports()) == 0 {
+		return nil
+	}
+
+	reportWorker := worker.NewWorker()
+	reportWorker.RegisterEvent(eventInit, workerReportInit)
+	reportWorker.RegisterEvent(eventShutdown, workerReportShutdown)
+	reportWorker.RegisterEvent(eventRun, workerReportRun)
+
+	if err := reportWorker.SendEvent(eventInit, false, server); err != nil {
+		return err
+	}
+
+	reportWorker.SendEvent(eventRun, true, nil)
+
+	server.reportWorker = reportWorker
+
+	return nil
+}
+
+func (server *Server) initAuth() error {
+	var backends []auth.Backend
+
+	if server.Config.Auth.HTPasswdFile != "" {
+		htpasswd, err := auth.NewHTPasswd(server.Config.Auth.HTPasswdFile)
+		if err != nil {
+			return err
+		}
+
+		backends = append(backends, htpasswd)
+	}
+
+	if len(server.Config.Auth.Tokens) > 0 {
+		backends = append(backends, auth.NewTokens(server.Config.Auth.Tokens))
+	}
+
+	if len(backends) == 0 {
+		return fmt.Errorf("no authentication backend configured")
+	}
+
+	// Parse users roles
+	defaultRole := server.Config.Auth.DefaultRole
+	if defaultRole == "" {
+		defaultRole = config.DefaultAuthRole
+	}
+
+	role, err := auth.ParseRole(defaultRole)
+	if err != nil {
+		return err
+	}
+
+	server.defaultRole = role
+	server.roles = make(map[string]int)
+
+	for user, name := range server.Config.Auth.Roles {
+		if server.roles[user], err = auth.ParseRole(name); err != nil {
+			return fmt.Errorf("user `%s': %s", user, err)
+		}
+	}
+
+	realm := server.Config.Auth.Realm
+	if realm == "" {
+		realm = config.DefaultAuthRealm
+	}
+
+	server.authenticator = auth.NewAuthenticator(realm, backends...)
+
+	return nil
+}
+
 // Stop stops the server.
 func (server *Server) Stop() {
 	if server.stopping {
@@ -165,6 +325,23 @@ func (server *Server) Stop() {
 		logger.Log(logger.LevelWarning, "server", "serve worker did not shut down successfully: %s", err)
 	}
 
+	// Close running plots streams
+	server.stopStreams()
+
+	// Shutdown rule worker
+	if server.ruleWorker != nil {
+		if err := server.ruleWorker.SendEvent(eventShutdown, false, nil); err != nil {
+			logger.Log(logger.LevelWarning, "server", "rule worker did not shut down successfully: %s", err)
+		}
+	}
+
+	// Shutdown report worker
+	if server.reportWorker != nil {
+		if err := server.reportWorker.SendEvent(eventShutdown, false, nil); err != nil {
+			logger.Log(logger.LevelWarning, "server", "report worker did not shut down successfully: %s", err)
+		}
+	}
+
 	// Shutdown running provider workers
 	server.stopProviderWorkers()
 
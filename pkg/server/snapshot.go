@@ -0,0 +1,51 @@
+package server
+
+import (
+	"os"
+	"path"
+	"time"
+
+	"github.com/facette/facette/pkg/utils"
+	uuid "github.com/facette/facette/thirdparty/github.com/nu7hatch/gouuid"
+)
+
+// storeSnapshot stores a new plot snapshot on the filesystem, assigning it a unique identifier.
+func (server *Server) storeSnapshot(snapshot *SnapshotResponse) error {
+	uuidTemp, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	snapshot.ID = uuidTemp.String()
+	snapshot.Created = time.Now()
+
+	return utils.JSONDump(server.getSnapshotFilePath(snapshot.ID), snapshot, snapshot.Created)
+}
+
+// loadSnapshot loads a stored plot snapshot from the filesystem.
+func (server *Server) loadSnapshot(id string) (*SnapshotResponse, error) {
+	if _, err := uuid.ParseHex(id); err != nil {
+		return nil, os.ErrNotExist
+	}
+
+	snapshot := &SnapshotResponse{}
+
+	if _, err := utils.JSONLoad(server.getSnapshotFilePath(id), snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// deleteSnapshot removes a stored plot snapshot from the filesystem.
+func (server *Server) deleteSnapshot(id string) error {
+	if _, err := uuid.ParseHex(id); err != nil {
+		return os.ErrNotExist
+	}
+
+	return os.Remove(server.getSnapshotFilePath(id))
+}
+
+func (server *Server) getSnapshotFilePath(id string) string {
+	return path.Join(server.Config.DataDir, "snapshots", id[0:2], id[2:4], id+".json")
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/config"
+	"github.com/facette/facette/pkg/plot"
+)
+
+func Test_StoreLoadSnapshot(test *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "facette")
+	if err != nil {
+		test.Fatalf("unable to create temporary directory: %s", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	server := NewServer("", "", 0)
+	server.Config = &config.Config{DataDir: tmpDir}
+
+	snapshot := &SnapshotResponse{
+		Request: &PlotRequest{ID: "graph1", Range: "-1h"},
+		Plots: &PlotResponse{Series: []*SeriesResponse{{
+			Name: "series1",
+			Plots: []plot.Plot{
+				{Time: time.Unix(60, 0), Value: 1},
+				{Time: time.Unix(120, 0), Value: plot.Value(math.NaN())},
+			},
+			Summary: map[string]plot.Value{"avg": 1, "last": plot.Value(math.NaN())},
+		}}},
+	}
+
+	if err := server.storeSnapshot(snapshot); err != nil {
+		test.Fatalf("unable to store snapshot: %s", err)
+	}
+
+	result, err := server.loadSnapshot(snapshot.ID)
+	if err != nil {
+		test.Fatalf("unable to load snapshot: %s", err)
+	}
+
+	series := result.Plots.Series[0]
+
+	// Missing values are marshalled as null, and thus must be loaded back as NaN
+	if len(series.Plots) != 2 || series.Plots[0].Value != 1 || !series.Plots[1].Value.IsNaN() {
+		test.Logf("\nExpected %v\nbut got  %v", snapshot.Plots.Series[0].Plots, series.Plots)
+		test.Fail()
+	}
+
+	if series.Summary["avg"] != 1 || !series.Summary["last"].IsNaN() {
+		test.Logf("\nExpected %v\nbut got  %v", snapshot.Plots.Series[0].Summary, series.Summary)
+		test.Fail()
+	}
+
+	if _, err := server.loadSnapshot("unknown"); err != os.ErrNotExist {
+		test.Logf("\nExpected %v\nbut got  %v", os.ErrNotExist, err)
+		test.Fail()
+	}
+}
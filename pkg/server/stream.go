@@ -0,0 +1,464 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/plot"
+	"github.com/facette/facette/pkg/utils"
+	uuid "github.com/facette/facette/thirdparty/github.com/nu7hatch/gouuid"
+)
+
+const (
+	streamEventSession string = "session"
+	streamEventPlots   string = "plots"
+	streamEventUpdate  string = "update"
+
+	defaultStreamInterval int = 60
+	streamMinInterval     int = 10
+	streamMaxCount        int = 100
+
+	streamBufferSize int = 64
+)
+
+var errTooManyStreams = errors.New("too many streams")
+
+// stream represents a live plots stream, shared among the subscribers of identical plot requests.
+type stream struct {
+	key         string
+	plotReq     *PlotRequest
+	interval    time.Duration
+	incremental bool
+	response    *PlotResponse
+	lastTimes   map[string]time.Time
+	subscribers map[*streamSubscriber]bool
+	stopChan    chan bool
+	sync.Mutex
+}
+
+// streamSession represents a client connection carrying the events of all its streams subscriptions.
+type streamSession struct {
+	id            string
+	user          string
+	events        chan *streamEvent
+	subscriptions map[string]*streamSubscriber
+	done          chan bool
+	closeOnce     sync.Once
+}
+
+// streamSubscriber represents a session subscription to a live plots stream.
+type streamSubscriber struct {
+	id      string
+	session *streamSession
+	stream  *stream
+}
+
+// streamEvent represents an event sent to stream sessions.
+type streamEvent struct {
+	name string
+	data []byte
+}
+
+// newStreamSession creates a new stream session, registering it into the server.
+func (server *Server) newStreamSession(user string) (*streamSession, error) {
+	uuidTemp, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &streamSession{
+		id:            uuidTemp.String(),
+		user:          user,
+		events:        make(chan *streamEvent, streamBufferSize),
+		subscriptions: make(map[string]*streamSubscriber),
+		done:          make(chan bool),
+	}
+
+	server.streamsLock.Lock()
+	server.streamSessions[session.id] = session
+	server.streamsLock.Unlock()
+
+	return session, nil
+}
+
+// getStreamSession returns a registered stream session.
+func (server *Server) getStreamSession(id string) *streamSession {
+	server.streamsLock.Lock()
+	defer server.streamsLock.Unlock()
+
+	return server.streamSessions[id]
+}
+
+// closeStreamSession closes a stream session, removing all its subscriptions.
+func (server *Server) closeStreamSession(session *streamSession) {
+	server.streamsLock.Lock()
+	defer server.streamsLock.Unlock()
+
+	for _, subscriber := range session.subscriptions {
+		server.unsubscribe(subscriber)
+	}
+
+	delete(server.streamSessions, session.id)
+
+	session.close()
+}
+
+// subscribeStream subscribes a session to the live plots stream matching a plot request, starting a new stream if no
+// identical subscription is running yet. An existing session subscription having the same identifier is replaced.
+func (server *Server) subscribeStream(session *streamSession, id string, plotReq *PlotRequest, interval int) error {
+	if plotReq.Time != "" || !strings.HasPrefix(strings.TrimSpace(plotReq.Range), "-") {
+		return os.ErrInvalid
+	} else if _, err := utils.TimeApplyRange(time.Now(), plotReq.Range); err != nil {
+		return os.ErrInvalid
+	} else if interval < streamMinInterval {
+		return os.ErrInvalid
+	}
+
+	data, err := json.Marshal(plotReq)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%d:%s", interval, data)
+
+	server.streamsLock.Lock()
+	defer server.streamsLock.Unlock()
+
+	if server.streamSessions[session.id] != session {
+		return os.ErrNotExist
+	}
+
+	if subscriber, ok := session.subscriptions[id]; ok {
+		server.unsubscribe(subscriber)
+	}
+
+	item, ok := server.streams[key]
+	if !ok {
+		if len(server.streams) >= streamMaxCount {
+			return errTooManyStreams
+		}
+
+		item = &stream{
+			key:      key,
+			plotReq:  plotReq,
+			interval: time.Duration(interval) * time.Second,
+			// Smoothing, time shifts and annotations need the whole time range to be computed
+			incremental: len(plotReq.Smoothing) == 0 && len(plotReq.Shifts) == 0 && plotReq.Annotations == nil,
+			lastTimes:   make(map[string]time.Time),
+			subscribers: make(map[*streamSubscriber]bool),
+			stopChan:    make(chan bool),
+		}
+
+		server.streams[key] = item
+
+		logger.Log(logger.LevelDebug, "server", "starting stream for graph `%s' every %s", plotReq.ID, item.interval)
+
+		go server.runStream(item)
+	}
+
+	subscriber := &streamSubscriber{id: id, session: session, stream: item}
+	session.subscriptions[id] = subscriber
+
+	item.Lock()
+	defer item.Unlock()
+
+	item.subscribers[subscriber] = true
+
+	// Send last known plots to the new subscriber, if any
+	if item.response != nil {
+		subscriber.send(streamEventPlots, item.response)
+	}
+
+	return nil
+}
+
+// unsubscribeStream removes a session subscription.
+func (server *Server) unsubscribeStream(session *streamSession, id string) error {
+	server.streamsLock.Lock()
+	defer server.streamsLock.Unlock()
+
+	subscriber, ok := session.subscriptions[id]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	server.unsubscribe(subscriber)
+
+	return nil
+}
+
+// unsubscribe removes a subscriber from its live plots stream, stopping the stream if no subscriber is left. The
+// streams lock must be held by the caller.
+func (server *Server) unsubscribe(subscriber *streamSubscriber) {
+	stream := subscriber.stream
+
+	delete(subscriber.session.subscriptions, subscriber.id)
+
+	stream.Lock()
+	defer stream.Unlock()
+
+	delete(stream.subscribers, subscriber)
+
+	if len(stream.subscribers) > 0 || server.streams[stream.key] != stream {
+		return
+	}
+
+	logger.Log(logger.LevelDebug, "server", "stopping stream for graph `%s'", stream.plotReq.ID)
+
+	close(stream.stopChan)
+	delete(server.streams, stream.key)
+}
+
+// stopStreams stops all the running live plots streams, closing their sessions.
+func (server *Server) stopStreams() {
+	server.streamsLock.Lock()
+	defer server.streamsLock.Unlock()
+
+	for id, session := range server.streamSessions {
+		session.close()
+		delete(server.streamSessions, id)
+	}
+
+	for key, stream := range server.streams {
+		close(stream.stopChan)
+		delete(server.streams, key)
+	}
+}
+
+func (server *Server) runStream(stream *stream) {
+	ticker := time.NewTicker(stream.interval)
+	defer ticker.Stop()
+
+	server.pollStream(stream)
+
+	for {
+		select {
+		case <-stream.stopChan:
+			return
+
+		case <-ticker.C:
+			server.pollStream(stream)
+		}
+	}
+}
+
+// pollStream retrieves the stream plots and broadcasts them to the subscribers. Once the whole time range plots have
+// been sent, incremental streams only retrieve and broadcast the points newer than the last ones sent for each series.
+func (server *Server) pollStream(stream *stream) {
+	plotReq := *stream.plotReq
+
+	stream.Lock()
+	initial := stream.response == nil
+	full := initial || !stream.incremental || stream.response.Step <= 0
+
+	if !full {
+		since := stream.since()
+
+		// Keep the initial plots resolution when retrieving the new points
+		window := time.Since(since) + time.Duration(stream.response.Step*float64(time.Second))
+
+		plotReq.Time = since.Format(time.RFC3339)
+		plotReq.Range = utils.DurationToRange(window)
+		plotReq.Sample = int(math.Ceil(window.Seconds() / stream.response.Step))
+	}
+	stream.Unlock()
+
+	response, err := server.getPlots(&plotReq)
+	if err != nil {
+		if err != errEmptyData {
+			logger.Log(logger.LevelError, "server", "stream for graph `%s': unable to get plots: %s",
+				stream.plotReq.ID, err)
+		}
+
+		if initial {
+			stream.Lock()
+			stream.broadcast(streamEventPlots, serverResponse{mesgEmptyData})
+			stream.Unlock()
+		}
+
+		return
+	}
+
+	stream.Lock()
+	defer stream.Unlock()
+
+	// Send whole time range plots on non-incremental streams, as previous points may have changed
+	if stream.response == nil || !stream.incremental {
+		for _, series := range response.Series {
+			stream.lastTimes[series.Name] = lastPlotTime(series.Plots, time.Time{})
+		}
+
+		stream.response = response
+		stream.broadcast(streamEventPlots, response)
+
+		return
+	}
+
+	update := &PlotResponse{
+		ID:    response.ID,
+		Start: response.Start,
+		End:   response.End,
+		Step:  stream.response.Step,
+	}
+
+	if !full {
+		// Report the whole time range to let subscribers discard outdated points
+		endTime := time.Now()
+		startTime, _ := utils.TimeApplyRange(endTime, stream.plotReq.Range)
+
+		update.Start = startTime.Format(time.RFC3339)
+		update.End = endTime.Format(time.RFC3339)
+	}
+
+	for _, series := range response.Series {
+		lastTime := stream.lastTimes[series.Name]
+		newTime := lastPlotTime(series.Plots, lastTime)
+
+		plots := []plot.Plot{}
+
+		for _, plotItem := range series.Plots {
+			if plotItem.Time.After(lastTime) && !plotItem.Time.After(newTime) {
+				plots = append(plots, plotItem)
+			}
+		}
+
+		if len(plots) == 0 {
+			continue
+		}
+
+		stream.lastTimes[series.Name] = newTime
+
+		update.Series = append(update.Series, &SeriesResponse{
+			Name:    series.Name,
+			StackID: series.StackID,
+			Plots:   plots,
+			Options: series.Options,
+		})
+	}
+
+	stream.response.merge(update, stream.plotReq.Percentiles)
+
+	if len(update.Series) > 0 {
+		stream.broadcast(streamEventUpdate, update)
+	}
+}
+
+// since returns the time of the oldest last point sent among the stream series.
+func (stream *stream) since() time.Time {
+	var result time.Time
+
+	for _, lastTime := range stream.lastTimes {
+		if !lastTime.IsZero() && (result.IsZero() || lastTime.Before(result)) {
+			result = lastTime
+		}
+	}
+
+	if result.IsZero() {
+		result, _ = time.Parse(time.RFC3339, stream.response.End)
+	}
+
+	return result
+}
+
+// broadcast sends an event to all the stream subscribers.
+func (stream *stream) broadcast(name string, data interface{}) {
+	for subscriber := range stream.subscribers {
+		subscriber.send(name, data)
+	}
+}
+
+// send sends an event to the subscriber session, tagged with the subscription identifier.
+func (subscriber *streamSubscriber) send(name string, data interface{}) {
+	output, err := json.Marshal(struct {
+		ID   string      `json:"id"`
+		Data interface{} `json:"data"`
+	}{subscriber.id, data})
+	if err != nil {
+		logger.Log(logger.LevelError, "server", "stream for graph `%s': %s", subscriber.stream.plotReq.ID, err)
+		return
+	}
+
+	subscriber.session.send(&streamEvent{name: name, data: output})
+}
+
+// send queues an event to be sent on the session connection. Sessions not keeping up with their streams are closed,
+// letting clients subscribe again to receive the whole time range plots.
+func (session *streamSession) send(event *streamEvent) {
+	select {
+	case <-session.done:
+
+	case session.events <- event:
+
+	default:
+		session.close()
+	}
+}
+
+func (session *streamSession) close() {
+	session.closeOnce.Do(func() { close(session.done) })
+}
+
+// merge merges the new points of a stream update into the plot response, discarding points outside of the update
+// time range, and updates the summaries of the merged series.
+func (response *PlotResponse) merge(update *PlotResponse, percentiles []float64) {
+	startTime, err := time.Parse(time.RFC3339, update.Start)
+	if err != nil {
+		return
+	}
+
+	response.Start = update.Start
+	response.End = update.End
+
+	for _, updateSeries := range update.Series {
+		var series *SeriesResponse
+
+		for _, item := range response.Series {
+			if item.Name == updateSeries.Name {
+				series = item
+				break
+			}
+		}
+
+		if series == nil {
+			series = &SeriesResponse{Name: updateSeries.Name, StackID: updateSeries.StackID,
+				Options: updateSeries.Options}
+			response.Series = append(response.Series, series)
+		}
+
+		plots := []plot.Plot{}
+
+		for _, plotItem := range series.Plots {
+			if !plotItem.Time.Before(startTime) && plotItem.Time.Before(updateSeries.Plots[0].Time) {
+				plots = append(plots, plotItem)
+			}
+		}
+
+		series.Plots = append(plots, updateSeries.Plots...)
+
+		// Recompute summary over the whole time range, sending it along with the new points
+		summarySeries := plot.Series{Plots: series.Plots, Summary: make(map[string]plot.Value)}
+		summarySeries.Summarize(percentiles)
+
+		series.Summary = summarySeries.Summary
+		updateSeries.Summary = summarySeries.Summary
+	}
+}
+
+// lastPlotTime returns the time of the last non-NaN point of a series, trailing points being possibly not yet
+// available on the origin backend.
+func lastPlotTime(plots []plot.Plot, defaultTime time.Time) time.Time {
+	for i := len(plots) - 1; i >= 0; i-- {
+		if !plots[i].Value.IsNaN() {
+			return plots[i].Time
+		}
+	}
+
+	return defaultTime
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/facette/facette/pkg/plot"
+)
+
+func Test_SubscribeStream(test *testing.T) {
+	server := NewServer("", "", 0)
+
+	session, err := server.newStreamSession("")
+	if err != nil {
+		test.Fatalf("unable to create session: %s", err)
+	}
+
+	for _, entry := range []struct {
+		Request  PlotRequest
+		Interval int
+		Error    error
+	}{
+		{PlotRequest{ID: "graph1", Range: "-1h"}, streamMinInterval - 1, os.ErrInvalid},
+		{PlotRequest{ID: "graph1", Range: "-1h", Time: "2015-01-01T00:00:00Z"}, defaultStreamInterval, os.ErrInvalid},
+		{PlotRequest{ID: "graph1", Range: "1h"}, defaultStreamInterval, os.ErrInvalid},
+		{PlotRequest{ID: "graph1", Range: "-1x"}, defaultStreamInterval, os.ErrInvalid},
+	} {
+		plotReq := entry.Request
+
+		if err := server.subscribeStream(session, "id1", &plotReq, entry.Interval); err != entry.Error {
+			test.Logf("\nExpected %v\nbut got  %v for request %+v", entry.Error, err, entry.Request)
+			test.Fail()
+		}
+	}
+
+	// Check for distinct streams limit
+	for i := 0; i < streamMaxCount; i++ {
+		server.streams[fmt.Sprintf("stream%d", i)] = &stream{}
+	}
+
+	err = server.subscribeStream(session, "id1", &PlotRequest{ID: "graph1", Range: "-1h"}, defaultStreamInterval)
+	if err != errTooManyStreams {
+		test.Logf("\nExpected %v\nbut got  %v", errTooManyStreams, err)
+		test.Fail()
+	}
+
+	// Check for closed session
+	server.closeStreamSession(session)
+
+	err = server.subscribeStream(session, "id1", &PlotRequest{ID: "graph1", Range: "-1h"}, defaultStreamInterval)
+	if err != os.ErrNotExist {
+		test.Logf("\nExpected %v\nbut got  %v", os.ErrNotExist, err)
+		test.Fail()
+	}
+}
+
+func Test_PlotResponseMerge(test *testing.T) {
+	startTime := time.Unix(0, 0).UTC()
+
+	response := &PlotResponse{
+		Start: startTime.Format(time.RFC3339),
+		Series: []*SeriesResponse{{Name: "series1", Plots: []plot.Plot{
+			{Time: startTime, Value: 1},
+			{Time: startTime.Add(time.Minute), Value: 2},
+			{Time: startTime.Add(2 * time.Minute), Value: 3},
+		}}},
+	}
+
+	update := &PlotResponse{
+		Start: startTime.Add(time.Minute).Format(time.RFC3339),
+		Series: []*SeriesResponse{{Name: "series1", Plots: []plot.Plot{
+			{Time: startTime.Add(2 * time.Minute), Value: 5},
+			{Time: startTime.Add(3 * time.Minute), Value: 8},
+		}}},
+	}
+
+	response.merge(update, []float64{50})
+
+	expected := []plot.Value{2, 5, 8}
+
+	result := []plot.Value{}
+	for _, plotItem := range response.Series[0].Plots {
+		result = append(result, plotItem.Value)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		test.Logf("\nExpected %v\nbut got  %v", expected, result)
+		test.Fail()
+	}
+
+	// Summaries are computed over the merged series, and sent along with the update
+	for key, value := range map[string]plot.Value{"min": 2, "max": 8, "avg": 5, "last": 8, "50th": 5} {
+		if response.Series[0].Summary[key] != value || update.Series[0].Summary[key] != value {
+			test.Logf("\nExpected %s=%v\nbut got  %v and %v", key, value, response.Series[0].Summary[key],
+				update.Series[0].Summary[key])
+			test.Fail()
+		}
+	}
+}
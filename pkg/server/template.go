@@ -78,6 +78,11 @@ func templateDumpMap(x map[string]interface{}) string {
 
 			chunks = append(chunks, fmt.Sprintf("%s: %v", key, strings.Join(valueString, ", ")))
 
+		case map[string]interface{}:
+			for subKey, subValue := range value.(map[string]interface{}) {
+				chunks = append(chunks, fmt.Sprintf("%s.%s: %v", key, subKey, subValue))
+			}
+
 		default:
 			chunks = append(chunks, fmt.Sprintf("%s: %v", key, value))
 		}
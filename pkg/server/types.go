@@ -1,8 +1,11 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/facette/facette/pkg/connector"
 	"github.com/facette/facette/pkg/library"
 	"github.com/facette/facette/pkg/plot"
 )
@@ -24,13 +27,101 @@ func (e ExpandRequest) Swap(i, j int) {
 
 // PlotRequest represents a plot request structure in the server backend.
 type PlotRequest struct {
-	Time        string         `json:"time"`
-	Range       string         `json:"range"`
-	Sample      int            `json:"sample"`
-	Constants   []float64      `json:"constants"`
-	Percentiles []float64      `json:"percentiles"`
-	ID          string         `json:"id"`
-	Graph       *library.Graph `json:"graph"`
+	Time         string                       `json:"time"`
+	Range        string                       `json:"range"`
+	Sample       int                          `json:"sample"`
+	Constants    []float64                    `json:"constants"`
+	Percentiles  []float64                    `json:"percentiles"`
+	ID           string                       `json:"id"`
+	Graph        *library.Graph               `json:"graph"`
+	Smoothing    map[string]*SmoothingRequest `json:"smoothing"`
+	Shifts       []string                     `json:"shifts"`
+	Downsampling string                       `json:"downsampling"`
+	Variables    map[string]string            `json:"variables"`
+	Annotations  []string                     `json:"annotations"`
+}
+
+// StreamRequest represents a plots stream subscription request structure in the server backend.
+type StreamRequest struct {
+	ID       string       `json:"id"`
+	Interval int          `json:"interval"`
+	Request  *PlotRequest `json:"request"`
+}
+
+const (
+	plotsFormatJSON string = "json"
+	plotsFormatFlat string = "flat"
+	plotsFormatCSV  string = "csv"
+)
+
+const (
+	downsamplingConsolidate string = "consolidate"
+	downsamplingLTTB        string = "lttb"
+)
+
+const (
+	smoothingTypeSMA         string = "sma"
+	smoothingTypeEMA         string = "ema"
+	smoothingTypeHoltWinters string = "holt_winters"
+
+	defaultSmoothingWindow   int     = 5
+	defaultSmoothingAlpha    float64 = 0.3
+	defaultHoltWintersAlpha  float64 = 0.1
+	defaultHoltWintersBeta   float64 = 0.0035
+	defaultHoltWintersGamma  float64 = 0.1
+	defaultHoltWintersSeason string  = "1d"
+	defaultHoltWintersDelta  float64 = 3
+)
+
+// SmoothingRequest represents a group smoothing request structure in the server backend.
+type SmoothingRequest struct {
+	Type   string  `json:"type"`
+	Window int     `json:"window"`
+	Alpha  float64 `json:"alpha"`
+	Beta   float64 `json:"beta"`
+	Gamma  float64 `json:"gamma"`
+	Season string  `json:"season"`
+	Delta  float64 `json:"delta"`
+}
+
+func (smoothReq *SmoothingRequest) check() error {
+	switch smoothReq.Type {
+	case smoothingTypeSMA:
+		if smoothReq.Window == 0 {
+			smoothReq.Window = defaultSmoothingWindow
+		}
+
+	case smoothingTypeEMA:
+		if smoothReq.Alpha == 0 {
+			smoothReq.Alpha = defaultSmoothingAlpha
+		}
+
+	case smoothingTypeHoltWinters:
+		if smoothReq.Alpha == 0 {
+			smoothReq.Alpha = defaultHoltWintersAlpha
+		}
+
+		if smoothReq.Beta == 0 {
+			smoothReq.Beta = defaultHoltWintersBeta
+		}
+
+		if smoothReq.Gamma == 0 {
+			smoothReq.Gamma = defaultHoltWintersGamma
+		}
+
+		if smoothReq.Season == "" {
+			smoothReq.Season = defaultHoltWintersSeason
+		}
+
+		if smoothReq.Delta == 0 {
+			smoothReq.Delta = defaultHoltWintersDelta
+		}
+
+	default:
+		return fmt.Errorf("unknown smoothing type `%s'", smoothReq.Type)
+	}
+
+	return nil
 }
 
 // OriginResponse represents an origin response structure in the server backend.
@@ -175,20 +266,49 @@ func (r UnitValueListResponse) slice(i, j int) interface{} {
 	return r[i:j]
 }
 
+// AnnotationListResponse represents a list of annotations response structure in the server backend.
+type AnnotationListResponse []*library.Annotation
+
+func (r AnnotationListResponse) Len() int {
+	return len(r)
+}
+
+func (r AnnotationListResponse) Less(i, j int) bool {
+	return r[i].Start.Before(r[j].Start)
+}
+
+func (r AnnotationListResponse) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r AnnotationListResponse) slice(i, j int) interface{} {
+	return r[i:j]
+}
+
 // PlotResponse represents a plot response structure in the server backend.
 type PlotResponse struct {
-	ID          string            `json:"id"`
-	Start       string            `json:"start"`
-	End         string            `json:"end"`
-	Step        float64           `json:"step"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Type        int               `json:"type"`
-	StackMode   int               `json:"stack_mode"`
-	UnitType    int               `json:"unit_type"`
-	UnitLegend  string            `json:"unit_legend"`
-	Series      []*SeriesResponse `json:"series"`
-	Modified    time.Time         `json:"modified"`
+	ID          string                `json:"id"`
+	Start       string                `json:"start"`
+	End         string                `json:"end"`
+	Step        float64               `json:"step"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Type        int                   `json:"type"`
+	StackMode   int                   `json:"stack_mode"`
+	UnitType    int                   `json:"unit_type"`
+	UnitLegend  string                `json:"unit_legend"`
+	Series      []*SeriesResponse     `json:"series"`
+	Annotations []*library.Annotation `json:"annotations,omitempty"`
+	Modified    time.Time             `json:"modified"`
+}
+
+// SnapshotResponse represents a stored plot snapshot structure in the server backend.
+type SnapshotResponse struct {
+	ID      string        `json:"id"`
+	Author  string        `json:"author,omitempty"`
+	Created time.Time     `json:"created"`
+	Request *PlotRequest  `json:"request"`
+	Plots   *PlotResponse `json:"plots"`
 }
 
 // SeriesResponse represents a series response structure in the server backend.
@@ -200,6 +320,21 @@ type SeriesResponse struct {
 	Options map[string]interface{} `json:"options"`
 }
 
+// RevisionResponse represents a library item revision response structure in the server backend.
+type RevisionResponse struct {
+	ID       int             `json:"id"`
+	Modified string          `json:"modified"`
+	Author   string          `json:"author,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// RevisionDiffResponse represents a library item revisions diff response structure in the server backend.
+type RevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
 // Unexported types
 type listResponse struct {
 	list   sortableListResponse
@@ -214,10 +349,68 @@ type sortableListResponse interface {
 	slice(offset, limit int) interface{}
 }
 
+type int64Slice []int64
+
+func (s int64Slice) Len() int {
+	return len(s)
+}
+
+func (s int64Slice) Less(i, j int) bool {
+	return s[i] < s[j]
+}
+
+func (s int64Slice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+type providerQuery struct {
+	query     *plot.QueryGroup
+	connector connector.Connector
+}
+
+type referenceResponse struct {
+	Message    string               `json:"message"`
+	References []*library.Reference `json:"references"`
+}
+
 type serverResponse struct {
 	Message string `json:"message"`
 }
 
+// RuleResponse represents an alerting rule response structure in the server backend.
+type RuleResponse struct {
+	Name     string                         `json:"name"`
+	State    string                         `json:"state"`
+	LastEval string                         `json:"last_eval,omitempty"`
+	Series   map[string]*RuleSeriesResponse `json:"series"`
+}
+
+// RuleSeriesResponse represents an alerting rule series state response structure in the server backend.
+type RuleSeriesResponse struct {
+	State string     `json:"state"`
+	Value plot.Value `json:"value"`
+	Since string     `json:"since"`
+}
+
+// RuleListResponse represents a list of alerting rules response structure in the server backend.
+type RuleListResponse []*RuleResponse
+
+func (r RuleListResponse) Len() int {
+	return len(r)
+}
+
+func (r RuleListResponse) Less(i, j int) bool {
+	return r[i].Name < r[j].Name
+}
+
+func (r RuleListResponse) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r RuleListResponse) slice(i, j int) interface{} {
+	return r[i:j]
+}
+
 type statsResponse struct {
 	Origins      int `json:"origins"`
 	Sources      int `json:"sources"`
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"time"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/worker"
+)
+
+func workerReportInit(w *worker.Worker, args ...interface{}) {
+	var server = args[0].(*Server)
+
+	logger.Log(logger.LevelDebug, "reportWorker", "init")
+
+	// Worker properties:
+	// 0: server instance (*Server)
+	w.Props = append(w.Props, server)
+
+	w.ReturnErr(nil)
+}
+
+func workerReportShutdown(w *worker.Worker, args ...interface{}) {
+	logger.Log(logger.LevelDebug, "reportWorker", "shutdown")
+
+	w.SendJobSignal(jobSignalShutdown)
+
+	w.ReturnErr(nil)
+}
+
+func workerReportRun(w *worker.Worker, args ...interface{}) {
+	var server = w.Props[0].(*Server)
+
+	defer w.Shutdown()
+
+	logger.Log(logger.LevelDebug, "reportWorker", "starting")
+
+	w.State = worker.JobStarted
+
+	timeTicker := time.NewTicker(time.Second)
+	defer timeTicker.Stop()
+
+	for {
+		select {
+		case cmd := <-w.ReceiveJobSignals():
+			switch cmd {
+			case jobSignalShutdown:
+				logger.Log(logger.LevelInfo, "reportWorker", "received shutdown command, stopping job")
+
+				w.State = worker.JobStopped
+
+				return
+
+			default:
+				logger.Log(logger.LevelNotice, "reportWorker", "received unknown command, ignoring")
+			}
+
+		case now := <-timeTicker.C:
+			for _, item := range server.getReports() {
+				if item.next.IsZero() || now.Before(item.next) {
+					continue
+				}
+
+				refTime := item.next
+				item.next = item.schedule.Next(now)
+
+				// Generate report in background, rendering a whole collection possibly taking a while
+				go func(item *report, refTime time.Time) {
+					logger.Log(logger.LevelInfo, "reportWorker", "generating report `%s'", item.name)
+
+					if err := server.runReport(item, refTime); err != nil {
+						logger.Log(logger.LevelError, "reportWorker", "report `%s': %s", item.name, err)
+					}
+				}(item, refTime)
+			}
+		}
+	}
+}
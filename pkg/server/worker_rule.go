@@ -0,0 +1,73 @@
+package server
+
+import (
+	"time"
+
+	"github.com/facette/facette/pkg/logger"
+	"github.com/facette/facette/pkg/worker"
+)
+
+func workerRuleInit(w *worker.Worker, args ...interface{}) {
+	var server = args[0].(*Server)
+
+	logger.Log(logger.LevelDebug, "ruleWorker", "init")
+
+	// Worker properties:
+	// 0: server instance (*Server)
+	w.Props = append(w.Props, server)
+
+	w.ReturnErr(nil)
+}
+
+func workerRuleShutdown(w *worker.Worker, args ...interface{}) {
+	logger.Log(logger.LevelDebug, "ruleWorker", "shutdown")
+
+	w.SendJobSignal(jobSignalShutdown)
+
+	w.ReturnErr(nil)
+}
+
+func workerRuleRun(w *worker.Worker, args ...interface{}) {
+	var server = w.Props[0].(*Server)
+
+	defer w.Shutdown()
+
+	logger.Log(logger.LevelDebug, "ruleWorker", "starting")
+
+	w.State = worker.JobStarted
+
+	// Check every second for rules due to evaluation, each rule having its own interval
+	timeTicker := time.NewTicker(time.Second)
+	defer timeTicker.Stop()
+
+	for {
+		select {
+		case cmd := <-w.ReceiveJobSignals():
+			switch cmd {
+			case jobSignalShutdown:
+				logger.Log(logger.LevelInfo, "ruleWorker", "received shutdown command, stopping job")
+
+				w.State = worker.JobStopped
+
+				return
+
+			default:
+				logger.Log(logger.LevelNotice, "ruleWorker", "received unknown command, ignoring")
+			}
+
+		case now := <-timeTicker.C:
+			// Wait for library to be loaded before evaluating rules
+			if server.Library == nil || server.Library.Graphs == nil {
+				continue
+			}
+
+			for _, rule := range server.getRules() {
+				if now.Sub(rule.lastEval) < time.Duration(rule.config.Interval)*time.Second {
+					continue
+				}
+
+				server.evalRule(rule)
+			}
+		}
+	}
+}
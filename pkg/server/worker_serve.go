@@ -12,13 +12,17 @@ import (
 )
 
 const (
-	urlStaticPath  string = "/static/"
-	urlAdminPath   string = "/admin/"
-	urlBrowsePath  string = "/browse/"
-	urlShowPath    string = "/show/"
-	urlCatalogPath string = "/api/v1/catalog/"
-	urlLibraryPath string = "/api/v1/library/"
-	urlStatsPath   string = "/api/v1/stats"
+	urlStaticPath    string = "/static/"
+	urlAdminPath     string = "/admin/"
+	urlBrowsePath    string = "/browse/"
+	urlShowPath      string = "/show/"
+	urlCatalogPath   string = "/api/v1/catalog/"
+	urlLibraryPath   string = "/api/v1/library/"
+	urlStatsPath     string = "/api/v1/stats"
+	urlRulesPath     string = "/api/v1/rules/"
+	urlSnapshotsPath string = "/api/v1/snapshots/"
+	urlStreamsPath   string = "/api/v1/streams/"
+	urlRenderPath    string = "/render"
 )
 
 func workerServeInit(w *worker.Worker, args ...interface{}) {
@@ -58,6 +62,10 @@ func workerServeRun(w *worker.Worker, args ...interface{}) {
 	router.HandleFunc(urlBrowsePath, server.serveBrowse)
 	router.HandleFunc(urlShowPath, server.serveShow)
 	router.HandleFunc(urlStatsPath, server.serveStats)
+	router.HandleFunc(urlRulesPath, server.serveRules)
+	router.HandleFunc(urlSnapshotsPath, server.serveSnapshot)
+	router.HandleFunc(urlStreamsPath, server.serveStreams)
+	router.HandleFunc(urlRenderPath, server.serveRender)
 
 	router.HandleFunc("/", server.serveBrowse)
 
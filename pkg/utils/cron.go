@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+var cronBounds = [5][2]int{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week (both 0 and 7 being Sunday)
+}
+
+// CronSchedule represents a cron-like schedule, made of the minute, hour, day of month, month and day of week fields.
+type CronSchedule struct {
+	fields [5]uint64
+	dom    bool
+	dow    bool
+}
+
+// ParseCronSchedule parses a cron-like schedule specification (e.g. `30 8 * * 1' or `@weekly').
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(spec)]; ok {
+		spec = macro
+	}
+
+	chunks := strings.Fields(spec)
+	if len(chunks) != 5 {
+		return nil, fmt.Errorf("invalid schedule `%s': expected 5 fields", spec)
+	}
+
+	schedule := &CronSchedule{
+		dom: chunks[2] != "*",
+		dow: chunks[4] != "*",
+	}
+
+	for index, chunk := range chunks {
+		value, err := parseCronField(chunk, cronBounds[index][0], cronBounds[index][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule `%s': %s", spec, err)
+		}
+
+		schedule.fields[index] = value
+	}
+
+	// Handle Sunday given as 7
+	if schedule.fields[4]&(1<<7) != 0 {
+		schedule.fields[4] |= 1
+	}
+
+	return schedule, nil
+}
+
+// Next returns the first time matching the schedule strictly after a given time, or the zero time if none is found
+// within the next 5 years.
+func (schedule *CronSchedule) Next(refTime time.Time) time.Time {
+	// Compute boundaries using calendar fields, as truncating the absolute time breaks on zones having non-hour
+	// offsets (e.g. Asia/Kolkata being UTC+05:30)
+	t := time.Date(refTime.Year(), refTime.Month(), refTime.Day(), refTime.Hour(), refTime.Minute()+1, 0, 0,
+		refTime.Location())
+	limit := refTime.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !schedule.match(3, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		} else if !schedule.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		} else if !schedule.match(1, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		} else if !schedule.match(0, t.Minute()) {
+			t = t.Add(time.Minute)
+		} else {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (schedule *CronSchedule) match(field, value int) bool {
+	return schedule.fields[field]&(1<<uint(value)) != 0
+}
+
+// matchDay follows the cron semantics, matching either the day of month or week if both are restricted.
+func (schedule *CronSchedule) matchDay(t time.Time) bool {
+	dom, dow := schedule.match(2, t.Day()), schedule.match(4, int(t.Weekday()))
+
+	if schedule.dom && schedule.dow {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+func parseCronField(input string, min, max int) (uint64, error) {
+	var result uint64
+
+	for _, chunk := range strings.Split(input, ",") {
+		var err error
+
+		start, end, step := min, max, 1
+
+		if index := strings.Index(chunk, "/"); index != -1 {
+			if step, err = strconv.Atoi(chunk[index+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in `%s'", chunk)
+			}
+
+			chunk = chunk[:index]
+		}
+
+		if chunk != "*" {
+			bounds := strings.SplitN(chunk, "-", 2)
+
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value `%s'", chunk)
+			}
+
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value `%s'", chunk)
+				}
+			} else if step == 1 {
+				end = start
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value `%s' out of range [%d-%d]", chunk, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_CronScheduleNext(test *testing.T) {
+	refTime := time.Date(2015, time.March, 4, 10, 17, 42, 0, time.UTC) // Wednesday
+
+	for _, entry := range []struct {
+		Spec   string
+		Result time.Time
+	}{
+		{"* * * * *", time.Date(2015, time.March, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2015, time.March, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2015, time.March, 5, 8, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1", time.Date(2015, time.March, 9, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2015, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 6 1,15 * *", time.Date(2015, time.March, 15, 6, 0, 0, 0, time.UTC)},
+		{"0 0 1 1-3 *", time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2015, time.March, 4, 13, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2015, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2015, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2015, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	} {
+		schedule, err := ParseCronSchedule(entry.Spec)
+		if err != nil {
+			test.Logf("\nUnexpected error for %q: %s", entry.Spec, err)
+			test.Fail()
+			continue
+		}
+
+		if result := schedule.Next(refTime); !result.Equal(entry.Result) {
+			test.Logf("\nFor %q, expected %s\nbut got  %s", entry.Spec, entry.Result, result)
+			test.Fail()
+		}
+	}
+}
+
+func Test_CronScheduleNextLocation(test *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+30*60)
+	kathmandu := time.FixedZone("NPT", 5*3600+45*60)
+
+	for _, entry := range []struct {
+		Spec    string
+		RefTime time.Time
+		Result  time.Time
+	}{
+		{"0 8 * * *", time.Date(2015, time.March, 4, 10, 17, 42, 0, kolkata),
+			time.Date(2015, time.March, 5, 8, 0, 0, 0, kolkata)},
+		{"0 8 * * *", time.Date(2015, time.March, 4, 7, 59, 59, 0, kolkata),
+			time.Date(2015, time.March, 4, 8, 0, 0, 0, kolkata)},
+		{"30 * * * *", time.Date(2015, time.March, 4, 10, 45, 0, 0, kathmandu),
+			time.Date(2015, time.March, 4, 11, 30, 0, 0, kathmandu)},
+		{"0 0 * * 1", time.Date(2015, time.March, 4, 10, 17, 42, 0, kathmandu),
+			time.Date(2015, time.March, 9, 0, 0, 0, 0, kathmandu)},
+	} {
+		schedule, err := ParseCronSchedule(entry.Spec)
+		if err != nil {
+			test.Logf("\nUnexpected error for %q: %s", entry.Spec, err)
+			test.Fail()
+			continue
+		}
+
+		if result := schedule.Next(entry.RefTime); !result.Equal(entry.Result) {
+			test.Logf("\nFor %q, expected %s\nbut got  %s", entry.Spec, entry.Result, result)
+			test.Fail()
+		}
+	}
+}
+
+func Test_ParseCronScheduleInvalid(test *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *",
+		"5-1 * * * *"} {
+		if _, err := ParseCronSchedule(spec); err == nil {
+			test.Logf("\nExpected error for %q", spec)
+			test.Fail()
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type diffLine struct {
+	op       byte
+	text     string
+	from, to int
+}
+
+// Diff returns the unified diff between two texts, showing a given number of context lines around changes.
+func Diff(fromName, toName, from, to string, context int) string {
+	var buffer bytes.Buffer
+
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	// Group changed lines along with their context into hunks
+	var hunks [][2]int
+
+	for i := range lines {
+		if lines[i].op == ' ' {
+			continue
+		}
+
+		start, end := i-context, i+context+1
+		if start < 0 {
+			start = 0
+		}
+
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	fmt.Fprintf(&buffer, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, hunk := range hunks {
+		fromStart, fromCount, toStart, toCount := 0, 0, 0, 0
+
+		for _, line := range lines[hunk[0]:hunk[1]] {
+			if line.op != '+' {
+				if fromCount == 0 {
+					fromStart = line.from
+				}
+
+				fromCount++
+			}
+
+			if line.op != '-' {
+				if toCount == 0 {
+					toStart = line.to
+				}
+
+				toCount++
+			}
+		}
+
+		// Empty ranges refer to the line preceding the hunk
+		if fromCount == 0 {
+			fromStart = lines[hunk[0]].from - 1
+		}
+
+		if toCount == 0 {
+			toStart = lines[hunk[0]].to - 1
+		}
+
+		fmt.Fprintf(&buffer, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+
+		for _, line := range lines[hunk[0]:hunk[1]] {
+			buffer.WriteByte(line.op)
+			buffer.WriteString(line.text)
+			buffer.WriteByte('\n')
+		}
+	}
+
+	return buffer.String()
+}
+
+// diffLines computes the edit script between two lists of lines using their longest common subsequence. Lines
+// numbers start at 1 and refer to the position the line would have in the source and target texts.
+func diffLines(from, to []string) []diffLine {
+	var result []diffLine
+
+	// Compute longest common subsequence lengths table, starting from lists ends
+	table := make([][]int, len(from)+1)
+	for i := range table {
+		table[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			result = append(result, diffLine{' ', from[i], i + 1, j + 1})
+			i++
+			j++
+
+		case j == len(to) || i < len(from) && table[i+1][j] >= table[i][j+1]:
+			result = append(result, diffLine{'-', from[i], i + 1, j + 1})
+			i++
+
+		default:
+			result = append(result, diffLine{'+', to[j], i + 1, j + 1})
+			j++
+		}
+	}
+
+	return result
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,38 @@
+package utils
+
+import "testing"
+
+func Test_Diff(test *testing.T) {
+	for _, entry := range []struct {
+		From   string
+		To     string
+		Result string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", ""},
+		{
+			"a\nb\nc\nd\ne\nf\ng\nh\n",
+			"a\nb\nc\nD\ne\nf\ng\nh\n",
+			"--- from\n+++ to\n@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n",
+		},
+		{
+			"a\nb\n",
+			"a\nb\nc\n",
+			"--- from\n+++ to\n@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			"",
+			"a\n",
+			"--- from\n+++ to\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			"a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			"A\n1\n2\n3\n4\n5\n6\n7\n8\n",
+			"--- from\n+++ to\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,3 @@\n 6\n 7\n 8\n-b\n",
+		},
+	} {
+		if result := Diff("from", "to", entry.From, entry.To, 3); result != entry.Result {
+			test.Logf("\nExpected %q\nbut got  %q", entry.Result, result)
+			test.Fail()
+		}
+	}
+}
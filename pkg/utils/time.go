@@ -13,6 +13,7 @@ const (
 	durationRegexp = "^([-+])?\\s*" +
 		"(?:(\\d+)\\s*y(?:ears?)?)?\\s*" +
 		"(?:(\\d+)\\s*mo(?:nths?)?)?\\s*" +
+		"(?:(\\d+)\\s*w(?:eeks?)?)?\\s*" +
 		"(?:(\\d+)\\s*d(?:ays?)?)?\\s*" +
 		"(?:(\\d+)\\s*h(?:ours?)?)?\\s*" +
 		"(?:(\\d+)\\s*m(?:inutes?)?)?\\s*" +
@@ -95,10 +96,10 @@ func TimeApplyRange(refTime time.Time, input string) (time.Time, error) {
 	}
 
 	newTime := refTime.
-		AddDate(chunks[0], chunks[1], chunks[2]).
-		Add(time.Duration(chunks[3]) * time.Hour).
-		Add(time.Duration(chunks[4]) * time.Minute).
-		Add(time.Duration(chunks[5]) * time.Second)
+		AddDate(chunks[0], chunks[1], chunks[2]*7+chunks[3]).
+		Add(time.Duration(chunks[4]) * time.Hour).
+		Add(time.Duration(chunks[5]) * time.Minute).
+		Add(time.Duration(chunks[6]) * time.Second)
 
 	return newTime, nil
 }
@@ -2,6 +2,7 @@
 
 read -d '' DEPENDS <<EOF
 git  https://github.com/fatih/set          master
+git  https://github.com/golang/crypto      master  /bcrypt /blowfish /LICENSE
 git  https://github.com/influxdb/influxdb  master  /client /LICENSE
 git  https://github.com/nu7hatch/gouuid    master
 git  https://github.com/ziutek/rrd         master
@@ -40,10 +41,12 @@ fetch_hg() {
 
 rewrite_imports() {
 	name=$1
+	path=${2:-$1}
 
 	echo "Rewriting $name import paths..."
 	find $SRC_DIR -type f ! -path "$SRC_DIR/checkouts/*" -name '*.go' \
-		-exec sed -e "s@\"$name@\"github.com/facette/facette/thirdparty/$name@" -i {} \;
+		-exec sed -e "s@\"$name@\"github.com/facette/facette/thirdparty/$path@" \
+			-e "s@^\(package [a-z_]*\) // import \".*\"\$@\1@" -i {} \;
 }
 
 print_usage() {
@@ -90,3 +93,6 @@ for package in ${PACKAGES[@]}; do
 	# Rewrite import paths
 	rewrite_imports $package
 done
+
+# Rewrite vanity import paths
+rewrite_imports golang.org/x/crypto github.com/golang/crypto